@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Artwork file base names stored in each media directory.
+// The names follow the Kodi conventions so other media tools pick them up too.
+const (
+	ArtworkPoster   = "poster"
+	ArtworkBackdrop = "fanart"
+	ArtworkLogo     = "clearlogo"
+)
+
+// artworkNamePattern matches the artwork names that can be served
+var artworkNamePattern = regexp.MustCompile(`^(poster|fanart|clearlogo|season\d{2}-poster)$`)
+
+// artworkExtensions lists the artwork file extensions in the order FindArtworkFile checks them
+var artworkExtensions = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+// SeasonArtworkName returns the artwork name for a TV season's poster (e.g. "season01-poster")
+func SeasonArtworkName(season int) string {
+	return fmt.Sprintf("season%02d-poster", season)
+}
+
+// findArtworkFile returns the path of the named artwork file in dirPath if it exists
+func findArtworkFile(dirPath, name string) (string, bool) {
+	for _, ext := range artworkExtensions {
+		artworkPath := filepath.Join(dirPath, name+ext)
+		if _, err := os.Stat(artworkPath); err == nil {
+			return artworkPath, true
+		}
+	}
+	return "", false
+}
+
+// tmdbImagePathPattern matches image file paths returned by the TMDB images endpoint
+var tmdbImagePathPattern = regexp.MustCompile(`^/?[A-Za-z0-9_-]+\.(jpg|jpeg|png|webp)$`)
+
+// maxPosterUploadSize limits the size of uploaded poster images
+const maxPosterUploadSize = 10 << 20 // 10 MB
+
+// ReplacePoster atomically writes a new poster image to the media directory
+// and removes poster files with other extensions, which FindPosterFile would
+// otherwise pick up before the new one
+func ReplacePoster(destDir, ext string, r io.Reader) error {
+	return ReplaceArtwork(destDir, ArtworkPoster, ext, r)
+}
+
+// ReplaceArtwork atomically writes the named artwork image and removes copies
+// of it with other extensions
+func ReplaceArtwork(destDir, name, ext string, r io.Reader) error {
+	ext = strings.ToLower(ext)
+	if !isArtworkExtension(ext) {
+		return fmt.Errorf("unsupported %s format: %s", name, ext)
+	}
+
+	destPath := filepath.Join(destDir, name+ext)
+	if err := writeReaderAtomic(destPath, r, 0644); err != nil {
+		return fmt.Errorf("failed to write %s file: %w", name, err)
+	}
+
+	// Remove stale copies with other extensions
+	for _, other := range artworkExtensions {
+		if other == ext {
+			continue
+		}
+		stalePath := filepath.Join(destDir, name+other)
+		if err := os.Remove(stalePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to remove stale %s %s: %v", name, stalePath, err)
+		}
+	}
+
+	return nil
+}
+
+// isArtworkExtension reports whether ext is a supported artwork file extension
+func isArtworkExtension(ext string) bool {
+	for _, candidate := range artworkExtensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// imageExtensionForContentType returns the file extension for an image content type
+// Returns an empty string for unsupported types
+func imageExtensionForContentType(contentType string) string {
+	switch strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0])) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ""
+	}
+}
+
+// SavePosterUpload validates an uploaded image by its content and stores it as the poster
+func SavePosterUpload(destDir string, r io.Reader) error {
+	// Sniff the content type from the first bytes rather than trusting the client
+	header := make([]byte, 512)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return fmt.Errorf("uploaded file is empty")
+		}
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	header = header[:n]
+
+	ext := imageExtensionForContentType(http.DetectContentType(header))
+	if ext == "" {
+		return fmt.Errorf("uploaded file must be a JPEG, PNG or WebP image")
+	}
+
+	return ReplacePoster(destDir, ext, io.MultiReader(strings.NewReader(string(header)), r))
+}
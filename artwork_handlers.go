@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// posterLanguages lists the languages offered by the poster picker filter
+var posterLanguages = []struct {
+	Code string
+	Name string
+}{
+	{"en", "English"},
+	{"de", "German"},
+	{"fr", "French"},
+	{"es", "Spanish"},
+	{"it", "Italian"},
+	{"ja", "Japanese"},
+	{"", "All languages"},
+}
+
+// PosterPickerHandler shows alternative TMDB posters and a custom upload form
+func (app *App) PosterPickerHandler(w http.ResponseWriter, r *http.Request) {
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
+
+	// Extract slug from URL: /media/{slug}/posters
+	path := strings.TrimPrefix(r.URL.Path, "/media/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+	slug := parts[0]
+
+	// Find media by slug
+	media := app.findMediaBySlug(slug)
+	if media == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Default to English posters unless another language is requested
+	language := "en"
+	if r.URL.Query().Has("language") {
+		language = r.URL.Query().Get("language")
+	}
+
+	// Fetch alternatives when the media item is matched to TMDB
+	var posters []Image
+	var errorMsg string
+	if app.tmdbClient != nil && media.TMDBID != "" {
+		images, err := app.tmdbClient.FetchImages(r.Context(), media.TMDBID, media.Type, language)
+		if err != nil {
+			errorMsg = tmdbErrorMessage(err)
+		} else {
+			posters = images.Posters
+		}
+	}
+
+	_, hasPoster := media.FindPosterFile()
+
+	data := struct {
+		Media         *Media
+		HasPoster     bool
+		Posters       []Image
+		Language      string
+		Languages     interface{}
+		TMDBAvailable bool
+		Error         string
+	}{
+		Media:         media,
+		HasPoster:     hasPoster,
+		Posters:       posters,
+		Language:      language,
+		Languages:     posterLanguages,
+		TMDBAvailable: app.tmdbClient != nil && media.TMDBID != "",
+		Error:         errorMsg,
+	}
+
+	err := tmpl.ExecuteTemplate(w, "posters.html", data)
+	if err != nil {
+		log.Printf("Error rendering posters template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// SetPosterHandler replaces the poster with a chosen TMDB image or an uploaded file
+func (app *App) SetPosterHandler(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract slug from URL: /media/{slug}/poster
+	path := strings.TrimPrefix(r.URL.Path, "/media/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+	slug := parts[0]
+
+	// Find media by slug
+	media := app.findMediaBySlug(slug)
+	if media == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if isMultipart(r) {
+		// Custom upload
+		// The body limit leaves room for the multipart headers; the file
+		// itself is held to the exact limit below
+		r.Body = http.MaxBytesReader(w, r.Body, maxPosterUploadSize+1<<20)
+		if err := r.ParseMultipartForm(maxPosterUploadSize); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Poster is too large (maximum size is 10 MB)", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Failed to parse upload (maximum size is 10 MB)", http.StatusBadRequest)
+			return
+		}
+
+		file, header, err := r.FormFile("poster")
+		if err != nil {
+			http.Error(w, "Poster file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		if header.Size > maxPosterUploadSize {
+			http.Error(w, "Poster is too large (maximum size is 10 MB)", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if err := SavePosterUpload(media.Path, file); err != nil {
+			log.Printf("Failed to save uploaded poster for %s: %v", media.Title, err)
+			http.Error(w, fmt.Sprintf("Failed to save poster: %v", err), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Saved uploaded poster for %s", media.Title)
+	} else {
+		// TMDB alternative
+		if app.tmdbClient == nil {
+			http.Error(w, "TMDB API is not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		filePath := r.FormValue("file_path")
+		if !tmdbImagePathPattern.MatchString(filePath) {
+			http.Error(w, "Invalid poster path", http.StatusBadRequest)
+			return
+		}
+
+		if err := app.tmdbClient.DownloadPoster(r.Context(), filePath, media.Path); err != nil {
+			log.Printf("Failed to download poster for %s: %v", media.Title, err)
+			http.Error(w, "Failed to download poster: "+tmdbErrorMessage(err), tmdbErrorStatus(err))
+			return
+		}
+	}
+
+	// Redirect back to detail page
+	http.Redirect(w, r, "/media/"+url.PathEscape(slug), http.StatusSeeOther)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPosterPickerHandler(t *testing.T) {
+	app, _, _ := setupAppWithMockTMDB(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025/posters", nil)
+	w := httptest.NewRecorder()
+	app.PosterPickerHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("PosterPickerHandler() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, expected := range []string{"/alt-one.jpg", "/alt-two.png", "No text", "Upload Custom Poster"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("PosterPickerHandler() body does not contain %q", expected)
+		}
+	}
+
+	// Language filter is passed through to TMDB
+	req = httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025/posters?language=de", nil)
+	w = httptest.NewRecorder()
+	app.PosterPickerHandler(w, req)
+
+	body = w.Body.String()
+	if !strings.Contains(body, "/german.jpg") || strings.Contains(body, "/alt-one.jpg") {
+		t.Error("PosterPickerHandler() did not filter posters by language")
+	}
+}
+
+func TestPosterPickerHandlerWithoutTMDB(t *testing.T) {
+	app, _, _ := setupAppWithMockTMDB(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/media/no-tmdb-2021/posters", nil)
+	w := httptest.NewRecorder()
+	app.PosterPickerHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("PosterPickerHandler() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	if !strings.Contains(w.Body.String(), "Set a TMDB ID") {
+		t.Error("PosterPickerHandler() should explain that TMDB posters need a TMDB ID")
+	}
+}
+
+func TestSetPosterHandlerTMDB(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+
+	filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	os.WriteFile(filepath.Join(filmDir, "poster.jpg"), []byte("old-poster"), 0644)
+
+	form := url.Values{"file_path": {"/alt-two.png"}}
+	req := httptest.NewRequest(http.MethodPost, "/media/war-of-the-worlds-2025/poster", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	app.SetPosterHandler(w, req)
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("SetPosterHandler() status = %v, want %v: %s", w.Code, http.StatusSeeOther, w.Body.String())
+	}
+	data, err := os.ReadFile(filepath.Join(filmDir, "poster.png"))
+	if err != nil || string(data) != "fake-image-data" {
+		t.Errorf("Expected poster.png with downloaded data, got %q (%v)", string(data), err)
+	}
+	if _, err := os.Stat(filepath.Join(filmDir, "poster.jpg")); !os.IsNotExist(err) {
+		t.Error("Expected stale poster.jpg to be removed")
+	}
+}
+
+func TestSetPosterHandlerUpload(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("poster", "cover.png")
+	part.Write(testPNG(t))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/media/no-tmdb-2021/poster", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	app.SetPosterHandler(w, req)
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("SetPosterHandler() status = %v, want %v: %s", w.Code, http.StatusSeeOther, w.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(testDir, "No TMDB (2021) [Film]", "poster.png")); err != nil {
+		t.Errorf("Expected uploaded poster.png: %v", err)
+	}
+}
+
+func TestSetPosterHandlerUploadWithoutScripts(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+
+	// Browsers without scripts post the form as is, token field and all
+	session := newCSRFSessionID()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField(csrfFieldName, csrfTokenFor(session))
+	part, _ := writer.CreateFormFile("poster", "cover.png")
+	part.Write(testPNG(t))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/media/no-tmdb-2021/poster", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: session})
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, req)
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Upload status = %v, want %v: %s", w.Code, http.StatusSeeOther, w.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(testDir, "No TMDB (2021) [Film]", "poster.png")); err != nil {
+		t.Errorf("Expected uploaded poster.png: %v", err)
+	}
+}
+
+func TestSetPosterHandlerUploadSizeLimit(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+	posterPath := filepath.Join(testDir, "No TMDB (2021) [Film]", "poster.png")
+
+	upload := func(size int) *httptest.ResponseRecorder {
+		data := testPNG(t)
+		data = append(data, make([]byte, size-len(data))...)
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("poster", "cover.png")
+		part.Write(data)
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/media/no-tmdb-2021/poster", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		app.SetPosterHandler(w, req)
+		return w
+	}
+
+	for _, size := range []int{maxPosterUploadSize + 1, maxPosterUploadSize + 2<<20} {
+		if w := upload(size); w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Upload of %d bytes status = %v, want %v", size, w.Code, http.StatusRequestEntityTooLarge)
+		}
+		if _, err := os.Stat(posterPath); !os.IsNotExist(err) {
+			t.Fatalf("Upload of %d bytes saved a poster", size)
+		}
+	}
+
+	if w := upload(maxPosterUploadSize); w.Code != http.StatusSeeOther {
+		t.Errorf("Upload at the limit status = %v, want %v: %s", w.Code, http.StatusSeeOther, w.Body.String())
+	}
+}
+
+func TestSetPosterHandlerValidation(t *testing.T) {
+	app, _, _ := setupAppWithMockTMDB(t)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		filePath       string
+		expectedStatus int
+	}{
+		{"GET not allowed", http.MethodGet, "/media/war-of-the-worlds-2025/poster", "", http.StatusMethodNotAllowed},
+		{"Unknown media", http.MethodPost, "/media/nonexistent/poster", "/alt-one.jpg", http.StatusNotFound},
+		{"Path traversal", http.MethodPost, "/media/war-of-the-worlds-2025/poster", "/../../etc/passwd", http.StatusBadRequest},
+		{"Missing path", http.MethodPost, "/media/war-of-the-worlds-2025/poster", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"file_path": {tt.filePath}}
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			app.SetPosterHandler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("SetPosterHandler() status = %v, want %v", w.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestFetchAndSaveMetadataDownloadsArtwork(t *testing.T) {
+	testDir := setupTestData(t)
+	server := mockTMDBServer()
+	defer server.Close()
+	client := newMockTMDBClient(t, server)
+
+	tvDir := filepath.Join(testDir, "Better Call Saul [TV]")
+	media := &Media{Title: "Better Call Saul", Type: TV, TMDBID: "60059", Path: tvDir}
+
+	if err := client.FetchAndSaveMetadata(context.Background(), media); err != nil {
+		t.Fatalf("FetchAndSaveMetadata() error = %v", err)
+	}
+
+	for _, name := range []string{"poster.jpg", "fanart.jpg", "clearlogo.png", "season01-poster.jpg"} {
+		if _, err := os.Stat(filepath.Join(tvDir, name)); err != nil {
+			t.Errorf("Expected %s to be downloaded: %v", name, err)
+		}
+	}
+
+	// SVG logos are skipped and seasons without posters are ignored
+	for _, name := range []string{"clearlogo.svg", "season02-poster.jpg"} {
+		if _, err := os.Stat(filepath.Join(tvDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to exist", name)
+		}
+	}
+}
+
+func TestArtworkHandler(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+	tvDir := filepath.Join(testDir, "Better Call Saul [TV]")
+	os.WriteFile(filepath.Join(tvDir, "fanart.jpg"), []byte("backdrop-data"), 0644)
+	os.WriteFile(filepath.Join(tvDir, "season01-poster.png"), []byte("season-data"), 0644)
+	os.WriteFile(filepath.Join(tvDir, "poster.jpg"), []byte("poster-data"), 0644)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Backdrop", "/art/better-call-saul/fanart", http.StatusOK, "backdrop-data"},
+		{"Season poster", "/art/better-call-saul/season01-poster", http.StatusOK, "season-data"},
+		{"Poster", "/art/better-call-saul/poster", http.StatusOK, "poster-data"},
+		{"Missing logo", "/art/better-call-saul/clearlogo", http.StatusNotFound, ""},
+		{"Unknown artwork name", "/art/better-call-saul/tmdb.txt", http.StatusNotFound, ""},
+		{"Traversal attempt", "/art/better-call-saul/../fanart", http.StatusNotFound, ""},
+		{"Unknown media", "/art/nonexistent/fanart", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			app.ArtworkHandler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("ArtworkHandler() status = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("ArtworkHandler() body = %q, want %q", w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+
+	// The legacy poster route serves the same file
+	req := httptest.NewRequest(http.MethodGet, "/posters/better-call-saul", nil)
+	w := httptest.NewRecorder()
+	app.PosterHandler(w, req)
+	if w.Body.String() != "poster-data" {
+		t.Errorf("PosterHandler() body = %q, want poster-data", w.Body.String())
+	}
+}
+
+func TestDetailHandlerShowsBackdropAndSeasonArt(t *testing.T) {
+	testDir := setupTestData(t)
+	tvDir := filepath.Join(testDir, "Better Call Saul [TV]")
+	os.WriteFile(filepath.Join(tvDir, "fanart.jpg"), []byte("backdrop-data"), 0644)
+	os.WriteFile(filepath.Join(tvDir, "season01-poster.jpg"), []byte("season-data"), 0644)
+
+	mediaList, _ := NewScanner(testDir).Scan()
+	tmpl, err := parseTemplates(templateFS(""))
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+	app := NewApp(mediaList, tmpl, testDir, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/media/better-call-saul", nil)
+	w := httptest.NewRecorder()
+	app.DetailHandler(w, req)
+
+	body := w.Body.String()
+	for _, expected := range []string{`class="hero"`, "/art/better-call-saul/fanart", "/art/better-call-saul/season01-poster"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("DetailHandler() body does not contain %q", expected)
+		}
+	}
+}
+
+func TestFetchAndSaveMetadataBackfillsArtwork(t *testing.T) {
+	testDir := setupTestData(t)
+	server := mockTMDBServer()
+	defer server.Close()
+	client := newMockTMDBClient(t, server)
+
+	// Fetched before artwork, cast and collections were saved
+	tvDir := filepath.Join(testDir, "Better Call Saul [TV]")
+	for name, content := range map[string]string{
+		"poster.jpg":      "poster",
+		"description.txt": "description",
+		"genre.txt":       "Drama",
+		"title.txt":       "Better Call Saul",
+	} {
+		os.WriteFile(filepath.Join(tvDir, name), []byte(content), 0644)
+	}
+	if hasAllMetadata(tvDir) {
+		t.Fatal("hasAllMetadata() = true for an item never fetched in full")
+	}
+
+	media := &Media{Title: "Better Call Saul", Type: TV, TMDBID: "60059", Path: tvDir}
+	if err := client.FetchAndSaveMetadata(context.Background(), media); err != nil {
+		t.Fatalf("FetchAndSaveMetadata() error = %v", err)
+	}
+	for _, name := range []string{"fanart.jpg", "clearlogo.png", "season01-poster.jpg", "credits.json"} {
+		if _, err := os.Stat(filepath.Join(tvDir, name)); err != nil {
+			t.Errorf("Expected %s to be backfilled: %v", name, err)
+		}
+	}
+	if poster, _ := os.ReadFile(filepath.Join(tvDir, "poster.jpg")); string(poster) != "poster" {
+		t.Error("Existing poster was replaced")
+	}
+	if !hasAllMetadata(tvDir) {
+		t.Error("hasAllMetadata() = false after a full fetch")
+	}
+}
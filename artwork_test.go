@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPNG returns the bytes of a small PNG image
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 3))); err != nil {
+		t.Fatalf("Failed to encode test PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReplacePosterRemovesStaleExtensions(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "poster.jpg"), []byte("old-jpg"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "poster.webp"), []byte("old-webp"), 0644)
+
+	if err := ReplacePoster(tmpDir, ".png", strings.NewReader("new-png")); err != nil {
+		t.Fatalf("ReplacePoster() error = %v", err)
+	}
+
+	for _, stale := range []string{"poster.jpg", "poster.webp"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, stale)); !os.IsNotExist(err) {
+			t.Errorf("Expected stale %s to be removed", stale)
+		}
+	}
+
+	media := &Media{Path: tmpDir}
+	posterPath, found := media.FindPosterFile()
+	if !found || filepath.Base(posterPath) != "poster.png" {
+		t.Errorf("FindPosterFile() = %q, %v; want poster.png", posterPath, found)
+	}
+	data, _ := os.ReadFile(posterPath)
+	if string(data) != "new-png" {
+		t.Errorf("Poster content = %q, want new-png", string(data))
+	}
+}
+
+func TestReplacePosterUnsupportedExtension(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := ReplacePoster(tmpDir, ".gif", strings.NewReader("gif")); err == nil {
+		t.Error("Expected error for unsupported extension, got nil")
+	}
+}
+
+func TestSavePosterUpload(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "poster.jpg"), []byte("old-jpg"), 0644)
+
+	if err := SavePosterUpload(tmpDir, bytes.NewReader(testPNG(t))); err != nil {
+		t.Fatalf("SavePosterUpload() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "poster.png")); err != nil {
+		t.Errorf("Expected poster.png to exist: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "poster.jpg")); !os.IsNotExist(err) {
+		t.Error("Expected poster.jpg to be removed")
+	}
+}
+
+func TestSavePosterUploadRejectsNonImages(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := SavePosterUpload(tmpDir, strings.NewReader("<html>not an image</html>")); err == nil {
+		t.Error("Expected error for non-image upload, got nil")
+	}
+	if err := SavePosterUpload(tmpDir, strings.NewReader("")); err == nil {
+		t.Error("Expected error for empty upload, got nil")
+	}
+
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 0 {
+		t.Errorf("Expected no files to be written, found %d", len(entries))
+	}
+}
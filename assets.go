@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Templates and static files are compiled into the binary so it can be started
+// from any working directory
+var (
+	//go:embed templates/*.html
+	embeddedTemplates embed.FS
+
+	//go:embed static
+	embeddedStatic embed.FS
+)
+
+// templateFiles lists the page templates parsed at startup
+var templateFiles = []string{
+	"index.html",
+	"detail.html",
+	"person.html",
+	"collections.html",
+	"search.html",
+	"confirm.html",
+	"posters.html",
+	"refresh.html",
+	"import_list.html",
+	"import_step1.html",
+	"import_step2.html",
+	"import_step3.html",
+	"import_step4.html",
+	"import_step5.html",
+	"import_confirm.html",
+	"import_success.html",
+	"job.html",
+	"jobs.html",
+	"matches.html",
+	"login.html",
+}
+
+// staticAssets serves the embedded static files and builds their versioned URLs
+var staticAssets = mustLoadStaticAssets()
+
+// overlayFS opens files from primary, falling back to fallback for files primary lacks
+type overlayFS struct {
+	primary  fs.FS
+	fallback fs.FS
+}
+
+// Open implements fs.FS
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.primary.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.fallback.Open(name)
+	}
+	return f, err
+}
+
+// ReadDir implements fs.ReadDirFS, listing the files of both filesystems
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(o.fallback, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	overrides, primaryErr := fs.ReadDir(o.primary, name)
+	if errors.Is(primaryErr, fs.ErrNotExist) {
+		return entries, err
+	}
+	if primaryErr != nil {
+		return nil, primaryErr
+	}
+
+	for _, entry := range overrides {
+		i := slices.IndexFunc(entries, func(e fs.DirEntry) bool { return e.Name() == entry.Name() })
+		if i >= 0 {
+			entries[i] = entry
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+// templateFS returns the filesystem templates are loaded from.
+// When dir is set, templates found there replace the embedded ones, so a
+// theme only needs to contain the pages it changes.
+func templateFS(dir string) fs.FS {
+	embedded, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		panic(err) // The embedded directory is fixed at compile time
+	}
+	if dir == "" {
+		return embedded
+	}
+	return overlayFS{primary: os.DirFS(dir), fallback: embedded}
+}
+
+// parseTemplates parses all page templates from fsys
+func parseTemplates(fsys fs.FS) (*template.Template, error) {
+	return template.New("").Funcs(template.FuncMap{
+		"asset": staticAssets.URL,
+
+		// Placeholders for the request-specific functions bound by App.templatesFor
+		"currentUser": func() *User { return nil },
+		"authEnabled": func() bool { return false },
+		"can":         func(role Role) bool { return false },
+		"csrfToken":   func() string { return "" },
+		"csrfField":   func() template.HTML { return "" },
+
+		// Replaced so previews follow the TMDB image server the client uses
+		"tmdbThumbnail": func(filePath string) string {
+			return tmdbImageURL(tmdbImageBaseURL, tmdbThumbnailSize, filePath)
+		},
+	}).ParseFS(fsys, templateFiles...)
+}
+
+// StaticAssets serves static files with content-hashed URLs
+type StaticAssets struct {
+	fsys   fs.FS
+	hashes map[string]string // File name to content hash
+}
+
+// NewStaticAssets hashes every file in fsys
+func NewStaticAssets(fsys fs.FS) (*StaticAssets, error) {
+	hashes := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hashes[name] = hex.EncodeToString(sum[:6])
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash static assets: %w", err)
+	}
+	return &StaticAssets{fsys: fsys, hashes: hashes}, nil
+}
+
+// staticFS returns the filesystem static files are served from. When dir is
+// set, files in its static directory replace or add to the embedded ones, so
+// a theme can bring its own stylesheets.
+func staticFS(dir string) fs.FS {
+	embedded, err := fs.Sub(embeddedStatic, "static")
+	if err != nil {
+		panic(err) // The embedded directory is fixed at compile time
+	}
+	if dir == "" {
+		return embedded
+	}
+	return overlayFS{primary: os.DirFS(filepath.Join(dir, "static")), fallback: embedded}
+}
+
+// mustLoadStaticAssets loads the embedded static files, panicking if they can't be read
+func mustLoadStaticAssets() *StaticAssets {
+	assets, err := NewStaticAssets(staticFS(""))
+	if err != nil {
+		panic(err)
+	}
+	return assets
+}
+
+// URL returns the versioned URL for a static file (e.g. "/static/jobs.js?v=1a2b3c4d5e6f").
+// The version changes with the file content so browsers can cache it indefinitely.
+func (a *StaticAssets) URL(name string) string {
+	hash, ok := a.hashes[name]
+	if !ok {
+		return "/static/" + name
+	}
+	return "/static/" + name + "?v=" + hash
+}
+
+// ServeHTTP serves static files under /static/
+func (a *StaticAssets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/static/")
+	hash, ok := a.hashes[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Only URLs carrying the current hash are safe to cache forever
+	w.Header().Set("ETag", `"`+hash+`"`)
+	if r.URL.Query().Get("v") == hash {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	http.ServeFileFS(w, r, a.fsys, name)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseEmbeddedTemplates(t *testing.T) {
+	tmpl, err := parseTemplates(templateFS(""))
+	if err != nil {
+		t.Fatalf("parseTemplates() error = %v", err)
+	}
+
+	for _, name := range templateFiles {
+		if tmpl.Lookup(name) == nil {
+			t.Errorf("Embedded templates missing %s", name)
+		}
+	}
+}
+
+func TestTemplateDirOverride(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<p>Custom theme {{asset "jobs.js"}}</p>`), 0644)
+
+	tmpl, err := parseTemplates(templateFS(dir))
+	if err != nil {
+		t.Fatalf("parseTemplates() error = %v", err)
+	}
+
+	// The override replaces the embedded page
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "index.html", nil); err != nil {
+		t.Fatalf("ExecuteTemplate() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "Custom theme") || !strings.Contains(buf.String(), "/static/jobs.js?v=") {
+		t.Errorf("index.html = %q, want custom template with versioned asset URL", buf.String())
+	}
+
+	// Pages not in the directory fall back to the embedded versions
+	if tmpl.Lookup("detail.html") == nil {
+		t.Error("detail.html missing, want embedded fallback")
+	}
+}
+
+func TestLoadTemplatesFromTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<p>Version one</p>`), 0644)
+
+	app := NewApp(nil, nil, t.TempDir(), "")
+	app.SetDevMode(true)
+	app.SetTemplateDir(dir)
+
+	// Edits are picked up on the next request in dev mode
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<p>Version two</p>`), 0644)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	app.IndexHandler(w, req)
+
+	if !strings.Contains(w.Body.String(), "Version two") {
+		t.Errorf("IndexHandler() body = %q, want reloaded template", w.Body.String())
+	}
+}
+
+func TestStaticAssets(t *testing.T) {
+	assets, err := NewStaticAssets(fstest.MapFS{
+		"site.css": {Data: []byte("body { color: red; }")},
+	})
+	if err != nil {
+		t.Fatalf("NewStaticAssets() error = %v", err)
+	}
+
+	assetURL := assets.URL("site.css")
+	if !strings.HasPrefix(assetURL, "/static/site.css?v=") {
+		t.Fatalf("URL() = %q, want versioned static URL", assetURL)
+	}
+
+	// A different file content produces a different URL
+	changed, _ := NewStaticAssets(fstest.MapFS{
+		"site.css": {Data: []byte("body { color: blue; }")},
+	})
+	if changed.URL("site.css") == assetURL {
+		t.Error("URL() did not change with file content")
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		cacheControl   string
+	}{
+		{"Versioned", assetURL, http.StatusOK, "public, max-age=31536000, immutable"},
+		{"Unversioned", "/static/site.css", http.StatusOK, "no-cache"},
+		{"Stale version", "/static/site.css?v=0000", http.StatusOK, "no-cache"},
+		{"Unknown file", "/static/missing.css", http.StatusNotFound, ""},
+		{"Traversal attempt", "/static/../main.go", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			assets.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", w.Code, tt.expectedStatus)
+			}
+			if tt.cacheControl != "" && w.Header().Get("Cache-Control") != tt.cacheControl {
+				t.Errorf("Cache-Control = %q, want %q", w.Header().Get("Cache-Control"), tt.cacheControl)
+			}
+		})
+	}
+}
+
+func TestEmbeddedStaticAssets(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, staticAssets.URL("jobs.js"), nil)
+	w := httptest.NewRecorder()
+	staticAssets.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") {
+		t.Errorf("Content-Type = %q, want text/javascript", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestTemplateDirStaticAssets(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "static"), 0755)
+	os.WriteFile(filepath.Join(dir, "static", "theme.css"), []byte("body { color: green; }"), 0644)
+
+	assets, err := NewStaticAssets(staticFS(dir))
+	if err != nil {
+		t.Fatalf("NewStaticAssets() error = %v", err)
+	}
+
+	// The theme's files are served alongside the embedded ones
+	for name, want := range map[string]string{"theme.css": "body { color: green; }", "jobs.js": ""} {
+		assetURL := assets.URL(name)
+		if !strings.Contains(assetURL, "?v=") {
+			t.Errorf("URL(%q) = %q, want a versioned URL", name, assetURL)
+		}
+		req := httptest.NewRequest(http.MethodGet, assetURL, nil)
+		w := httptest.NewRecorder()
+		assets.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), want) {
+			t.Errorf("GET %s = %v %q", assetURL, w.Code, w.Body.String())
+		}
+	}
+
+	// Without a static directory only the embedded files are served
+	assets, err = NewStaticAssets(staticFS(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewStaticAssets() without a static directory error = %v", err)
+	}
+	if assets.URL("jobs.js") != staticAssets.URL("jobs.js") {
+		t.Errorf("URL(jobs.js) = %q, want the embedded version", assets.URL("jobs.js"))
+	}
+}
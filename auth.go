@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// sessionCookieName is the name of the cookie holding the session token
+	sessionCookieName = "shelf_session"
+
+	// sessionLifetime is how long a login lasts before the user must sign in again
+	sessionLifetime = 30 * 24 * time.Hour
+)
+
+// AnonymousAccess controls what unauthenticated visitors may do
+type AnonymousAccess string
+
+const (
+	AnonymousNone     AnonymousAccess = "none"      // Redirect to the login page
+	AnonymousReadOnly AnonymousAccess = "read-only" // Browse the library without changing anything
+)
+
+// ParseAnonymousAccess parses the AUTH_ANONYMOUS_ACCESS setting
+func ParseAnonymousAccess(value string) (AnonymousAccess, error) {
+	switch AnonymousAccess(value) {
+	case "", AnonymousNone:
+		return AnonymousNone, nil
+	case AnonymousReadOnly:
+		return AnonymousReadOnly, nil
+	default:
+		return "", fmt.Errorf("invalid anonymous access mode %q (expected %q or %q)", value, AnonymousNone, AnonymousReadOnly)
+	}
+}
+
+// Role determines what a signed-in user may change. Each role includes the
+// permissions of the roles before it.
+type Role string
+
+const (
+	RoleViewer  Role = "viewer"  // Browse the library and copy play commands
+	RoleCurator Role = "curator" // Also set TMDB IDs, posters and metadata
+	RoleAdmin   Role = "admin"   // Also import, delete and reorganise media
+)
+
+// roleRanks orders the roles from least to most privileged
+var roleRanks = map[Role]int{
+	RoleViewer:  1,
+	RoleCurator: 2,
+	RoleAdmin:   3,
+}
+
+// ParseRole parses a role name. An empty value means a viewer.
+func ParseRole(value string) (Role, error) {
+	if value == "" {
+		return RoleViewer, nil
+	}
+	role := Role(value)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("invalid role %q (expected %q, %q or %q)", value, RoleViewer, RoleCurator, RoleAdmin)
+	}
+	return role, nil
+}
+
+// Allows reports whether the role has the permissions of required
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// User is an account that can sign in
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash,omitempty"` // bcrypt hash
+	Role         Role   `json:"role,omitempty"`          // Defaults to viewer
+}
+
+// usersFile is the JSON layout of the users file
+type usersFile struct {
+	Users []User `json:"users"`
+}
+
+// UserStore holds the local user accounts
+type UserStore struct {
+	users map[string]User
+}
+
+// dummyPasswordHash is compared against when a username is unknown, so failed
+// logins take the same time whether or not the account exists
+var dummyPasswordHash = []byte("$2a$10$bkcbEEnBmRzIoR48UIenHe8eNoXbQuoeFEYW.vdgkw5Yu83r5pdnW")
+
+// LoadUserStore reads user accounts from a JSON users file
+func LoadUserStore(path string) (*UserStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+
+	var file usersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse users file: %w", err)
+	}
+
+	store := &UserStore{users: make(map[string]User)}
+	for _, user := range file.Users {
+		if user.Username == "" {
+			return nil, fmt.Errorf("users file contains a user without a username")
+		}
+		if _, exists := store.users[user.Username]; exists {
+			return nil, fmt.Errorf("users file contains duplicate user %q", user.Username)
+		}
+		if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+			return nil, fmt.Errorf("user %q has an invalid bcrypt password hash", user.Username)
+		}
+		role, err := ParseRole(string(user.Role))
+		if err != nil {
+			return nil, fmt.Errorf("user %q: %w", user.Username, err)
+		}
+		user.Role = role
+		store.users[user.Username] = user
+	}
+	return store, nil
+}
+
+// Lookup returns the user with the given username
+func (s *UserStore) Lookup(username string) (User, bool) {
+	user, ok := s.users[username]
+	return user, ok
+}
+
+// Authenticate checks a username and password
+func (s *UserStore) Authenticate(username, password string) (User, bool) {
+	user, ok := s.users[username]
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return User{}, false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return User{}, false
+	}
+	return user, true
+}
+
+// HashPassword returns the bcrypt hash of a password for the users file
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", fmt.Errorf("password must not be empty")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Session is a signed-in browser session
+type Session struct {
+	Username string
+	Expires  time.Time
+}
+
+// SessionStore keeps track of signed-in sessions by their random token
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewSessionStore creates an empty session store
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: make(map[string]Session)}
+}
+
+// Create starts a session for username and returns its token
+func (s *SessionStore) Create(username string) (string, Session, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", Session{}, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	session := Session{Username: username, Expires: time.Now().Add(sessionLifetime)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop expired sessions while we hold the lock
+	now := time.Now()
+	for t, existing := range s.sessions {
+		if now.After(existing.Expires) {
+			delete(s.sessions, t)
+		}
+	}
+	s.sessions[token] = session
+	return token, session, nil
+}
+
+// Get returns the unexpired session for a token
+func (s *SessionStore) Get(token string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[token]
+	if !ok {
+		return Session{}, false
+	}
+	if time.Now().After(session.Expires) {
+		delete(s.sessions, token)
+		return Session{}, false
+	}
+	return session, true
+}
+
+// Delete ends a session
+func (s *SessionStore) Delete(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, token)
+}
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and CIDR ranges
+func ParseTrustedProxies(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address %q", entry)
+			}
+			bits := 128
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range %q", entry)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// AuthConfig configures authentication
+type AuthConfig struct {
+	Users           *UserStore   // Local accounts; nil disables password login
+	TrustedHeader   string       // Header set by an SSO reverse proxy with the username
+	TrustedProxies  []*net.IPNet // Addresses allowed to set TrustedHeader
+	DefaultRole     Role         // Role of TrustedHeader users missing from Users
+	AnonymousAccess AnonymousAccess
+}
+
+// Authenticator identifies the user behind each request
+type Authenticator struct {
+	config   AuthConfig
+	sessions *SessionStore
+}
+
+// NewAuthenticator creates an authenticator for the given configuration
+func NewAuthenticator(config AuthConfig) (*Authenticator, error) {
+	if config.Users == nil && config.TrustedHeader == "" {
+		return nil, fmt.Errorf("authentication needs a users file or a trusted header")
+	}
+	if config.TrustedHeader != "" && len(config.TrustedProxies) == 0 {
+		return nil, fmt.Errorf("a trusted header requires at least one trusted proxy address")
+	}
+	if config.AnonymousAccess == "" {
+		config.AnonymousAccess = AnonymousNone
+	}
+	if config.DefaultRole == "" {
+		config.DefaultRole = RoleViewer
+	}
+	return &Authenticator{config: config, sessions: NewSessionStore()}, nil
+}
+
+// authenticatorFromEnv builds the authenticator from the AUTH_* environment variables.
+// Returns nil when neither a users file nor a trusted header is configured.
+func authenticatorFromEnv(getenv func(string) string) (*Authenticator, error) {
+	var config AuthConfig
+
+	if usersFile := getenv("AUTH_USERS_FILE"); usersFile != "" {
+		users, err := LoadUserStore(usersFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Users = users
+	}
+
+	config.TrustedHeader = getenv("AUTH_TRUSTED_HEADER")
+	proxies, err := ParseTrustedProxies(getenv("AUTH_TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
+	config.TrustedProxies = proxies
+
+	config.DefaultRole, err = ParseRole(getenv("AUTH_DEFAULT_ROLE"))
+	if err != nil {
+		return nil, err
+	}
+
+	config.AnonymousAccess, err = ParseAnonymousAccess(getenv("AUTH_ANONYMOUS_ACCESS"))
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Users == nil && config.TrustedHeader == "" {
+		return nil, nil
+	}
+	return NewAuthenticator(config)
+}
+
+// contextKey is the type of request context keys set by this package
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	csrfTokenContextKey
+)
+
+// userFromContext returns the signed-in user, or nil for anonymous requests
+func userFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userContextKey).(*User)
+	return user
+}
+
+// identify returns the user making the request, or nil if they aren't signed in
+func (a *Authenticator) identify(r *http.Request) *User {
+	// An SSO proxy's header is only trusted when the request comes from the proxy
+	if a.config.TrustedHeader != "" && a.fromTrustedProxy(r) {
+		if username := strings.TrimSpace(r.Header.Get(a.config.TrustedHeader)); username != "" {
+			if a.config.Users != nil {
+				if user, ok := a.config.Users.Lookup(username); ok {
+					return &user
+				}
+			}
+			return &User{Username: username, Role: a.config.DefaultRole}
+		}
+	}
+
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil
+	}
+	session, ok := a.sessions.Get(cookie.Value)
+	if !ok {
+		return nil
+	}
+	if a.config.Users != nil {
+		if user, ok := a.config.Users.Lookup(session.Username); ok {
+			return &user
+		}
+	}
+	// The account was removed since the session started
+	return nil
+}
+
+// fromTrustedProxy reports whether the request's remote address is a trusted proxy
+func (a *Authenticator) fromTrustedProxy(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range a.config.TrustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware identifies the user behind each request and adds them to the
+// request context. Access is checked per route by Require.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := a.identify(r); user != nil {
+			r = r.WithContext(context.WithValue(r.Context(), userContextKey, user))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Allows reports whether the request's user has the permissions of role.
+// Anonymous visitors count as viewers when read-only access is enabled.
+func (a *Authenticator) Allows(r *http.Request, role Role) bool {
+	if user := userFromContext(r.Context()); user != nil {
+		return user.Role.Allows(role)
+	}
+	return a.config.AnonymousAccess == AnonymousReadOnly && role == RoleViewer
+}
+
+// Require only lets requests through from users with the permissions of role
+func (a *Authenticator) Require(role Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Allows(r, role) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if userFromContext(r.Context()) != nil {
+			http.Error(w, "You don't have permission to do that", http.StatusForbidden)
+			return
+		}
+
+		// Send browsers to the login page; scripts and form posts get a plain error
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && !strings.Contains(r.Header.Get("Accept"), "application/json") {
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+	})
+}
+
+// safeRedirectTarget returns next if it's a local path, otherwise "/".
+// This stops the login form being used to redirect to other sites.
+func safeRedirectTarget(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// setSessionCookie sends the session cookie to the browser
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearSessionCookie removes the session cookie from the browser
+func clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
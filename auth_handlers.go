@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// LoginHandler shows the login form and signs users in
+func (app *App) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	// Nothing to sign in to when authentication is disabled
+	if app.auth == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	next := safeRedirectTarget(r.FormValue("next"))
+
+	// Already signed in
+	if userFromContext(r.Context()) != nil && r.Method != http.MethodPost {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+
+	var errorMsg string
+	status := http.StatusOK
+
+	if r.Method == http.MethodPost {
+		if app.auth.config.Users == nil {
+			http.Error(w, "Password login is disabled", http.StatusForbidden)
+			return
+		}
+
+		username := r.FormValue("username")
+		user, ok := app.auth.config.Users.Authenticate(username, r.FormValue("password"))
+		if ok {
+			token, session, err := app.auth.sessions.Create(user.Username)
+			if err != nil {
+				log.Printf("Failed to create session for %s: %v", user.Username, err)
+				http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+				return
+			}
+			setSessionCookie(w, r, token, session.Expires)
+			// Forms from before signing in stop working, with the new session's
+			// CSRF token replacing the anonymous one
+			setCSRFCookie(w, r)
+			log.Printf("User %s signed in", user.Username)
+			http.Redirect(w, r, next, http.StatusSeeOther)
+			return
+		}
+
+		log.Printf("Failed sign in attempt for %q from %s", username, r.RemoteAddr)
+		errorMsg = "Incorrect username or password"
+		status = http.StatusUnauthorized
+	} else if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
+
+	data := struct {
+		Next            string
+		Error           string
+		PasswordLogin   bool
+		AnonymousAccess bool
+	}{
+		Next:            next,
+		Error:           errorMsg,
+		PasswordLogin:   app.auth.config.Users != nil,
+		AnonymousAccess: app.auth.config.AnonymousAccess == AnonymousReadOnly,
+	}
+
+	w.WriteHeader(status)
+	err := tmpl.ExecuteTemplate(w, "login.html", data)
+	if err != nil {
+		log.Printf("Error rendering login template: %v", err)
+		return
+	}
+}
+
+// LogoutHandler ends the current session
+func (app *App) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if app.auth != nil {
+		if cookie, err := r.Cookie(sessionCookieName); err == nil {
+			app.auth.sessions.Delete(cookie.Value)
+		}
+	}
+	clearSessionCookie(w, r)
+	setCSRFCookie(w, r)
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
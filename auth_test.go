@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// writeUsersFile writes a users file with the given username/password pairs.
+// Passwords are hashed at the minimum cost to keep tests fast.
+func writeUsersFile(t *testing.T, passwords map[string]string) string {
+	t.Helper()
+
+	var file usersFile
+	for username, password := range passwords {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		if err != nil {
+			t.Fatalf("Failed to hash password: %v", err)
+		}
+		file.Users = append(file.Users, User{Username: username, PasswordHash: string(hash)})
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatalf("Failed to encode users file: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write users file: %v", err)
+	}
+	return path
+}
+
+// newAuthTestApp creates an app over the test media with authentication enabled
+func newAuthTestApp(t *testing.T, config AuthConfig) *App {
+	t.Helper()
+
+	tmpl, err := parseTemplates(templateFS(""))
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+	testDir := setupTestData(t)
+	mediaList, err := NewScanner(testDir).Scan()
+	if err != nil {
+		t.Fatalf("Failed to scan test data: %v", err)
+	}
+
+	auth, err := NewAuthenticator(config)
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+	app := NewApp(mediaList, tmpl, testDir, "")
+	app.SetAuthenticator(auth)
+	return app
+}
+
+func TestLoadUserStore(t *testing.T) {
+	path := writeUsersFile(t, map[string]string{"sam": "secret"})
+	store, err := LoadUserStore(path)
+	if err != nil {
+		t.Fatalf("LoadUserStore() error = %v", err)
+	}
+	if _, ok := store.Lookup("sam"); !ok {
+		t.Error("Lookup(sam) not found")
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid JSON", "{"},
+		{"missing username", `{"users": [{"password_hash": "$2a$04$abcdefghijklmnopqrstuu"}]}`},
+		{"invalid hash", `{"users": [{"username": "sam", "password_hash": "secret"}]}`},
+		{"duplicate user", `{"users": [{"username": "sam", "password_hash": "` + string(dummyPasswordHash) + `"}, {"username": "sam", "password_hash": "` + string(dummyPasswordHash) + `"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "users.json")
+			os.WriteFile(path, []byte(tt.content), 0600)
+			if _, err := LoadUserStore(path); err == nil {
+				t.Error("LoadUserStore() expected error, got nil")
+			}
+		})
+	}
+
+	if _, err := LoadUserStore(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadUserStore() missing file expected error, got nil")
+	}
+}
+
+func TestUserStoreAuthenticate(t *testing.T) {
+	store, err := LoadUserStore(writeUsersFile(t, map[string]string{"sam": "secret"}))
+	if err != nil {
+		t.Fatalf("LoadUserStore() error = %v", err)
+	}
+
+	if user, ok := store.Authenticate("sam", "secret"); !ok || user.Username != "sam" {
+		t.Errorf("Authenticate(sam, secret) = %v, %v, want sam, true", user, ok)
+	}
+	if _, ok := store.Authenticate("sam", "wrong"); ok {
+		t.Error("Authenticate() accepted a wrong password")
+	}
+	if _, ok := store.Authenticate("alex", "secret"); ok {
+		t.Error("Authenticate() accepted an unknown user")
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("secret")); err != nil {
+		t.Errorf("HashPassword() hash does not match password: %v", err)
+	}
+	if _, err := HashPassword(""); err == nil {
+		t.Error("HashPassword(\"\") expected error, got nil")
+	}
+}
+
+func TestRunHashPassword(t *testing.T) {
+	var out bytes.Buffer
+	if err := runHashPassword(strings.NewReader("secret\n"), &out); err != nil {
+		t.Fatalf("runHashPassword() error = %v", err)
+	}
+	hash := strings.TrimSpace(out.String())
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("secret")); err != nil {
+		t.Errorf("runHashPassword() printed %q which does not match the password", hash)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	nets, err := ParseTrustedProxies("127.0.0.1, 10.0.0.0/8,::1")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+	if len(nets) != 3 {
+		t.Fatalf("ParseTrustedProxies() returned %d networks, want 3", len(nets))
+	}
+	if nets[0].String() != "127.0.0.1/32" || nets[1].String() != "10.0.0.0/8" || nets[2].String() != "::1/128" {
+		t.Errorf("ParseTrustedProxies() = %v", nets)
+	}
+
+	for _, invalid := range []string{"not-an-ip", "10.0.0.0/33"} {
+		if _, err := ParseTrustedProxies(invalid); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) expected error, got nil", invalid)
+		}
+	}
+}
+
+func TestParseAnonymousAccess(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    AnonymousAccess
+		wantErr bool
+	}{
+		{"", AnonymousNone, false},
+		{"none", AnonymousNone, false},
+		{"read-only", AnonymousReadOnly, false},
+		{"everything", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAnonymousAccess(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAnonymousAccess(%q) = %q, %v, want %q (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAuthenticatorFromEnv(t *testing.T) {
+	usersPath := writeUsersFile(t, map[string]string{"sam": "secret"})
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		wantNil  bool
+		wantErr  bool
+		wantMode AnonymousAccess
+	}{
+		{"disabled", map[string]string{}, true, false, ""},
+		{"users file", map[string]string{"AUTH_USERS_FILE": usersPath}, false, false, AnonymousNone},
+		{"read-only", map[string]string{"AUTH_USERS_FILE": usersPath, "AUTH_ANONYMOUS_ACCESS": "read-only"}, false, false, AnonymousReadOnly},
+		{"trusted header", map[string]string{"AUTH_TRUSTED_HEADER": "X-Remote-User", "AUTH_TRUSTED_PROXIES": "127.0.0.1"}, false, false, AnonymousNone},
+		{"header without proxies", map[string]string{"AUTH_TRUSTED_HEADER": "X-Remote-User"}, false, true, ""},
+		{"missing users file", map[string]string{"AUTH_USERS_FILE": "/nonexistent/users.json"}, false, true, ""},
+		{"invalid default role", map[string]string{"AUTH_USERS_FILE": usersPath, "AUTH_DEFAULT_ROLE": "owner"}, false, true, ""},
+		{"invalid anonymous mode", map[string]string{"AUTH_USERS_FILE": usersPath, "AUTH_ANONYMOUS_ACCESS": "all"}, false, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := authenticatorFromEnv(func(key string) string { return tt.env[key] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("authenticatorFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (auth == nil) != tt.wantNil {
+				t.Fatalf("authenticatorFromEnv() = %v, want nil %v", auth, tt.wantNil)
+			}
+			if auth != nil && auth.config.AnonymousAccess != tt.wantMode {
+				t.Errorf("AnonymousAccess = %q, want %q", auth.config.AnonymousAccess, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRequiresLogin(t *testing.T) {
+	users, _ := LoadUserStore(writeUsersFile(t, map[string]string{"sam": "secret"}))
+	app := newAuthTestApp(t, AuthConfig{Users: users})
+	handler := app.routes()
+
+	// Browsers are sent to the login page
+	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("GET status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+	if loc := w.Header().Get("Location"); loc != "/login?next="+url.QueryEscape("/media/war-of-the-worlds-2025") {
+		t.Errorf("GET redirect = %q, want login page", loc)
+	}
+
+	// Form posts and scripts get an error
+	req = httptest.NewRequest(http.MethodPost, "/rescan", nil)
+	addCSRFToken(t, req)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("POST status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+
+	// The login page and static files are public
+	for _, path := range []string{"/login", "/static/jobs.js"} {
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s status = %v, want %v", path, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestMiddlewareAnonymousReadOnly(t *testing.T) {
+	users, _ := LoadUserStore(writeUsersFile(t, map[string]string{"sam": "secret"}))
+	app := newAuthTestApp(t, AuthConfig{Users: users, AnonymousAccess: AnonymousReadOnly})
+	handler := app.routes()
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/", http.StatusOK},
+		{http.MethodGet, "/media/war-of-the-worlds-2025", http.StatusOK},
+		{http.MethodGet, "/media/war-of-the-worlds-2025/edit", http.StatusSeeOther},
+		{http.MethodGet, "/import", http.StatusSeeOther},
+		{http.MethodPost, "/rescan", http.StatusUnauthorized},
+		{http.MethodPost, "/media/war-of-the-worlds-2025/edit", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		addCSRFToken(t, req)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s status = %v, want %v", tt.method, tt.path, w.Code, tt.want)
+		}
+	}
+
+	// Anonymous visitors are offered a sign in link
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), `href="/login"`) {
+		t.Error("Index page does not link to the login page for anonymous visitors")
+	}
+}
+
+func TestMiddlewareTrustedHeader(t *testing.T) {
+	proxies, _ := ParseTrustedProxies("10.0.0.1")
+	app := newAuthTestApp(t, AuthConfig{TrustedHeader: "X-Remote-User", TrustedProxies: proxies})
+	handler := app.routes()
+
+	// Accepted from the proxy
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:54321"
+	req.Header.Set("X-Remote-User", "alex")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Proxied request status = %v, want %v", w.Code, http.StatusOK)
+	}
+	if !strings.Contains(w.Body.String(), "alex") {
+		t.Error("Index page does not show the signed in user")
+	}
+
+	// Ignored from anywhere else
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.168.1.50:54321"
+	req.Header.Set("X-Remote-User", "alex")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Direct request with header status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+}
+
+func TestLoginAndLogout(t *testing.T) {
+	users, _ := LoadUserStore(writeUsersFile(t, map[string]string{"sam": "secret"}))
+	app := newAuthTestApp(t, AuthConfig{Users: users})
+	handler := app.routes()
+
+	// Wrong password re-renders the form
+	form := url.Values{"username": {"sam"}, "password": {"wrong"}, "next": {"/import"}}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	addCSRFToken(t, req)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Wrong password status = %v, want %v", w.Code, http.StatusUnauthorized)
+	}
+	if !strings.Contains(w.Body.String(), "Incorrect username or password") {
+		t.Error("Login page does not show the error")
+	}
+
+	// Correct password sets a session cookie and follows next
+	form.Set("password", "secret")
+	req = httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	addCSRFToken(t, req)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/import" {
+		t.Fatalf("Login = %v %q, want redirect to /import", w.Code, w.Header().Get("Location"))
+	}
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			session = c
+		}
+	}
+	if session == nil || !session.HttpOnly || session.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("Session cookie = %+v, want HttpOnly SameSite=Lax cookie", session)
+	}
+
+	// The session signs in later requests
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(session)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Sign Out") {
+		t.Errorf("Signed in index = %v, want page with Sign Out", w.Code)
+	}
+
+	// Logging out ends the session
+	req = httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(session)
+	addCSRFToken(t, req)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Errorf("Logout = %v %q, want redirect to /login", w.Code, w.Header().Get("Location"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(session)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Request after logout status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+}
+
+func TestSafeRedirectTarget(t *testing.T) {
+	tests := map[string]string{
+		"":                     "/",
+		"/media/film":          "/media/film",
+		"/import?step=1":       "/import?step=1",
+		"https://evil.example": "/",
+		"//evil.example/path":  "/",
+		"/\\evil.example":      "/",
+		"javascript:alert(1)":  "/",
+	}
+	for next, want := range tests {
+		if got := safeRedirectTarget(next); got != want {
+			t.Errorf("safeRedirectTarget(%q) = %q, want %q", next, got, want)
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Role
+		wantErr bool
+	}{
+		{"", RoleViewer, false},
+		{"viewer", RoleViewer, false},
+		{"curator", RoleCurator, false},
+		{"admin", RoleAdmin, false},
+		{"owner", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRole(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRole(%q) = %q, %v, want %q (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if !RoleAdmin.Allows(RoleCurator) || !RoleCurator.Allows(RoleCurator) || RoleViewer.Allows(RoleCurator) {
+		t.Error("Role.Allows() does not order viewer < curator < admin")
+	}
+}
+
+func TestLoadUserStoreRoles(t *testing.T) {
+	hash := string(dummyPasswordHash)
+	path := filepath.Join(t.TempDir(), "users.json")
+	os.WriteFile(path, []byte(`{"users": [
+		{"username": "sam", "password_hash": "`+hash+`", "role": "admin"},
+		{"username": "kid", "password_hash": "`+hash+`"}
+	]}`), 0600)
+
+	store, err := LoadUserStore(path)
+	if err != nil {
+		t.Fatalf("LoadUserStore() error = %v", err)
+	}
+	if user, _ := store.Lookup("sam"); user.Role != RoleAdmin {
+		t.Errorf("sam role = %q, want admin", user.Role)
+	}
+	if user, _ := store.Lookup("kid"); user.Role != RoleViewer {
+		t.Errorf("kid role = %q, want viewer by default", user.Role)
+	}
+
+	os.WriteFile(path, []byte(`{"users": [{"username": "sam", "password_hash": "`+hash+`", "role": "owner"}]}`), 0600)
+	if _, err := LoadUserStore(path); err == nil {
+		t.Error("LoadUserStore() invalid role expected error, got nil")
+	}
+}
+
+func TestRoutesEnforceRoles(t *testing.T) {
+	users := &UserStore{users: map[string]User{
+		"viewer":  {Username: "viewer", Role: RoleViewer},
+		"curator": {Username: "curator", Role: RoleCurator},
+		"admin":   {Username: "admin", Role: RoleAdmin},
+	}}
+	app := newAuthTestApp(t, AuthConfig{Users: users})
+	handler := app.routes()
+
+	cookies := make(map[string]*http.Cookie)
+	for username := range users.users {
+		token, _, err := app.auth.sessions.Create(username)
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		cookies[username] = &http.Cookie{Name: sessionCookieName, Value: token}
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		role   Role // Least privileged role allowed
+	}{
+		{http.MethodGet, "/", RoleViewer},
+		{http.MethodGet, "/media/war-of-the-worlds-2025", RoleViewer},
+		{http.MethodGet, "/media/war-of-the-worlds-2025/edit", RoleCurator},
+		{http.MethodGet, "/media/war-of-the-worlds-2025/search-tmdb", RoleCurator},
+		{http.MethodPost, "/media/war-of-the-worlds-2025/set-tmdb", RoleCurator},
+		{http.MethodGet, "/media/war-of-the-worlds-2025/posters", RoleCurator},
+		{http.MethodPost, "/media/war-of-the-worlds-2025/poster", RoleCurator},
+		{http.MethodPost, "/media/war-of-the-worlds-2025/mpv", RoleCurator},
+		{http.MethodPost, "/media/war-of-the-worlds-2025/kodi", RoleCurator},
+		{http.MethodGet, "/kodi/status", RoleCurator},
+		{http.MethodGet, "/import", RoleAdmin},
+		{http.MethodPost, "/import/execute", RoleAdmin},
+		{http.MethodPost, "/rescan", RoleAdmin},
+	}
+	for _, tt := range tests {
+		for username, cookie := range cookies {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.AddCookie(cookie)
+			addCSRFToken(t, req)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			allowed := users.users[username].Role.Allows(tt.role)
+			if forbidden := w.Code == http.StatusForbidden; forbidden == allowed {
+				t.Errorf("%s %s as %s status = %v, want allowed %v", tt.method, tt.path, username, w.Code, allowed)
+			}
+		}
+	}
+}
+
+func TestTemplatesHideControlsByRole(t *testing.T) {
+	users := &UserStore{users: map[string]User{
+		"viewer":  {Username: "viewer", Role: RoleViewer},
+		"curator": {Username: "curator", Role: RoleCurator},
+		"admin":   {Username: "admin", Role: RoleAdmin},
+	}}
+	app := newAuthTestApp(t, AuthConfig{Users: users})
+	app.importScanner = NewImportScanner(t.TempDir())
+	mpv, err := NewMPVClient("tcp://127.0.0.1:1")
+	if err != nil {
+		t.Fatalf("NewMPVClient() error = %v", err)
+	}
+	app.SetMPVClient(mpv, PathMapping{})
+	app.SetKodiClients([]*KodiClient{NewKodiClient(KodiInstance{Name: "Living room", URL: "http://127.0.0.1:1/jsonrpc"})})
+	handler := app.routes()
+
+	get := func(username, path string) string {
+		token, _, _ := app.auth.sessions.Create(username)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s as %s status = %v, want %v", path, username, w.Code, http.StatusOK)
+		}
+		return w.Body.String()
+	}
+
+	tests := []struct {
+		username string
+		path     string
+		control  string
+		visible  bool
+	}{
+		{"viewer", "/", "Import Media", false},
+		{"viewer", "/", "Rescan Library", false},
+		{"curator", "/", "Import Media", false},
+		{"admin", "/", "Import Media", true},
+		{"admin", "/", "Rescan Library", true},
+		{"viewer", "/media/war-of-the-worlds-2025", "Edit metadata", false},
+		{"viewer", "/media/war-of-the-worlds-2025", "/search-tmdb", false},
+		{"curator", "/media/war-of-the-worlds-2025", "Edit metadata", true},
+		{"curator", "/media/war-of-the-worlds-2025", "/posters", true},
+		{"viewer", "/media/war-of-the-worlds-2025", "Play in MPV", false},
+		{"curator", "/media/war-of-the-worlds-2025", "Play in MPV", true},
+		{"viewer", "/media/war-of-the-worlds-2025", "Play on…", false},
+		{"curator", "/media/war-of-the-worlds-2025", "Play on…", true},
+	}
+	for _, tt := range tests {
+		body := get(tt.username, tt.path)
+		if strings.Contains(body, tt.control) != tt.visible {
+			t.Errorf("GET %s as %s shows %q = %v, want %v", tt.path, tt.username, tt.control, !tt.visible, tt.visible)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// collectionFileName is the file a film's TMDB collection is saved in
+const collectionFileName = "collection.json"
+
+// Collection is a TMDB collection a film belongs to, such as a trilogy
+type Collection struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	PosterPath string `json:"poster_path,omitempty"`
+}
+
+// CollectionPart is a film in a TMDB collection
+type CollectionPart struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	ReleaseDate string `json:"release_date"`
+	PosterPath  string `json:"poster_path"`
+}
+
+// Year returns the year the film was released, or 0 if it hasn't a date
+func (p CollectionPart) Year() int {
+	if len(p.ReleaseDate) < 4 {
+		return 0
+	}
+	year, _ := strconv.Atoi(p.ReleaseDate[:4])
+	return year
+}
+
+// Released reports whether the film is out, as of now
+func (p CollectionPart) Released(now time.Time) bool {
+	return p.ReleaseDate != "" && p.ReleaseDate <= now.Format("2006-01-02")
+}
+
+// CollectionResponse represents the TMDB API response for a collection
+type CollectionResponse struct {
+	ID         int              `json:"id"`
+	Name       string           `json:"name"`
+	PosterPath string           `json:"poster_path"`
+	Parts      []CollectionPart `json:"parts"`
+}
+
+// FetchCollection fetches a collection and the films in it from TMDB
+func (c *TMDBClient) FetchCollection(ctx context.Context, collectionID int) (*CollectionResponse, error) {
+	collectionURL := fmt.Sprintf("%s/collection/%d?api_key=%s", c.apiBaseURL, collectionID, c.apiKey)
+
+	var collection CollectionResponse
+	if err := c.getJSON(ctx, withLanguage(collectionURL, c.language), &collection); err != nil {
+		return nil, fmt.Errorf("failed to fetch collection %d: %w", collectionID, err)
+	}
+
+	// Films without a release date are unannounced, so they go last
+	slices.SortStableFunc(collection.Parts, func(a, b CollectionPart) int {
+		switch {
+		case a.ReleaseDate == b.ReleaseDate:
+			return 0
+		case a.ReleaseDate == "":
+			return 1
+		case b.ReleaseDate == "":
+			return -1
+		}
+		return strings.Compare(a.ReleaseDate, b.ReleaseDate)
+	})
+	return &collection, nil
+}
+
+// LoadCollection reads the collection saved in collection.json, if the film
+// belongs to one
+func (m *Media) LoadCollection() *Collection {
+	data, err := os.ReadFile(filepath.Join(m.Path, collectionFileName))
+	if err != nil {
+		return nil
+	}
+	var collection Collection
+	if err := json.Unmarshal(data, &collection); err != nil || collection.ID == 0 {
+		log.Printf("Warning: Ignoring invalid %s for %s", collectionFileName, m.Title)
+		return nil
+	}
+	return &collection
+}
+
+// saveCollection writes a film's collection to collection.json in the media
+// directory, reporting whether the file changed. A nil collection removes
+// the file, for films TMDB no longer puts in one.
+func saveCollection(mediaPath string, collection *Collection) (bool, error) {
+	path := filepath.Join(mediaPath, collectionFileName)
+	if collection == nil {
+		err := os.Remove(path)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	data, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return false, fmt.Errorf("failed to save collection: %w", err)
+	}
+	return true, nil
+}
+
+// collectionGroup is a collection on the collections page: the films in it
+// the library has, and those it's missing
+type collectionGroup struct {
+	Collection
+	Owned   []*Media
+	Missing []CollectionPart
+	Error   string // Why the missing films couldn't be listed
+}
+
+// Complete reports whether the library has every released film in the
+// collection
+func (g collectionGroup) Complete() bool {
+	return g.Error == "" && len(g.Missing) == 0
+}
+
+// Total returns how many released films the collection has, as far as is known
+func (g collectionGroup) Total() int {
+	return len(g.Owned) + len(g.Missing)
+}
+
+// collectionFetchTimeout bounds how long the collections page waits on TMDB
+// to list the films it's missing
+const collectionFetchTimeout = 10 * time.Second
+
+// collectionGroups groups the library's films by the collections they belong
+// to, in name order, and asks TMDB which of each collection's released films
+// are missing
+func (app *App) collectionGroups(ctx context.Context, language string) []collectionGroup {
+	var groups []collectionGroup
+	for _, media := range app.allMedia() {
+		if media.Type != Film {
+			continue
+		}
+		collection := media.LoadCollection()
+		if collection == nil {
+			continue
+		}
+		localized := localizeMedia(media, language)
+		i := slices.IndexFunc(groups, func(g collectionGroup) bool { return g.ID == collection.ID })
+		if i < 0 {
+			groups = append(groups, collectionGroup{Collection: *collection})
+			i = len(groups) - 1
+		}
+		groups[i].Owned = append(groups[i].Owned, &localized)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, collectionFetchTimeout)
+	defer cancel()
+
+	// List every collection at once
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i := range groups {
+		group := &groups[i]
+		slices.SortStableFunc(group.Owned, func(a, b *Media) int { return a.Year - b.Year })
+
+		if app.tmdbClient == nil {
+			group.Error = "TMDB is not configured"
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			remote, err := app.tmdbClient.FetchCollection(ctx, group.ID)
+			if err != nil {
+				log.Printf("Warning: Failed to list collection %s: %v", group.Name, err)
+				group.Error = tmdbErrorMessage(err)
+				return
+			}
+			if remote.Name != "" {
+				group.Name = remote.Name
+			}
+			for _, part := range remote.Parts {
+				owned := slices.ContainsFunc(group.Owned, func(m *Media) bool { return m.TMDBID == strconv.Itoa(part.ID) })
+				if !owned && part.Released(now) {
+					group.Missing = append(group.Missing, part)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.SortStableFunc(groups, func(a, b collectionGroup) int { return strings.Compare(a.Name, b.Name) })
+	return groups
+}
+
+// CollectionsHandler lists the collections films in the library belong to,
+// with the films from each that the library is missing
+func (app *App) CollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := app.templatesFor(r)
+	groups := app.collectionGroups(r.Context(), app.metadataLanguageFor(r))
+
+	if wantsJSON(r) {
+		type filmJSON struct {
+			TMDBID string `json:"tmdb_id"`
+			Title  string `json:"title"`
+			Year   int    `json:"year,omitempty"`
+			URL    string `json:"url,omitempty"`
+		}
+		type collectionJSON struct {
+			ID      int        `json:"id"`
+			Name    string     `json:"name"`
+			Owned   []filmJSON `json:"owned"`
+			Missing []filmJSON `json:"missing"`
+			Error   string     `json:"error,omitempty"`
+		}
+		list := make([]collectionJSON, 0, len(groups))
+		for _, group := range groups {
+			collection := collectionJSON{ID: group.ID, Name: group.Name, Owned: []filmJSON{}, Missing: []filmJSON{}, Error: group.Error}
+			for _, media := range group.Owned {
+				collection.Owned = append(collection.Owned, filmJSON{
+					TMDBID: media.TMDBID,
+					Title:  media.DisplayTitle(),
+					Year:   media.Year,
+					URL:    "/media/" + url.PathEscape(media.Slug()),
+				})
+			}
+			for _, part := range group.Missing {
+				collection.Missing = append(collection.Missing, filmJSON{TMDBID: strconv.Itoa(part.ID), Title: part.Title, Year: part.Year()})
+			}
+			list = append(list, collection)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	missing := 0
+	for _, group := range groups {
+		missing += len(group.Missing)
+	}
+	data := struct {
+		Collections []collectionGroup
+		Missing     int
+	}{
+		Collections: groups,
+		Missing:     missing,
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "collections.html", data); err != nil {
+		log.Printf("Error rendering collections template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
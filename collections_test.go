@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchCollection(t *testing.T) {
+	client := newFakeTMDBClient(t, builtinTMDBFixtures(t))
+
+	collection, err := client.FetchCollection(context.Background(), 2344)
+	if err != nil {
+		t.Fatalf("FetchCollection() error = %v", err)
+	}
+	var titles []string
+	for _, part := range collection.Parts {
+		titles = append(titles, part.Title)
+	}
+	want := "The Matrix, The Matrix Reloaded, The Matrix Revolutions, The Matrix Resurrections, Untitled Matrix Film"
+	if strings.Join(titles, ", ") != want {
+		t.Errorf("Parts = %v, want release order with the undated film last", titles)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !collection.Parts[0].Released(now) || collection.Parts[4].Released(now) {
+		t.Error("Released() doesn't tell released films from unannounced ones")
+	}
+	if year := collection.Parts[3].Year(); year != 2021 {
+		t.Errorf("Year() = %d, want 2021", year)
+	}
+
+	if _, err := client.FetchCollection(context.Background(), 999); err == nil {
+		t.Error("FetchCollection() of an unknown collection succeeded")
+	}
+}
+
+func TestFetchAndSaveMetadataSavesCollection(t *testing.T) {
+	client := newFakeTMDBClient(t, builtinTMDBFixtures(t))
+	ctx := context.Background()
+
+	matrix := &Media{Title: "The Matrix", Type: Film, Path: t.TempDir(), TMDBID: "603"}
+	if err := client.FetchAndSaveMetadata(ctx, matrix); err != nil {
+		t.Fatalf("FetchAndSaveMetadata() error = %v", err)
+	}
+	if collection := matrix.LoadCollection(); collection == nil || collection.ID != 2344 || collection.Name != "The Matrix Collection" {
+		t.Errorf("LoadCollection() = %+v, want The Matrix Collection", collection)
+	}
+
+	fightClub := &Media{Title: "Fight Club", Type: Film, Path: t.TempDir(), TMDBID: "550"}
+	if err := client.FetchAndSaveMetadata(ctx, fightClub); err != nil {
+		t.Fatalf("FetchAndSaveMetadata() error = %v", err)
+	}
+	if collection := fightClub.LoadCollection(); collection != nil {
+		t.Errorf("LoadCollection() = %+v for a film in no collection", collection)
+	}
+
+	// A film fetched before collections were saved gets its collection too
+	older := &Media{Title: "The Matrix", Type: Film, Path: t.TempDir(), TMDBID: "603"}
+	for _, name := range []string{"poster.jpg", "description.txt", "genre.txt", "title.txt"} {
+		os.WriteFile(filepath.Join(older.Path, name), []byte("existing"), 0644)
+	}
+	if err := client.FetchAndSaveMetadata(ctx, older); err != nil {
+		t.Fatalf("FetchAndSaveMetadata() error = %v", err)
+	}
+	if collection := older.LoadCollection(); collection == nil || collection.ID != 2344 {
+		t.Errorf("LoadCollection() = %+v after backfilling, want The Matrix Collection", collection)
+	}
+
+	// A film dropped from its collection loses the file
+	if changed, err := saveCollection(matrix.Path, nil); err != nil || !changed {
+		t.Errorf("saveCollection(nil) = %v, %v, want the file removed", changed, err)
+	}
+	if changed, err := saveCollection(matrix.Path, nil); err != nil || changed {
+		t.Errorf("saveCollection(nil) again = %v, %v", changed, err)
+	}
+}
+
+// newCollectionsTestApp returns an app on the fake TMDB server whose library
+// has the first two Matrix films
+func newCollectionsTestApp(t *testing.T) *App {
+	t.Helper()
+
+	app, _, _ := setupAppWithMockTMDB(t, func(testDir string) {
+		matrix := Collection{ID: 2344, Name: "The Matrix Collection"}
+		for dir, tmdbID := range map[string]string{
+			"The Matrix (1999) [Film]":          "603",
+			"The Matrix Reloaded (2003) [Film]": "604",
+		} {
+			filmDir := filepath.Join(testDir, dir)
+			if err := os.MkdirAll(filepath.Join(filmDir, "Disk [Blu-Ray]"), 0755); err != nil {
+				t.Fatalf("Failed to create film: %v", err)
+			}
+			os.WriteFile(filepath.Join(filmDir, "tmdb.txt"), []byte(tmdbID), 0644)
+			if _, err := saveCollection(filmDir, &matrix); err != nil {
+				t.Fatalf("saveCollection() error = %v", err)
+			}
+		}
+	})
+	app.SetTMDBClient(newFakeTMDBClient(t, builtinTMDBFixtures(t)))
+	return app
+}
+
+func TestCollectionsHandler(t *testing.T) {
+	app := newCollectionsTestApp(t)
+	handler := app.routes()
+
+	req := httptest.NewRequest(http.MethodGet, "/collections", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Collections status = %v", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`id="collection-2344"`,
+		"Missing 2 of 4",
+		`href="/media/the-matrix-1999"`,
+		"The Matrix Revolutions (2003)",
+		"The Matrix Resurrections (2021)",
+		"/t/p/w342/t1wm4PgOQ8e4z1C6tk1yDYrb7GE.jpg",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Collections page doesn't show %q", want)
+		}
+	}
+	if strings.Contains(body, "Untitled Matrix Film") {
+		t.Error("Collections page lists an unreleased film as missing")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/collections", nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	var collections []struct {
+		ID    int `json:"id"`
+		Owned []struct {
+			TMDBID string `json:"tmdb_id"`
+		}
+		Missing []struct {
+			TMDBID string `json:"tmdb_id"`
+		}
+	}
+	if err := json.NewDecoder(w.Body).Decode(&collections); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(collections) != 1 || len(collections[0].Owned) != 2 || len(collections[0].Missing) != 2 || collections[0].Missing[0].TMDBID != "605" {
+		t.Errorf("Collections JSON = %+v", collections)
+	}
+
+	// The detail page links to the collection
+	req = httptest.NewRequest(http.MethodGet, "/media/the-matrix-reloaded-2003", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), `<a href="/collections#collection-2344">The Matrix Collection</a>`) {
+		t.Error("Detail page doesn't link to the collection")
+	}
+
+	// Without TMDB the owned films are still grouped
+	app.SetTMDBClient(nil)
+	req = httptest.NewRequest(http.MethodGet, "/collections", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if body := w.Body.String(); !strings.Contains(body, "TMDB is not configured") || !strings.Contains(body, "The Matrix Reloaded") {
+		t.Errorf("Collections page without TMDB:\n%s", body)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// creditsFileName is the file a media item's cast and crew are saved in
+	creditsFileName = "credits.json"
+
+	// maxBilledCast is how many cast members are saved, in billing order
+	maxBilledCast = 10
+)
+
+// Person is someone credited on a movie or TV show
+type Person struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Role        string `json:"role,omitempty"` // Character played, or jobs on the crew
+	ProfilePath string `json:"profile_path,omitempty"`
+}
+
+// Credits are the people behind a media item, as saved in credits.json
+type Credits struct {
+	Directors []Person `json:"directors,omitempty"`
+	Creators  []Person `json:"creators,omitempty"` // TV shows only
+	Writers   []Person `json:"writers,omitempty"`
+	Cast      []Person `json:"cast,omitempty"`
+}
+
+// IsEmpty reports whether no one is credited
+func (c Credits) IsEmpty() bool {
+	return len(c.Directors) == 0 && len(c.Creators) == 0 && len(c.Writers) == 0 && len(c.Cast) == 0
+}
+
+// roles returns what a person did on the media item, e.g. "Director" or
+// "as Neo", or nothing when they aren't credited
+func (c Credits) roles(personID int) []string {
+	var roles []string
+	for _, group := range []struct {
+		people []Person
+		label  string
+	}{
+		{c.Directors, "Director"},
+		{c.Creators, "Creator"},
+		{c.Writers, "Writer"},
+		{c.Cast, "Cast"},
+	} {
+		for _, person := range group.people {
+			if person.ID != personID {
+				continue
+			}
+			switch {
+			case group.label == "Cast" && person.Role != "":
+				roles = append(roles, "as "+person.Role)
+			case group.label == "Writer" && person.Role != "":
+				roles = append(roles, person.Role)
+			default:
+				roles = append(roles, group.label)
+			}
+		}
+	}
+	return roles
+}
+
+// person returns a credited person's details
+func (c Credits) person(personID int) (Person, bool) {
+	for _, people := range [][]Person{c.Directors, c.Creators, c.Writers, c.Cast} {
+		for _, person := range people {
+			if person.ID == personID {
+				return person, true
+			}
+		}
+	}
+	return Person{}, false
+}
+
+// CastCredit is a cast member in a TMDB credits response
+type CastCredit struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Character   string `json:"character"`
+	ProfilePath string `json:"profile_path"`
+	Order       int    `json:"order"` // Billing position, 0 first
+}
+
+// CrewCredit is a crew member's job in a TMDB credits response
+type CrewCredit struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Job         string `json:"job"`
+	Department  string `json:"department"`
+	ProfilePath string `json:"profile_path"`
+}
+
+// CreditsResponse represents the TMDB API response for a movie or TV show's
+// cast and crew
+type CreditsResponse struct {
+	Cast []CastCredit `json:"cast"`
+	Crew []CrewCredit `json:"crew"`
+}
+
+// Credits picks out the directors, writers and top-billed cast. A writer
+// credited for several jobs appears once, with the jobs joined.
+func (r *CreditsResponse) Credits() Credits {
+	var credits Credits
+	if r == nil {
+		return credits
+	}
+
+	for _, member := range r.Crew {
+		person := Person{ID: member.ID, Name: member.Name, ProfilePath: member.ProfilePath}
+		switch {
+		case member.Job == "Director":
+			if !slices.ContainsFunc(credits.Directors, func(p Person) bool { return p.ID == member.ID }) {
+				credits.Directors = append(credits.Directors, person)
+			}
+		case member.Department == "Writing":
+			i := slices.IndexFunc(credits.Writers, func(p Person) bool { return p.ID == member.ID })
+			if i < 0 {
+				person.Role = member.Job
+				credits.Writers = append(credits.Writers, person)
+			} else if !strings.Contains(credits.Writers[i].Role, member.Job) {
+				credits.Writers[i].Role += ", " + member.Job
+			}
+		}
+	}
+
+	cast := slices.Clone(r.Cast)
+	slices.SortStableFunc(cast, func(a, b CastCredit) int { return a.Order - b.Order })
+	for _, member := range cast {
+		if len(credits.Cast) == maxBilledCast {
+			break
+		}
+		credits.Cast = append(credits.Cast, Person{ID: member.ID, Name: member.Name, Role: member.Character, ProfilePath: member.ProfilePath})
+	}
+	return credits
+}
+
+// LoadCredits reads the cast and crew saved in credits.json
+func (m *Media) LoadCredits() Credits {
+	var credits Credits
+	data, err := os.ReadFile(filepath.Join(m.Path, creditsFileName))
+	if err != nil {
+		return credits
+	}
+	if err := json.Unmarshal(data, &credits); err != nil {
+		log.Printf("Warning: Ignoring invalid %s for %s: %v", creditsFileName, m.Title, err)
+		return Credits{}
+	}
+	return credits
+}
+
+// saveCredits writes the cast and crew to credits.json in the media
+// directory, reporting whether the file changed
+func saveCredits(mediaPath string, credits Credits) (bool, error) {
+	data, err := json.MarshalIndent(credits, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	path := filepath.Join(mediaPath, creditsFileName)
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return false, fmt.Errorf("failed to save credits: %w", err)
+	}
+	return true, nil
+}
+
+// personCredit is a media item a person worked on, for the person page
+type personCredit struct {
+	Media *Media
+	Roles []string
+}
+
+// PersonHandler lists every media item in the library a person is credited
+// on: /people/{id}
+func (app *App) PersonHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl := app.templatesFor(r)
+
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/people/"), "/"))
+	if err != nil || id <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var person Person
+	var found []personCredit
+	language := app.metadataLanguageFor(r)
+	for _, media := range app.allMedia() {
+		credits := media.LoadCredits()
+		roles := credits.roles(id)
+		if len(roles) == 0 {
+			continue
+		}
+		if person.ID == 0 {
+			person, _ = credits.person(id)
+			person.Role = ""
+		}
+		localized := localizeMedia(media, language)
+		found = append(found, personCredit{Media: &localized, Roles: roles})
+	}
+	if len(found) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Oldest first, as a filmography reads
+	slices.SortStableFunc(found, func(a, b personCredit) int {
+		if a.Media.Year != b.Media.Year {
+			return a.Media.Year - b.Media.Year
+		}
+		return strings.Compare(a.Media.DisplayTitle(), b.Media.DisplayTitle())
+	})
+
+	if wantsJSON(r) {
+		type mediaJSON struct {
+			Title string   `json:"title"`
+			Year  int      `json:"year,omitempty"`
+			Type  string   `json:"type"`
+			URL   string   `json:"url"`
+			Roles []string `json:"roles"`
+		}
+		list := make([]mediaJSON, 0, len(found))
+		for _, credit := range found {
+			list = append(list, mediaJSON{
+				Title: credit.Media.DisplayTitle(),
+				Year:  credit.Media.Year,
+				Type:  credit.Media.Type.String(),
+				URL:   "/media/" + credit.Media.Slug(),
+				Roles: credit.Roles,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    person.ID,
+			"name":  person.Name,
+			"media": list,
+		})
+		return
+	}
+
+	data := struct {
+		Person  Person
+		Credits []personCredit
+	}{
+		Person:  person,
+		Credits: found,
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "person.html", data); err != nil {
+		log.Printf("Error rendering person template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreditsResponse(t *testing.T) {
+	var response CreditsResponse
+	for i := 11; i >= 0; i-- {
+		response.Cast = append(response.Cast, CastCredit{ID: 100 + i, Name: fmt.Sprintf("Actor %d", i), Character: "Role", Order: i})
+	}
+	response.Crew = []CrewCredit{
+		{ID: 1, Name: "Stanley Kubrick", Job: "Director", Department: "Directing"},
+		{ID: 1, Name: "Stanley Kubrick", Job: "Screenplay", Department: "Writing"},
+		{ID: 2, Name: "Arthur C. Clarke", Job: "Screenplay", Department: "Writing"},
+		{ID: 2, Name: "Arthur C. Clarke", Job: "Novel", Department: "Writing"},
+		{ID: 1, Name: "Stanley Kubrick", Job: "Director", Department: "Directing"},
+		{ID: 3, Name: "Geoffrey Unsworth", Job: "Director of Photography", Department: "Camera"},
+	}
+
+	credits := response.Credits()
+	if len(credits.Directors) != 1 || credits.Directors[0].Name != "Stanley Kubrick" {
+		t.Errorf("Directors = %+v, want Stanley Kubrick once", credits.Directors)
+	}
+	if len(credits.Writers) != 2 || credits.Writers[1].Role != "Screenplay, Novel" {
+		t.Errorf("Writers = %+v, want two with Clarke's jobs joined", credits.Writers)
+	}
+	if len(credits.Cast) != maxBilledCast || credits.Cast[0].Name != "Actor 0" || credits.Cast[9].Name != "Actor 9" {
+		t.Errorf("Cast = %+v, want the first %d in billing order", credits.Cast, maxBilledCast)
+	}
+
+	if roles := credits.roles(1); strings.Join(roles, "; ") != "Director; Screenplay" {
+		t.Errorf("roles(1) = %v", roles)
+	}
+	if roles := credits.roles(103); strings.Join(roles, "; ") != "as Role" {
+		t.Errorf("roles(103) = %v", roles)
+	}
+	if roles := credits.roles(3); len(roles) != 0 {
+		t.Errorf("roles(3) = %v, want none for an uncredited job", roles)
+	}
+
+	var missing *CreditsResponse
+	if !missing.Credits().IsEmpty() {
+		t.Error("Credits() of no response isn't empty")
+	}
+}
+
+func TestFetchAndSaveMetadataSavesCredits(t *testing.T) {
+	client := newFakeTMDBClient(t, builtinTMDBFixtures(t))
+	ctx := context.Background()
+
+	film := &Media{Title: "The Matrix", Type: Film, Path: t.TempDir(), TMDBID: "603"}
+	if err := client.FetchAndSaveMetadata(ctx, film); err != nil {
+		t.Fatalf("FetchAndSaveMetadata() error = %v", err)
+	}
+	credits := film.LoadCredits()
+	if len(credits.Directors) != 2 || credits.Directors[0].Name != "Lana Wachowski" {
+		t.Errorf("Directors = %+v, want the Wachowskis", credits.Directors)
+	}
+	if len(credits.Cast) != 3 || credits.Cast[0].Role != "Thomas A. Anderson / Neo" {
+		t.Errorf("Cast = %+v, want Keanu Reeves first", credits.Cast)
+	}
+
+	show := &Media{Title: "Breaking Bad", Type: TV, Path: t.TempDir(), TMDBID: "1396"}
+	if err := client.FetchAndSaveMetadata(ctx, show); err != nil {
+		t.Fatalf("FetchAndSaveMetadata() error = %v", err)
+	}
+	credits = show.LoadCredits()
+	if len(credits.Creators) != 1 || credits.Creators[0].Name != "Vince Gilligan" || len(credits.Directors) != 0 {
+		t.Errorf("TV credits = %+v, want Vince Gilligan as creator", credits)
+	}
+
+	// Saved credits are kept when other metadata is fetched again
+	os.Remove(filepath.Join(film.Path, "description.txt"))
+	if _, err := saveCredits(film.Path, Credits{Cast: []Person{{ID: 1, Name: "Someone"}}}); err != nil {
+		t.Fatalf("saveCredits() error = %v", err)
+	}
+	if err := client.FetchAndSaveMetadata(ctx, film); err != nil {
+		t.Fatalf("FetchAndSaveMetadata() again error = %v", err)
+	}
+	if credits := film.LoadCredits(); len(credits.Cast) != 1 {
+		t.Errorf("Saved credits were replaced: %+v", credits)
+	}
+
+	// Unchanged credits aren't written again
+	if changed, err := saveCredits(film.Path, film.LoadCredits()); err != nil || changed {
+		t.Errorf("saveCredits() unchanged = %v, %v", changed, err)
+	}
+}
+
+func TestPersonHandler(t *testing.T) {
+	app, _ := newMatchTestApp(t)
+	media := app.allMedia()
+
+	odenkirk := Person{ID: 59410, Name: "Bob Odenkirk", ProfilePath: "/odenkirk.jpg"}
+	for _, m := range media {
+		var credits Credits
+		switch m.TMDBID {
+		case "755898":
+			cast := odenkirk
+			cast.Role = "Ray"
+			credits = Credits{Directors: []Person{{ID: 1, Name: "Rich Lee"}}, Cast: []Person{cast}}
+		case "60059":
+			cast := odenkirk
+			cast.Role = "Jimmy McGill"
+			credits = Credits{Creators: []Person{{ID: 66633, Name: "Vince Gilligan"}}, Cast: []Person{cast}}
+		default:
+			continue
+		}
+		if _, err := saveCredits(m.Path, credits); err != nil {
+			t.Fatalf("saveCredits() error = %v", err)
+		}
+	}
+	handler := app.routes()
+
+	get := func(target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// The detail page links the credits to their people
+	w := get("/media/war-of-the-worlds-2025", "")
+	body := w.Body.String()
+	if !strings.Contains(body, `<a href="/people/1">Rich Lee</a>`) || !strings.Contains(body, `<a href="/people/59410">Bob Odenkirk</a>`) {
+		t.Errorf("Detail page doesn't link the credits:\n%s", body)
+	}
+
+	w = get("/people/59410", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Person page status = %v", w.Code)
+	}
+	body = w.Body.String()
+	for _, want := range []string{"Bob Odenkirk", "2 in the library", "War of the Worlds", "as Ray", "Better Call Saul", "as Jimmy McGill", "/odenkirk.jpg"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Person page doesn't show %q", want)
+		}
+	}
+
+	w = get("/people/66633", "application/json")
+	var person struct {
+		Name  string `json:"name"`
+		Media []struct {
+			Title string   `json:"title"`
+			URL   string   `json:"url"`
+			Roles []string `json:"roles"`
+		} `json:"media"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&person); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if person.Name != "Vince Gilligan" || len(person.Media) != 1 || person.Media[0].URL != "/media/better-call-saul" || person.Media[0].Roles[0] != "Creator" {
+		t.Errorf("Person JSON = %+v", person)
+	}
+
+	for _, target := range []string{"/people/12345", "/people/kubrick", "/people/"} {
+		if w := get(target, ""); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s status = %v, want 404", target, w.Code)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronAliases are the shorthand schedules accepted in place of five fields
+var cronAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// cronSearchYears bounds the search for the next run, so a schedule that can
+// never fire (such as February 30th) doesn't loop forever
+const cronSearchYears = 5
+
+// CronSchedule is a parsed cron expression: minute, hour, day of month,
+// month and day of week
+type CronSchedule struct {
+	spec     string
+	minutes  [60]bool
+	hours    [24]bool
+	days     [32]bool
+	months   [13]bool
+	weekdays [7]bool
+
+	// Whether the day fields start with "*"; cron matches either day
+	// field only when both are restricted
+	anyDay     bool
+	anyWeekday bool
+}
+
+// ParseCronSchedule parses a five-field cron expression such as "0 3 * * 0",
+// or one of @hourly, @daily, @weekly, @monthly and @yearly. Fields accept
+// "*", numbers, ranges ("1-5"), steps ("*/15", "0-30/10") and lists ("1,15").
+// Day of week runs from 0 (Sunday) to 6; 7 is also accepted for Sunday.
+func ParseCronSchedule(spec string) (*CronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	expanded := spec
+	if alias, ok := cronAliases[strings.ToLower(spec)]; ok {
+		expanded = alias
+	}
+
+	fields := strings.Fields(expanded)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron schedule %q must have 5 fields: minute hour day-of-month month day-of-week", spec)
+	}
+
+	s := &CronSchedule{
+		spec:       spec,
+		anyDay:     strings.HasPrefix(fields[2], "*"),
+		anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}
+	if err := parseCronField(fields[0], 0, 59, s.minutes[:]); err != nil {
+		return nil, fmt.Errorf("invalid minute in %q: %w", spec, err)
+	}
+	if err := parseCronField(fields[1], 0, 23, s.hours[:]); err != nil {
+		return nil, fmt.Errorf("invalid hour in %q: %w", spec, err)
+	}
+	if err := parseCronField(fields[2], 1, 31, s.days[:]); err != nil {
+		return nil, fmt.Errorf("invalid day of month in %q: %w", spec, err)
+	}
+	if err := parseCronField(fields[3], 1, 12, s.months[:]); err != nil {
+		return nil, fmt.Errorf("invalid month in %q: %w", spec, err)
+	}
+	var weekdays [8]bool
+	if err := parseCronField(fields[4], 0, 7, weekdays[:]); err != nil {
+		return nil, fmt.Errorf("invalid day of week in %q: %w", spec, err)
+	}
+	copy(s.weekdays[:], weekdays[:7])
+	if weekdays[7] {
+		s.weekdays[0] = true
+	}
+	return s, nil
+}
+
+// parseCronField marks the values a single cron field matches in set
+func parseCronField(field string, min, max int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if before, after, found := strings.Cut(part, "/"); found {
+			n, err := strconv.Atoi(after)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step %q", after)
+			}
+			rangePart, step = before, n
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			before, after, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = cronValue(before, min, max); err != nil {
+				return err
+			}
+			if high, err = cronValue(after, min, max); err != nil {
+				return err
+			}
+			if low > high {
+				return fmt.Errorf("range %q is backwards", rangePart)
+			}
+		default:
+			value, err := cronValue(rangePart, min, max)
+			if err != nil {
+				return err
+			}
+			low = value
+			// "5/15" means every 15 starting at 5
+			if step == 1 {
+				high = value
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+// cronValue parses a number in a cron field and checks its bounds
+func cronValue(text string, min, max int) (int, error) {
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", text)
+	}
+	if value < min || value > max {
+		return 0, fmt.Errorf("%d is outside %d-%d", value, min, max)
+	}
+	return value, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *CronSchedule) String() string {
+	return s.spec
+}
+
+// Next returns the first time after t that the schedule fires, in t's
+// location, or the zero time if it never fires
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if !s.months[t.Month()] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether t's day matches the day-of-month and
+// day-of-week fields. As in cron, when both are restricted either may match.
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	day := s.days[t.Day()]
+	weekday := s.weekdays[t.Weekday()]
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
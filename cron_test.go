@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2026, time.March, 4, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, time.March, 4, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, time.March, 4, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, time.March, 5, 3, 0, 0, 0, time.UTC)},
+		{"0 4 * * 0", time.Date(2026, time.March, 8, 4, 0, 0, 0, time.UTC)},
+		{"0 4 * * 7", time.Date(2026, time.March, 8, 4, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2026, time.March, 4, 13, 0, 0, 0, time.UTC)},
+		{"30 2 1,15 * *", time.Date(2026, time.March, 15, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, time.March, 8, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches (the 20th, or Friday the 6th)
+		{"0 0 20 * 5", time.Date(2026, time.March, 6, 0, 0, 0, 0, time.UTC)},
+		// February 29th only exists in leap years
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseCronSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseCronSchedule(%q) error = %v", tt.spec, err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNeverFires(t *testing.T) {
+	schedule, err := ParseCronSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("ParseCronSchedule() error = %v", err)
+	}
+	if got := schedule.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next() = %v, want zero time for February 30th", got)
+	}
+}
+
+func TestParseCronScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"@fortnightly",
+	} {
+		if _, err := ParseCronSchedule(spec); err == nil {
+			t.Errorf("ParseCronSchedule(%q) expected error, got nil", spec)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// csrfCookieName is the name of the cookie holding the browser's
+	// anonymous session ID, for browsers that aren't signed in
+	csrfCookieName = "shelf_csrf"
+
+	// csrfFieldName is the hidden form field forms send the token back in
+	csrfFieldName = "csrf_token"
+
+	// csrfHeaderName is the header scripts send the token back in
+	csrfHeaderName = "X-CSRF-Token"
+
+	// csrfTokenLength is the length of an encoded session ID or token
+	csrfTokenLength = 43
+
+	// maxMultipartBodySize caps multipart bodies read to find the token in,
+	// at the largest upload a form takes plus room for the multipart headers
+	maxMultipartBodySize = maxPosterUploadSize + 1<<20
+)
+
+// csrfKey signs CSRF tokens. Sessions only live as long as the process, so
+// the key does too.
+var csrfKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+// newCSRFSessionID generates a random anonymous session ID
+func newCSRFSessionID() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// csrfTokenFor returns the CSRF token for a session ID
+func csrfTokenFor(sessionID string) string {
+	mac := hmac.New(sha256.New, csrfKey)
+	mac.Write([]byte(sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// csrfSessionID returns the session a request's CSRF token is derived from:
+// the signed-in session if there is one, or else the browser's anonymous one
+func csrfSessionID(r *http.Request) string {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && len(cookie.Value) == csrfTokenLength {
+		return cookie.Value
+	}
+	return ""
+}
+
+// setCSRFCookie gives the browser a new anonymous session ID, returning it
+func setCSRFCookie(w http.ResponseWriter, r *http.Request) string {
+	sessionID := newCSRFSessionID()
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return sessionID
+}
+
+// csrfTokenFromContext returns the CSRF token for the request's session
+func csrfTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenContextKey).(string)
+	return token
+}
+
+// csrfField returns the hidden form input carrying the request's CSRF token
+func csrfField(r *http.Request) template.HTML {
+	return template.HTML(`<input type="hidden" name="` + csrfFieldName + `" value="` +
+		template.HTMLEscapeString(csrfTokenFromContext(r.Context())) + `">`)
+}
+
+// csrfMiddleware protects every state-changing request from cross-site forgery.
+// Forms must echo back a token signed from the browser's session, which changes
+// when the user signs in or out, and requests whose Origin or Referer is another
+// site are refused.
+func csrfMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID := csrfSessionID(r)
+
+		if !isSafeMethod(r.Method) {
+			if !sameOrigin(r) {
+				http.Error(w, "Cross-origin request refused", http.StatusForbidden)
+				return
+			}
+			if isMultipart(r) && r.Header.Get(csrfHeaderName) == "" {
+				// Forms posted without scripts send the token as a field,
+				// which can only be read by parsing the upload before it
+				r.Body = http.MaxBytesReader(w, r.Body, maxMultipartBodySize)
+				if err := r.ParseMultipartForm(maxPosterUploadSize); err != nil {
+					var tooLarge *http.MaxBytesError
+					if errors.As(err, &tooLarge) {
+						http.Error(w, "Upload is too large (maximum size is 10 MB)", http.StatusRequestEntityTooLarge)
+						return
+					}
+				}
+			}
+			if sessionID == "" || subtle.ConstantTimeCompare([]byte(csrfTokenFor(sessionID)), []byte(submittedCSRFToken(r))) != 1 {
+				http.Error(w, "Invalid or missing CSRF token, reload the page and try again", http.StatusForbidden)
+				return
+			}
+		}
+
+		if sessionID == "" {
+			sessionID = setCSRFCookie(w, r)
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfTokenContextKey, csrfTokenFor(sessionID))))
+	})
+}
+
+// submittedCSRFToken returns the token sent with a request, in the header or
+// the form, but never in the URL. Multipart forms must have been parsed
+// already, within their size limit.
+func submittedCSRFToken(r *http.Request) string {
+	if token := r.Header.Get(csrfHeaderName); token != "" {
+		return token
+	}
+	if isMultipart(r) && r.MultipartForm == nil {
+		return ""
+	}
+	return r.PostFormValue(csrfFieldName)
+}
+
+// isMultipart reports whether a request's body is a multipart form
+func isMultipart(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+}
+
+// isSafeMethod reports whether an HTTP method only reads
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// sameOrigin reports whether a request came from one of our own pages.
+// Browsers send Origin on cross-site posts; Referer is checked when it's missing.
+// Requests with neither (such as scripts) rely on the token alone.
+func sameOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+		if source == "" {
+			return true
+		}
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false // Includes the opaque "null" origin
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// addCSRFToken gives a test request the CSRF token header for its session,
+// adding an anonymous session cookie if it isn't signed in. Session cookies
+// must be added first.
+func addCSRFToken(t *testing.T, req *http.Request) {
+	t.Helper()
+
+	sessionID := csrfSessionID(req)
+	if sessionID == "" {
+		sessionID = newCSRFSessionID()
+		req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: sessionID})
+	}
+	req.Header.Set(csrfHeaderName, csrfTokenFor(sessionID))
+}
+
+// newCSRFTestApp creates an app over the test media without authentication,
+// and with an import directory
+func newCSRFTestApp(t *testing.T) *App {
+	t.Helper()
+
+	app, _, _ := setupAppWithMockTMDB(t)
+	app.importScanner = NewImportScanner(t.TempDir())
+	return app
+}
+
+func TestCSRFTokenInForms(t *testing.T) {
+	handler := newCSRFTestApp(t).routes()
+
+	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == csrfCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("CSRF cookie = %+v, want HttpOnly SameSite=Lax cookie", cookie)
+	}
+	field := `name="csrf_token" value="` + csrfTokenFor(cookie.Value) + `"`
+	if !strings.Contains(w.Body.String(), field) {
+		t.Errorf("Detail page forms do not contain the CSRF token")
+	}
+	if strings.Contains(w.Body.String(), cookie.Value) {
+		t.Errorf("Page contains the session ID itself")
+	}
+
+	// An existing session is reused rather than replaced
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if len(w.Result().Cookies()) != 0 {
+		t.Errorf("CSRF cookie reissued when the browser already has one")
+	}
+	if !strings.Contains(w.Body.String(), field) {
+		t.Errorf("Index page forms do not contain the existing CSRF token")
+	}
+}
+
+func TestCSRFMiddlewareChecks(t *testing.T) {
+	var reached bool
+	handler := csrfMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	session := newCSRFSessionID()
+	token := csrfTokenFor(session)
+	otherToken := csrfTokenFor(newCSRFSessionID())
+
+	tests := []struct {
+		name    string
+		cookie  string
+		field   string
+		header  string
+		origin  string
+		referer string
+		want    bool
+	}{
+		{"form field", session, token, "", "", "", true},
+		{"header", session, "", token, "", "", true},
+		{"same origin", session, token, "", "http://shelf.local", "", true},
+		{"same referer", session, token, "", "", "http://shelf.local/media/film", true},
+		{"no token", session, "", "", "", "", false},
+		{"no cookie", "", token, "", "", "", false},
+		{"mismatched token", session, otherToken, "", "", "", false},
+		{"session ID as token", session, session, "", "", "", false},
+		{"cross origin", session, token, "", "https://evil.example", "", false},
+		{"null origin", session, token, "", "null", "", false},
+		{"cross referer", session, token, "", "", "https://evil.example/page", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+			form := url.Values{}
+			if tt.field != "" {
+				form.Set(csrfFieldName, tt.field)
+			}
+			req := httptest.NewRequest(http.MethodPost, "http://shelf.local/rescan", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(csrfHeaderName, tt.header)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if reached != tt.want {
+				t.Errorf("Request reached handler = %v, want %v (status %v)", reached, tt.want, w.Code)
+			}
+			if !tt.want && w.Code != http.StatusForbidden {
+				t.Errorf("Refused request status = %v, want %v", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+
+	// Reads never need a token
+	reached = false
+	req := httptest.NewRequest(http.MethodGet, "http://shelf.local/", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if !reached {
+		t.Error("GET request refused")
+	}
+}
+
+func TestCSRFMultipartUpload(t *testing.T) {
+	session := newCSRFSessionID()
+	token := csrfTokenFor(session)
+	form := func(fields map[string]string) (*bytes.Buffer, string) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for name, value := range fields {
+			mw.WriteField(name, value)
+		}
+		mw.Close()
+		return &body, mw.FormDataContentType()
+	}
+
+	var reached, parsed bool
+	handler := csrfMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		parsed = r.MultipartForm != nil
+	}))
+
+	tests := []struct {
+		name   string
+		query  string
+		field  string
+		header string
+		want   bool
+		parsed bool // Whether the middleware had to read the body
+	}{
+		{"no token", "", "", "", false, true},
+		{"token in URL", "?csrf_token=" + token, "", "", false, true},
+		{"token in header", "", "", token, true, false},
+		{"token in field", "", token, "", true, true},
+		{"wrong token in field", "", csrfTokenFor(newCSRFSessionID()), "", false, true},
+	}
+	for _, tt := range tests {
+		reached, parsed = false, false
+		body, contentType := form(map[string]string{"note": "poster", csrfFieldName: tt.field})
+		req := httptest.NewRequest(http.MethodPost, "/media/film/poster"+tt.query, body)
+		req.Header.Set("Content-Type", contentType)
+		if tt.header != "" {
+			req.Header.Set(csrfHeaderName, tt.header)
+		}
+		req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: session})
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if reached != tt.want {
+			t.Errorf("Upload with %s reached handler = %v, want %v", tt.name, reached, tt.want)
+		}
+		if reached && parsed != tt.parsed {
+			t.Errorf("Upload with %s parsed by middleware = %v, want %v", tt.name, parsed, tt.parsed)
+		}
+	}
+
+	// Bodies are only read up to the upload limit to find the field
+	body, contentType := form(map[string]string{csrfFieldName: token, "poster": strings.Repeat("x", maxMultipartBodySize)})
+	req := httptest.NewRequest(http.MethodPost, "/media/film/poster", body)
+	req.Header.Set("Content-Type", contentType)
+	req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: session})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Oversized upload status = %v, want %v", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestCSRFProtectsEveryPostRoute(t *testing.T) {
+	handler := newCSRFTestApp(t).routes()
+
+	routes := []string{
+		"/login",
+		"/logout",
+		"/rescan",
+		"/import/start",
+		"/import/step1",
+		"/import/step2",
+		"/import/step2/confirm",
+		"/import/step3",
+		"/import/step4",
+		"/import/step5",
+		"/import/confirm",
+		"/import/execute",
+		"/media/war-of-the-worlds-2025/set-tmdb",
+		"/media/war-of-the-worlds-2025/edit",
+		"/media/war-of-the-worlds-2025/poster",
+	}
+	for _, route := range routes {
+		t.Run(route, func(t *testing.T) {
+			// Without a token the request never reaches the handler
+			req := httptest.NewRequest(http.MethodPost, route, strings.NewReader("session=x"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "CSRF") {
+				t.Errorf("POST %s without token = %v %q, want CSRF refusal", route, w.Code, w.Body.String())
+			}
+
+			// With one it's handled normally
+			req = httptest.NewRequest(http.MethodPost, route, strings.NewReader("session=x"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Accept", "application/json")
+			addCSRFToken(t, req)
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if strings.Contains(w.Body.String(), "CSRF") {
+				t.Errorf("POST %s with token refused: %v %q", route, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCSRFTokenChangesOnLogin(t *testing.T) {
+	users, _ := LoadUserStore(writeUsersFile(t, map[string]string{"sam": "secret"}))
+	handler := newAuthTestApp(t, AuthConfig{Users: users}).routes()
+
+	cookie := func(w *httptest.ResponseRecorder, name string) *http.Cookie {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// The login form carries the anonymous session's token
+	req := httptest.NewRequest(http.MethodGet, "/login", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	anonymous := cookie(w, csrfCookieName)
+	if anonymous == nil {
+		t.Fatal("Login page didn't start an anonymous session")
+	}
+	oldToken := csrfTokenFor(anonymous.Value)
+
+	form := url.Values{"username": {"sam"}, "password": {"secret"}, csrfFieldName: {oldToken}}
+	req = httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(anonymous)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	session := cookie(w, sessionCookieName)
+	if w.Code != http.StatusSeeOther || session == nil {
+		t.Fatalf("Login = %v, want a new session", w.Code)
+	}
+	if rotated := cookie(w, csrfCookieName); rotated == nil || rotated.Value == anonymous.Value {
+		t.Error("Login didn't replace the anonymous session ID")
+	}
+
+	// Signed in, the token comes from the new session and the old one is refused
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(session)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	newToken := csrfTokenFor(session.Value)
+	if newToken == oldToken || !strings.Contains(w.Body.String(), newToken) {
+		t.Errorf("Signed in page doesn't carry the session's token")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/rescan", nil)
+	req.Header.Set(csrfHeaderName, oldToken)
+	req.AddCookie(session)
+	req.AddCookie(anonymous)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "CSRF") {
+		t.Errorf("Token from before signing in = %v %q, want CSRF refusal", w.Code, w.Body.String())
+	}
+}
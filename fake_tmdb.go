@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The fixtures shelf fake-tmdb serves when it isn't given a directory. They
+// cover the media items used by the Go and Playwright tests.
+//
+//go:embed tmdb-fixtures
+var embeddedTMDBFixtures embed.FS
+
+// defaultFakeTMDBAddr is where shelf fake-tmdb listens unless told otherwise
+const defaultFakeTMDBAddr = ":8091"
+
+// FakeTMDB is a stand-in for the TMDB API and image server that answers from a
+// fixtures directory, so shelf can be tested and demonstrated without an API
+// key or network access. The directory holds:
+//
+//	movie/{id}.json              Movie details, as returned by /3/movie/{id}
+//	movie/{id}/images.json       Alternative images (optional, defaults to the poster and backdrop)
+//	movie/{id}/translations.json Titles and overviews in other languages (optional)
+//	tv/{id}.json                 TV show details, as returned by /3/tv/{id}
+//	tv/{id}/images.json          Alternative images (optional)
+//	tv/{id}/translations.json    Titles and overviews in other languages (optional)
+//	collection/{id}.json         Collection details, as returned by /3/collection/{id}
+//	images/{file}                Image files (optional, others are drawn as placeholders)
+//
+// Searches match the titles of the movie and TV fixtures. Details asked for in
+// a language other than English take its title and overview from the
+// translations, leaving the overview empty without one as TMDB does.
+type FakeTMDB struct {
+	fixtures fs.FS
+	mux      *http.ServeMux
+}
+
+// NewFakeTMDB creates a fake TMDB server answering from fixtures
+func NewFakeTMDB(fixtures fs.FS) *FakeTMDB {
+	f := &FakeTMDB{fixtures: fixtures, mux: http.NewServeMux()}
+	for _, kind := range []string{"movie", "tv"} {
+		f.mux.HandleFunc("GET /3/"+kind+"/{id}", f.detailsHandler(kind))
+		f.mux.HandleFunc("GET /3/"+kind+"/{id}/images", f.imagesHandler(kind))
+		f.mux.HandleFunc("GET /3/"+kind+"/{id}/translations", f.translationsHandler(kind))
+		f.mux.HandleFunc("GET /3/search/"+kind, f.searchHandler(kind))
+	}
+	f.mux.HandleFunc("GET /3/collection/{id}", f.collectionHandler)
+	f.mux.HandleFunc("GET /t/p/{size}/{file...}", f.imageFileHandler)
+	f.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { writeFakeTMDBNotFound(w) })
+	return f
+}
+
+// ServeHTTP implements http.Handler
+func (f *FakeTMDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.ServeHTTP(w, r)
+}
+
+// detailsHandler serves a movie or TV show's fixture, translated into the
+// requested language
+func (f *FakeTMDB) detailsHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		data, err := fs.ReadFile(f.fixtures, path.Join(kind, id+".json"))
+		if err != nil {
+			writeFakeTMDBNotFound(w)
+			return
+		}
+
+		language := r.URL.Query().Get("language")
+		if language == "" || strings.HasPrefix(language, "en") {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+			return
+		}
+
+		var details map[string]interface{}
+		if err := json.Unmarshal(data, &details); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid fixture %s/%s.json: %v", kind, id, err), http.StatusInternalServerError)
+			return
+		}
+		details["overview"] = ""
+		if translation := findTranslation(f.translations(kind, id), language); translation != nil {
+			details["overview"] = translation.Data.Overview
+			if title := translation.Title(); title != "" && kind == "tv" {
+				details["name"] = title
+			} else if title != "" {
+				details["title"] = title
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(details)
+	}
+}
+
+// translations returns a movie or TV show's translations fixture, if it has one
+func (f *FakeTMDB) translations(kind, id string) []Translation {
+	data, err := fs.ReadFile(f.fixtures, path.Join(kind, id, "translations.json"))
+	if err != nil {
+		return nil
+	}
+	var translations TranslationsResponse
+	if err := json.Unmarshal(data, &translations); err != nil {
+		log.Printf("Skipping invalid fixture %s/%s/translations.json: %v", kind, id, err)
+		return nil
+	}
+	return translations.Translations
+}
+
+// translationsHandler serves a movie or TV show's translations fixture, or
+// an empty list when it has none
+func (f *FakeTMDB) translationsHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if data, err := fs.ReadFile(f.fixtures, path.Join(kind, id, "translations.json")); err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+			return
+		}
+		if _, err := fs.Stat(f.fixtures, path.Join(kind, id+".json")); err != nil {
+			writeFakeTMDBNotFound(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"translations": []}`)
+	}
+}
+
+// collectionHandler serves a collection's fixture
+func (f *FakeTMDB) collectionHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := fs.ReadFile(f.fixtures, path.Join("collection", r.PathValue("id")+".json"))
+	if err != nil {
+		writeFakeTMDBNotFound(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// imagesHandler serves a movie or TV show's images fixture, or offers just its
+// poster and backdrop when it has none
+func (f *FakeTMDB) imagesHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if data, err := fs.ReadFile(f.fixtures, path.Join(kind, id, "images.json")); err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+			return
+		}
+
+		data, err := fs.ReadFile(f.fixtures, path.Join(kind, id+".json"))
+		if err != nil {
+			writeFakeTMDBNotFound(w)
+			return
+		}
+		var details struct {
+			ID           int    `json:"id"`
+			PosterPath   string `json:"poster_path"`
+			BackdropPath string `json:"backdrop_path"`
+		}
+		if err := json.Unmarshal(data, &details); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid fixture %s/%s.json: %v", kind, id, err), http.StatusInternalServerError)
+			return
+		}
+
+		images := ImagesResponse{ID: details.ID, Posters: []Image{}, Backdrops: []Image{}, Logos: []Image{}}
+		if details.PosterPath != "" {
+			images.Posters = append(images.Posters, Image{FilePath: details.PosterPath, Language: "en", Width: 1000, Height: 1500})
+		}
+		if details.BackdropPath != "" {
+			images.Backdrops = append(images.Backdrops, Image{FilePath: details.BackdropPath, Width: 1920, Height: 1080})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(images)
+	}
+}
+
+// searchHandler finds the movie or TV fixtures whose title contains the query,
+// most popular first. Movie searches also filter on the optional year.
+func (f *FakeTMDB) searchHandler(kind string) http.HandlerFunc {
+	titleField, dateField := "title", "release_date"
+	if kind == "tv" {
+		titleField, dateField = "name", "first_air_date"
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := normalizeTitle(r.URL.Query().Get("query"))
+		year := r.URL.Query().Get("year")
+
+		entries, err := fs.ReadDir(f.fixtures, kind)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, fmt.Sprintf("Failed to read %s fixtures: %v", kind, err), http.StatusInternalServerError)
+			return
+		}
+
+		results := []map[string]interface{}{}
+		for _, entry := range entries {
+			if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+				continue
+			}
+			data, err := fs.ReadFile(f.fixtures, path.Join(kind, entry.Name()))
+			if err != nil {
+				continue
+			}
+			var result map[string]interface{}
+			if err := json.Unmarshal(data, &result); err != nil {
+				log.Printf("Skipping invalid fixture %s/%s: %v", kind, entry.Name(), err)
+				continue
+			}
+			title, _ := result[titleField].(string)
+			date, _ := result[dateField].(string)
+			if query == "" || !strings.Contains(normalizeTitle(title), query) {
+				continue
+			}
+			if year != "" && !strings.HasPrefix(date, year) {
+				continue
+			}
+			results = append(results, result)
+		}
+
+		slices.SortFunc(results, func(a, b map[string]interface{}) int {
+			pa, _ := a["popularity"].(float64)
+			pb, _ := b["popularity"].(float64)
+			switch {
+			case pa > pb:
+				return -1
+			case pa < pb:
+				return 1
+			}
+			return 0
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"page":          1,
+			"results":       results,
+			"total_pages":   1,
+			"total_results": len(results),
+		})
+	}
+}
+
+// imageFileHandler serves an image from the fixtures at any size, drawing a
+// placeholder for images the fixtures don't have
+func (f *FakeTMDB) imageFileHandler(w http.ResponseWriter, r *http.Request) {
+	file := r.PathValue("file")
+	if data, err := fs.ReadFile(f.fixtures, path.Join("images", file)); err == nil {
+		http.ServeContent(w, r, file, time.Time{}, bytes.NewReader(data))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := writePlaceholderImage(&buf, file); err != nil {
+		http.Error(w, "Failed to draw placeholder", http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, file, time.Time{}, bytes.NewReader(buf.Bytes()))
+}
+
+// writePlaceholderImage draws a poster-shaped image in a colour picked from
+// name, encoded to suit its extension
+func writePlaceholderImage(w io.Writer, name string) error {
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	sum := hash.Sum32()
+	fill := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, 200, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 200; x++ {
+			img.Set(x, y, fill)
+		}
+	}
+
+	if strings.EqualFold(path.Ext(name), ".png") {
+		return png.Encode(w, img)
+	}
+	return jpeg.Encode(w, img, nil)
+}
+
+// writeFakeTMDBNotFound answers like TMDB does for an unknown resource
+func writeFakeTMDBNotFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        false,
+		"status_code":    34,
+		"status_message": "The resource you requested could not be found.",
+	})
+}
+
+// runFakeTMDB runs shelf fake-tmdb with the arguments following it
+func runFakeTMDB(args []string) error {
+	flags := flag.NewFlagSet("fake-tmdb", flag.ContinueOnError)
+	addr := flags.String("addr", defaultFakeTMDBAddr, "address to listen on")
+	fixturesDir := flags.String("fixtures", "", "directory of fixtures (default: built-in fixtures)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var fixtures fs.FS
+	if *fixturesDir != "" {
+		if info, err := os.Stat(*fixturesDir); err != nil {
+			return err
+		} else if !info.IsDir() {
+			return fmt.Errorf("fixtures path is not a directory: %s", *fixturesDir)
+		}
+		fixtures = os.DirFS(*fixturesDir)
+		log.Printf("Serving TMDB fixtures from %s", *fixturesDir)
+	} else {
+		sub, err := fs.Sub(embeddedTMDBFixtures, "tmdb-fixtures")
+		if err != nil {
+			return err
+		}
+		fixtures = sub
+		log.Println("Serving built-in TMDB fixtures")
+	}
+
+	base := "http://localhost" + *addr
+	if !strings.HasPrefix(*addr, ":") {
+		base = "http://" + *addr
+	}
+	log.Printf("Fake TMDB listening on %s; start shelf with TMDB_API_URL=%s/3 TMDB_IMAGE_URL=%s/t/p", *addr, base, base)
+	return http.ListenAndServe(*addr, NewFakeTMDB(fixtures))
+}
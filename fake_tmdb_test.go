@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"image"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// newFakeTMDBClient starts a fake TMDB server on fixtures and returns a client
+// pointed at it, with no API key
+func newFakeTMDBClient(t *testing.T, fixtures fs.FS) *TMDBClient {
+	t.Helper()
+
+	server := httptest.NewServer(NewFakeTMDB(fixtures))
+	t.Cleanup(server.Close)
+
+	client := NewTMDBClient("")
+	client.SetBaseURLs(server.URL+"/3/", server.URL+"/t/p")
+	return client
+}
+
+// builtinTMDBFixtures returns the fixtures shelf fake-tmdb serves by default
+func builtinTMDBFixtures(t *testing.T) fs.FS {
+	t.Helper()
+
+	fixtures, err := fs.Sub(embeddedTMDBFixtures, "tmdb-fixtures")
+	if err != nil {
+		t.Fatalf("Failed to open built-in fixtures: %v", err)
+	}
+	return fixtures
+}
+
+func TestFakeTMDBDetails(t *testing.T) {
+	client := newFakeTMDBClient(t, builtinTMDBFixtures(t))
+	ctx := context.Background()
+
+	movie, err := client.FetchMovieMetadata(ctx, "603")
+	if err != nil {
+		t.Fatalf("FetchMovieMetadata() error = %v", err)
+	}
+	if movie.Title != "The Matrix" || len(movie.Genres) != 2 {
+		t.Errorf("Movie = %+v, want The Matrix with two genres", movie)
+	}
+
+	tv, err := client.FetchTVMetadata(ctx, "1396")
+	if err != nil {
+		t.Fatalf("FetchTVMetadata() error = %v", err)
+	}
+	if tv.Name != "Breaking Bad" || len(tv.Seasons) != 2 {
+		t.Errorf("TV show = %+v, want Breaking Bad with two seasons", tv)
+	}
+
+	if _, err := client.FetchMovieMetadata(ctx, "999999"); !errors.Is(err, ErrTMDBNotFound) {
+		t.Errorf("FetchMovieMetadata() of a missing fixture error = %v, want ErrTMDBNotFound", err)
+	}
+	if err := client.ValidateTMDBID(ctx, "603", TV); !errors.Is(err, ErrTMDBNotFound) {
+		t.Errorf("ValidateTMDBID() of a film as TV error = %v, want ErrTMDBNotFound", err)
+	}
+}
+
+func TestFakeTMDBSearch(t *testing.T) {
+	client := newFakeTMDBClient(t, builtinTMDBFixtures(t))
+	ctx := context.Background()
+
+	movies, err := client.SearchMovies(ctx, "the matrix", 1999)
+	if err != nil {
+		t.Fatalf("SearchMovies() error = %v", err)
+	}
+	if len(movies) != 1 || movies[0].ID != 603 || movies[0].ReleaseDate != "1999-03-31" {
+		t.Errorf("SearchMovies() = %+v, want The Matrix", movies)
+	}
+
+	if movies, _ := client.SearchMovies(ctx, "Matrix", 2003); len(movies) != 0 {
+		t.Errorf("SearchMovies() for another year = %+v, want none", movies)
+	}
+
+	shows, err := client.SearchTV(ctx, "Better Call Saul")
+	if err != nil {
+		t.Fatalf("SearchTV() error = %v", err)
+	}
+	if len(shows) != 1 || shows[0].ID != 60059 {
+		t.Errorf("SearchTV() = %+v, want Better Call Saul", shows)
+	}
+}
+
+func TestFakeTMDBImages(t *testing.T) {
+	client := newFakeTMDBClient(t, builtinTMDBFixtures(t))
+	ctx := context.Background()
+
+	// The Matrix has an images fixture, Fight Club offers its own poster
+	images, err := client.FetchImages(ctx, "603", Film, "en")
+	if err != nil {
+		t.Fatalf("FetchImages() error = %v", err)
+	}
+	if len(images.Posters) != 3 {
+		t.Errorf("Posters = %d, want 3 from the fixture", len(images.Posters))
+	}
+	images, err = client.FetchImages(ctx, "550", Film, "")
+	if err != nil {
+		t.Fatalf("FetchImages() without a fixture error = %v", err)
+	}
+	if len(images.Posters) != 1 || images.Posters[0].FilePath != "/pB8BM7pdSp6B6Ih7QZ4DrQ3PmJK.jpg" || len(images.Backdrops) != 1 {
+		t.Errorf("Images = %+v, want the movie's poster and backdrop", images)
+	}
+
+	// Images missing from the fixtures are drawn as placeholders
+	dir := t.TempDir()
+	if err := client.DownloadPoster(ctx, images.Posters[0].FilePath, dir); err != nil {
+		t.Fatalf("DownloadPoster() error = %v", err)
+	}
+	file, err := os.Open(filepath.Join(dir, "poster.jpg"))
+	if err != nil {
+		t.Fatalf("Poster not saved: %v", err)
+	}
+	defer file.Close()
+	if config, format, err := image.DecodeConfig(file); err != nil || format != "jpeg" || config.Height <= config.Width {
+		t.Errorf("Poster = %v %s %+v, want a portrait JPEG", err, format, config)
+	}
+}
+
+func TestFakeTMDBCustomFixtures(t *testing.T) {
+	fixtures := fstest.MapFS{
+		"movie/1.json":     {Data: []byte(`{"id": 1, "title": "Home Movie", "release_date": "2010-01-01"}`)},
+		"images/cover.jpg": {Data: []byte("not really a jpeg")},
+	}
+	client := newFakeTMDBClient(t, fixtures)
+
+	results, err := client.SearchMovies(context.Background(), "home", 0)
+	if err != nil || len(results) != 1 || results[0].Title != "Home Movie" {
+		t.Errorf("SearchMovies() = %+v, %v, want Home Movie", results, err)
+	}
+
+	// Image files in the fixtures are served as they are, at any size
+	resp, err := http.Get(client.ThumbnailURL("/cover.jpg"))
+	if err != nil {
+		t.Fatalf("Get thumbnail error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != int64(len("not really a jpeg")) {
+		t.Errorf("Thumbnail = %d with %d bytes, want the fixture file", resp.StatusCode, resp.ContentLength)
+	}
+}
+
+func TestTMDBClientBaseURLs(t *testing.T) {
+	client := NewTMDBClient("key")
+	if got := client.ThumbnailURL("/abc.jpg"); got != "https://image.tmdb.org/t/p/w342/abc.jpg" {
+		t.Errorf("ThumbnailURL() = %q", got)
+	}
+
+	client.SetBaseURLs("", "http://localhost:8091/t/p/")
+	if client.apiBaseURL != tmdbAPIBaseURL {
+		t.Errorf("apiBaseURL = %q, want the default kept", client.apiBaseURL)
+	}
+	if got := client.ThumbnailURL("abc.jpg"); got != "http://localhost:8091/t/p/w342/abc.jpg" {
+		t.Errorf("ThumbnailURL() = %q", got)
+	}
+}
@@ -1,3 +1,7 @@
 module shelf
 
 go 1.24.7
+
+require golang.org/x/image v0.36.0
+
+require golang.org/x/crypto v0.48.0
@@ -1,19 +1,24 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"net/url"
+	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // App holds the application state
 type App struct {
+	mu             sync.RWMutex // Guards mediaList, which background rescans replace
 	mediaList      []Media
 	templates      *template.Template
 	mediaDir       string
@@ -22,6 +27,20 @@ type App struct {
 	devMode        bool // Enable template hot-reloading in development
 	tmdbClient     *TMDBClient
 	playURLPrefix  string // URL prefix for play commands
+	players        []PlayerProfile
+	pathProfiles   []PathProfile // Path mappings each browser can choose from
+	mpv            *MPVClient    // nil when MPV remote control is disabled
+	mpvPathMapping PathMapping   // How MPV's machine sees the media paths
+	kodi           []*KodiClient
+	streams        *StreamSigner // nil when streaming is disabled
+	thumbnails     *ThumbnailCache
+	templateDir    string // Optional directory overriding the embedded templates
+	jobs           *JobManager
+	matches        *MatchQueue    // TMDB matches waiting for review
+	auth           *Authenticator // nil when authentication is disabled
+
+	metadataLanguage string   // Language metadata is saved in, empty for TMDB's default
+	languageVariants []string // Further languages titles and descriptions are saved in
 }
 
 // NewApp creates a new App instance
@@ -31,7 +50,7 @@ func NewApp(mediaList []Media, templates *template.Template, mediaDir, importDir
 		importScanner = NewImportScanner(importDir)
 	}
 
-	return &App{
+	app := &App{
 		mediaList:     mediaList,
 		templates:     templates,
 		mediaDir:      mediaDir,
@@ -40,7 +59,12 @@ func NewApp(mediaList []Media, templates *template.Template, mediaDir, importDir
 		devMode:       false,
 		tmdbClient:    nil,
 		playURLPrefix: "",
+		players:       DefaultPlayerProfiles(),
+		jobs:          NewJobManager(),
+		matches:       NewMatchQueue(),
 	}
+	app.registerJobTypes()
+	return app
 }
 
 // SetTMDBClient sets the TMDB client for the app
@@ -58,22 +82,72 @@ func (app *App) SetPlayURLPrefix(prefix string) {
 	app.playURLPrefix = prefix
 }
 
-// loadTemplates reloads templates from disk (used in dev mode)
+// SetPlayerProfiles sets the players offered on the detail page
+func (app *App) SetPlayerProfiles(players []PlayerProfile) {
+	app.players = players
+}
+
+// SetMPVClient enables playing disks in MPV, with mapping rewriting disk
+// paths into the paths MPV's machine sees
+func (app *App) SetMPVClient(client *MPVClient, mapping PathMapping) {
+	app.mpv = client
+	app.mpvPathMapping = mapping
+}
+
+// SetKodiClients sets the Kodi instances disks can be played on
+func (app *App) SetKodiClients(clients []*KodiClient) {
+	app.kodi = clients
+}
+
+// findKodi returns the Kodi client with the given instance name
+func (app *App) findKodi(name string) *KodiClient {
+	for _, client := range app.kodi {
+		if client.Name() == name {
+			return client
+		}
+	}
+	return nil
+}
+
+// SetStreamSigner enables streaming file-based disks over HTTP
+func (app *App) SetStreamSigner(signer *StreamSigner) {
+	app.streams = signer
+}
+
+// SetPathProfiles sets the path mapping profiles browsers can choose from
+func (app *App) SetPathProfiles(profiles []PathProfile) {
+	app.pathProfiles = profiles
+}
+
+// pathProfileFor returns the path profile chosen by the request's browser,
+// or nil to use the server's default paths
+func (app *App) pathProfileFor(r *http.Request) *PathProfile {
+	cookie, err := r.Cookie(pathProfileCookieName)
+	if err != nil {
+		return nil
+	}
+	return FindPathProfile(app.pathProfiles, cookie.Value)
+}
+
+// SetTemplateDir sets a directory whose templates override the embedded ones
+func (app *App) SetTemplateDir(dir string) {
+	app.templateDir = dir
+}
+
+// SetAuthenticator enables authentication for the app
+func (app *App) SetAuthenticator(auth *Authenticator) {
+	app.auth = auth
+}
+
+// SetThumbnailCache sets the cache used to serve resized artwork
+func (app *App) SetThumbnailCache(cache *ThumbnailCache) {
+	app.thumbnails = cache
+}
+
+// loadTemplates reloads templates (used in dev mode).
+// Edits are only picked up from TEMPLATE_DIR; embedded templates never change.
 func (app *App) loadTemplates() *template.Template {
-	tmpl, err := template.ParseFiles(
-		"templates/index.html",
-		"templates/detail.html",
-		"templates/search.html",
-		"templates/confirm.html",
-		"templates/import_list.html",
-		"templates/import_step1.html",
-		"templates/import_step2.html",
-		"templates/import_step3.html",
-		"templates/import_step4.html",
-		"templates/import_step5.html",
-		"templates/import_confirm.html",
-		"templates/import_success.html",
-	)
+	tmpl, err := parseTemplates(templateFS(app.templateDir))
 	if err != nil {
 		log.Printf("Error reloading templates: %v", err)
 		return app.templates // Fall back to cached templates
@@ -81,17 +155,56 @@ func (app *App) loadTemplates() *template.Template {
 	return tmpl
 }
 
-// IndexHandler handles the main page request
-func (app *App) IndexHandler(w http.ResponseWriter, r *http.Request) {
-	// Reload templates in dev mode
+// templatesFor returns the templates to render for a request, with the
+// request-specific template functions (such as currentUser) bound to it.
+// The shared set is cloned because html/template can't change functions
+// after a template has been executed.
+func (app *App) templatesFor(r *http.Request) *template.Template {
 	tmpl := app.templates
 	if app.devMode {
 		tmpl = app.loadTemplates()
 	}
+	if tmpl == nil {
+		return nil
+	}
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		log.Printf("Error cloning templates: %v", err)
+		return tmpl
+	}
+	return clone.Funcs(app.requestFuncs(r))
+}
+
+// requestFuncs returns the template functions that depend on the current request
+func (app *App) requestFuncs(r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"currentUser": func() *User { return userFromContext(r.Context()) },
+		"authEnabled": func() bool { return app.auth != nil },
+		"can":         func(role Role) bool { return app.userCan(r, role) },
+		"csrfToken":   func() string { return csrfTokenFromContext(r.Context()) },
+		"csrfField":   func() template.HTML { return csrfField(r) },
+
+		"tmdbThumbnail": app.tmdbThumbnailURL,
+	}
+}
+
+// tmdbThumbnailURL returns a small rendition of a TMDB image from the image
+// server the TMDB client is configured with
+func (app *App) tmdbThumbnailURL(filePath string) string {
+	if app.tmdbClient == nil {
+		return tmdbImageURL(tmdbImageBaseURL, tmdbThumbnailSize, filePath)
+	}
+	return app.tmdbClient.ThumbnailURL(filePath)
+}
+
+// IndexHandler handles the main page request
+func (app *App) IndexHandler(w http.ResponseWriter, r *http.Request) {
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	// Sort media list: Films first, then TV shows, alphabetically within each type
-	sorted := make([]Media, len(app.mediaList))
-	copy(sorted, app.mediaList)
+	sorted := app.allMedia()
 
 	sort.Slice(sorted, func(i, j int) bool {
 		// Films come before TV shows
@@ -102,12 +215,22 @@ func (app *App) IndexHandler(w http.ResponseWriter, r *http.Request) {
 		return sorted[i].Title < sorted[j].Title
 	})
 
+	// Show titles in the viewer's chosen language
+	language := app.metadataLanguageFor(r)
+	for i := range sorted {
+		sorted[i] = localizeMedia(sorted[i], language)
+	}
+
 	data := struct {
 		MediaList     []Media
 		ImportEnabled bool
+		Languages     []metadataLanguageOption
+		Language      string
 	}{
 		MediaList:     sorted,
 		ImportEnabled: app.importScanner != nil,
+		Languages:     app.metadataLanguageOptions(),
+		Language:      language,
 	}
 
 	err := tmpl.ExecuteTemplate(w, "index.html", data)
@@ -123,6 +246,25 @@ func (app *App) PosterHandler(w http.ResponseWriter, r *http.Request) {
 	slug := strings.TrimPrefix(r.URL.Path, "/posters/")
 	slug = strings.TrimSuffix(slug, "/")
 
+	app.serveArtwork(w, r, slug, ArtworkPoster)
+}
+
+// ArtworkHandler serves any artwork image for media items:
+// poster, fanart (backdrop), clearlogo and per-season posters
+func (app *App) ArtworkHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract slug and artwork name from URL: /art/{slug}/{name}
+	path := strings.TrimPrefix(r.URL.Path, "/art/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || !artworkNamePattern.MatchString(parts[1]) {
+		http.NotFound(w, r)
+		return
+	}
+
+	app.serveArtwork(w, r, parts[0], parts[1])
+}
+
+// serveArtwork serves the named artwork file for the media item with the given slug
+func (app *App) serveArtwork(w http.ResponseWriter, r *http.Request, slug, name string) {
 	if slug == "" {
 		http.NotFound(w, r)
 		return
@@ -135,32 +277,64 @@ func (app *App) PosterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Find poster file
-	posterPath, found := media.FindPosterFile()
+	// Find artwork file
+	artworkPath, found := media.FindArtworkFile(name)
 	if !found {
 		http.NotFound(w, r)
 		return
 	}
 
 	// Validate path is within media directory (security check)
-	cleanPath := filepath.Clean(posterPath)
+	cleanPath := filepath.Clean(artworkPath)
 	if !strings.HasPrefix(cleanPath, filepath.Clean(app.mediaDir)) {
 		log.Printf("Security warning: attempted access to path outside media dir: %s", cleanPath)
 		http.NotFound(w, r)
 		return
 	}
 
+	// Resize when a thumbnail width is requested
+	if widthParam := r.URL.Query().Get("w"); widthParam != "" && app.thumbnails != nil {
+		width, err := strconv.Atoi(widthParam)
+		if err != nil || width <= 0 {
+			http.Error(w, "Invalid width", http.StatusBadRequest)
+			return
+		}
+		thumbPath, err := app.thumbnails.Thumbnail(artworkPath, snapThumbnailWidth(width))
+		if err != nil {
+			// Fall back to the original image rather than a broken image
+			log.Printf("Failed to generate thumbnail for %s: %v", artworkPath, err)
+		} else {
+			artworkPath = thumbPath
+		}
+	}
+
+	info, err := os.Stat(artworkPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Versioned URLs change whenever the artwork changes, so they can be cached forever.
+	// Unversioned URLs must be revalidated, which the ETag keeps cheap. Behind
+	// authentication only the browser may keep a copy, not shared caches.
+	w.Header().Set("ETag", artworkETag(artworkPath, info.ModTime(), info.Size()))
+	switch {
+	case r.URL.Query().Get("v") == "":
+		w.Header().Set("Cache-Control", "no-cache")
+	case app.auth != nil:
+		w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	default:
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+
 	// Serve the file with appropriate content type
-	http.ServeFile(w, r, posterPath)
+	http.ServeFile(w, r, artworkPath)
 }
 
 // DetailHandler handles individual media detail pages
 func (app *App) DetailHandler(w http.ResponseWriter, r *http.Request) {
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	// Extract slug from URL: /media/{slug}
 	slug := strings.TrimPrefix(r.URL.Path, "/media/")
@@ -178,23 +352,73 @@ func (app *App) DetailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Show the title and description in the viewer's chosen language
+	language := app.metadataLanguageFor(r)
+	localized := localizeMedia(*media, language)
+	media = &localized
+
 	// Load additional metadata
-	description := media.LoadDescription()
+	description := localizedDescription(media, language)
 	genres := media.LoadGenres()
+	credits := media.LoadCredits()
 	_, hasPoster := media.FindPosterFile()
+	_, hasBackdrop := media.FindArtworkFile(ArtworkBackdrop)
+	_, hasLogo := media.FindArtworkFile(ArtworkLogo)
+
+	// Record which series have season art
+	seasonArt := make(map[int]bool)
+	for _, disk := range media.Disks {
+		if disk.Series > 0 {
+			_, seasonArt[disk.Series] = media.FindArtworkFile(SeasonArtworkName(disk.Series))
+		}
+	}
 
 	data := struct {
-		Media         *Media
-		Description   string
-		Genres        []string
-		HasPoster     bool
-		PlayURLPrefix string
+		Media           *Media
+		Description     string
+		Genres          []string
+		Credits         Credits
+		Collection      *Collection
+		HasPoster       bool
+		HasBackdrop     bool
+		HasLogo         bool
+		SeasonArt       map[int]bool
+		PlayURLPrefix   string
+		Players         []PlayerProfile
+		PathProfiles    []PathProfile
+		PathProfile     *PathProfile
+		MPVEnabled      bool
+		Kodi            []*KodiClient
+		StreamFiles     [][]StreamFile
+		Overrides       MetadataOverrides
+		TMDBTitle       string
+		TMDBDescription string
+		TMDBGenres      []string
+		Languages       []metadataLanguageOption
+		Language        string
 	}{
-		Media:         media,
-		Description:   description,
-		Genres:        genres,
-		HasPoster:     hasPoster,
-		PlayURLPrefix: app.playURLPrefix,
+		Media:           media,
+		Description:     description,
+		Genres:          genres,
+		Credits:         credits,
+		Collection:      media.LoadCollection(),
+		HasPoster:       hasPoster,
+		HasBackdrop:     hasBackdrop,
+		HasLogo:         hasLogo,
+		SeasonArt:       seasonArt,
+		PlayURLPrefix:   app.playURLPrefix,
+		Players:         app.players,
+		PathProfiles:    app.pathProfiles,
+		PathProfile:     app.pathProfileFor(r),
+		MPVEnabled:      app.mpv != nil,
+		Kodi:            app.kodi,
+		StreamFiles:     app.streamFiles(r, media),
+		Overrides:       media.LoadOverrides(),
+		TMDBTitle:       media.LoadTMDBTitle(),
+		TMDBDescription: media.LoadTMDBDescription(),
+		TMDBGenres:      media.LoadTMDBGenres(),
+		Languages:       app.metadataLanguageOptions(),
+		Language:        language,
 	}
 
 	err := tmpl.ExecuteTemplate(w, "detail.html", data)
@@ -205,16 +429,100 @@ func (app *App) DetailHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// findMediaBySlug finds a media item by its slug
+// findMediaBySlug returns a copy of the media item with the given slug.
+// The library is only changed through setters such as setMediaTMDBID.
 func (app *App) findMediaBySlug(slug string) *Media {
-	for i := range app.mediaList {
-		if app.mediaList[i].Slug() == slug {
-			return &app.mediaList[i]
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+
+	for _, media := range app.mediaList {
+		if media.Slug() == slug {
+			return &media
+		}
+	}
+	return nil
+}
+
+// findMediaByPath returns a copy of the media item in a directory
+func (app *App) findMediaByPath(path string) *Media {
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+
+	for _, media := range app.mediaList {
+		if media.Path == path {
+			return &media
 		}
 	}
 	return nil
 }
 
+// setDiskSize records the measured size of the disk in diskPath. The disks
+// are replaced rather than changed in place, as copies of the media item
+// share them.
+func (app *App) setDiskSize(diskPath string, sizeGB float64) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	for i := range app.mediaList {
+		for j := range app.mediaList[i].Disks {
+			if app.mediaList[i].Disks[j].Path == diskPath {
+				disks := slices.Clone(app.mediaList[i].Disks)
+				disks[j].SizeGB = sizeGB
+				app.mediaList[i].Disks = disks
+			}
+		}
+	}
+}
+
+// allMedia returns a copy of the media list
+func (app *App) allMedia() []Media {
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+
+	list := make([]Media, len(app.mediaList))
+	copy(list, app.mediaList)
+	return list
+}
+
+// setMediaList replaces the media list, e.g. after a rescan
+func (app *App) setMediaList(mediaList []Media) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	app.mediaList = mediaList
+}
+
+// PathProfileHandler remembers which path profile this browser's play
+// commands use, then returns to the page the choice was made on
+func (app *App) PathProfileHandler(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.FormValue("profile")
+	cookie := &http.Cookie{
+		Name:     pathProfileCookieName,
+		Value:    name,
+		Path:     "/",
+		MaxAge:   pathProfileCookieMaxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if name == "" {
+		// Back to the server's default paths
+		cookie.MaxAge = -1
+	} else if FindPathProfile(app.pathProfiles, name) == nil {
+		http.Error(w, "Unknown path profile", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, cookie)
+
+	http.Redirect(w, r, safeRedirectTarget(r.FormValue("next")), http.StatusSeeOther)
+}
+
 // SearchTMDBHandler handles the TMDB search page
 func (app *App) SearchTMDBHandler(w http.ResponseWriter, r *http.Request) {
 	// Check if TMDB client is available
@@ -223,11 +531,8 @@ func (app *App) SearchTMDBHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	// Extract slug from URL: /media/{slug}/search-tmdb
 	path := strings.TrimPrefix(r.URL.Path, "/media/")
@@ -273,7 +578,7 @@ func (app *App) SearchTMDBHandler(w http.ResponseWriter, r *http.Request) {
 			if year == 0 && media.Year > 0 {
 				year = media.Year
 			}
-			movieResults, err := app.tmdbClient.SearchMovies(query, year)
+			movieResults, err := app.tmdbClient.SearchMovies(r.Context(), query, year)
 			if err != nil {
 				searchErr = err
 			} else {
@@ -281,7 +586,7 @@ func (app *App) SearchTMDBHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		} else if media.Type == TV {
 			// Search for TV shows
-			tvResults, err := app.tmdbClient.SearchTV(query)
+			tvResults, err := app.tmdbClient.SearchTV(r.Context(), query)
 			if err != nil {
 				searchErr = err
 			} else {
@@ -293,7 +598,7 @@ func (app *App) SearchTMDBHandler(w http.ResponseWriter, r *http.Request) {
 	// Prepare error message
 	var errorMsg string
 	if searchErr != nil {
-		errorMsg = fmt.Sprintf("Search error: %v", searchErr)
+		errorMsg = tmdbErrorMessage(searchErr)
 	}
 
 	data := struct {
@@ -326,11 +631,8 @@ func (app *App) ConfirmTMDBHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	// Extract slug from URL: /media/{slug}/confirm-tmdb
 	path := strings.TrimPrefix(r.URL.Path, "/media/")
@@ -363,7 +665,7 @@ func (app *App) ConfirmTMDBHandler(w http.ResponseWriter, r *http.Request) {
 	var fetchErr error
 
 	if media.Type == Film {
-		movieData, err := app.tmdbClient.FetchMovieMetadata(tmdbID)
+		movieData, err := app.tmdbClient.FetchMovieMetadata(r.Context(), tmdbID)
 		if err != nil {
 			fetchErr = err
 		} else {
@@ -377,7 +679,7 @@ func (app *App) ConfirmTMDBHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	} else if media.Type == TV {
-		tvData, err := app.tmdbClient.FetchTVMetadata(tmdbID)
+		tvData, err := app.tmdbClient.FetchTVMetadata(r.Context(), tmdbID)
 		if err != nil {
 			fetchErr = err
 		} else {
@@ -399,7 +701,7 @@ func (app *App) ConfirmTMDBHandler(w http.ResponseWriter, r *http.Request) {
 	// Prepare error message
 	var errorMsg string
 	if fetchErr != nil {
-		errorMsg = fmt.Sprintf("Failed to fetch TMDB details: %v", fetchErr)
+		errorMsg = tmdbErrorMessage(fetchErr)
 	}
 
 	data := struct {
@@ -473,10 +775,14 @@ func (app *App) SaveTMDBHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate TMDB ID
-	err = app.tmdbClient.ValidateTMDBID(tmdbID, media.Type)
+	err = app.tmdbClient.ValidateTMDBID(r.Context(), tmdbID, media.Type)
 	if err != nil {
 		log.Printf("Invalid TMDB ID %s for %s: %v", tmdbID, media.Title, err)
-		http.Error(w, fmt.Sprintf("Invalid TMDB ID: %v", err), http.StatusBadRequest)
+		if errors.Is(err, ErrTMDBNotFound) {
+			http.Error(w, "Invalid TMDB ID: "+tmdbErrorMessage(err), http.StatusBadRequest)
+		} else {
+			http.Error(w, tmdbErrorMessage(err), tmdbErrorStatus(err))
+		}
 		return
 	}
 
@@ -488,21 +794,103 @@ func (app *App) SaveTMDBHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update media object
+	// Update the library
+	app.setMediaTMDBID(media.Path, tmdbID)
 	media.TMDBID = tmdbID
 
-	// Check if metadata should be downloaded now
+	detailURL := "/media/" + url.PathEscape(slug)
+
+	// Download metadata in the background if requested, showing progress meanwhile
 	downloadMetadata := r.FormValue("download_metadata") == "true"
 	if downloadMetadata {
-		err = app.tmdbClient.FetchAndSaveMetadata(media)
+		job, err := app.queueMediaJob(jobTypeMetadata, "Metadata for "+media.Title, media)
 		if err != nil {
-			log.Printf("Warning: Failed to fetch metadata for %s: %v", media.Title, err)
-			// Don't fail the request, just log the warning
-		} else {
-			log.Printf("Successfully fetched metadata for %s", media.Title)
+			log.Printf("Failed to queue metadata download for %s: %v", media.Title, err)
+			http.Error(w, "Failed to start metadata download", http.StatusInternalServerError)
+			return
+		}
+		app.respondWithJob(w, r, job)
+		return
+	}
+
+	// Redirect back to detail page
+	http.Redirect(w, r, detailURL, http.StatusSeeOther)
+}
+
+// EditMetadataHandler saves title, description and genre edits made on the detail page.
+// Edits are stored as overrides alongside the TMDB metadata files, so a later
+// FetchAndSaveMetadata run never replaces them. A field that is cleared or set
+// back to the TMDB value stops being overridden.
+func (app *App) EditMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract slug from URL: /media/{slug}/edit
+	path := strings.TrimPrefix(r.URL.Path, "/media/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+	slug := parts[0]
+
+	// Find media by slug
+	media := app.findMediaBySlug(slug)
+	if media == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Parse form data
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	var overrides MetadataOverrides
+
+	// The reset action discards all local edits
+	if r.FormValue("action") != "reset" {
+		title := strings.TrimSpace(r.FormValue("title"))
+		description := strings.TrimSpace(r.FormValue("description"))
+		genres := ParseGenreList(r.FormValue("genres"))
+
+		if err := ValidateMetadataEdit(title, description, genres); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid metadata: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		// Only keep fields that differ from what TMDB provided
+		baseTitle := media.LoadTMDBTitle()
+		if baseTitle == "" {
+			baseTitle = directoryTitle(media.Path)
+		}
+		if title != baseTitle {
+			overrides.Title = title
+		}
+		if description != media.LoadTMDBDescription() {
+			overrides.Description = description
+		}
+		if !equalGenres(genres, media.LoadTMDBGenres()) {
+			overrides.Genres = genres
 		}
 	}
 
+	if err := saveOverrides(media.Path, overrides); err != nil {
+		log.Printf("Failed to save metadata edits for %s: %v", media.Title, err)
+		http.Error(w, "Failed to save metadata", http.StatusInternalServerError)
+		return
+	}
+
+	// Update the library (the slug changes with the title)
+	app.refreshMediaTitle(media.Path)
+	media.Title = media.ResolveTitle()
+	log.Printf("Saved metadata edits for %s", media.Title)
+
 	// Redirect back to detail page
-	http.Redirect(w, r, "/media/"+url.PathEscape(slug), http.StatusSeeOther)
+	http.Redirect(w, r, "/media/"+url.PathEscape(media.Slug()), http.StatusSeeOther)
 }
@@ -4,6 +4,9 @@ import (
 	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -374,3 +377,123 @@ func TestFindMediaBySlug(t *testing.T) {
 		})
 	}
 }
+
+func TestFindMediaReturnsCopy(t *testing.T) {
+	mediaList := []Media{
+		{Title: "The Thing", Type: Film, Year: 1982, Path: "/test/thing", Disks: []Disk{{Name: "Disk", Path: "/test/thing/Disk"}}},
+	}
+	app := NewApp(mediaList, template.Must(template.New("test").Parse("test")), "/test/media", "")
+
+	// Changing a found item leaves the library alone
+	media := app.findMediaByPath("/test/thing")
+	media.TMDBID = "1091"
+	app.setDiskSize("/test/thing/Disk", 42)
+	if media.Disks[0].SizeGB != 0 {
+		t.Error("setDiskSize() changed the disks of a copy")
+	}
+	if got := app.findMediaBySlug("the-thing-1982"); got.TMDBID != "" || got.Disks[0].SizeGB != 42 {
+		t.Errorf("Library item = %+v, want only the disk size changed", got)
+	}
+
+	// The setters change it
+	app.setMediaTMDBID("/test/thing", "1091")
+	if got := app.findMediaByPath("/test/thing"); got.TMDBID != "1091" {
+		t.Errorf("TMDBID = %q after setMediaTMDBID(), want 1091", got.TMDBID)
+	}
+}
+
+func TestEditMetadataHandler(t *testing.T) {
+	testDir := setupTestData(t)
+	mediaList, err := NewScanner(testDir).Scan()
+	if err != nil {
+		t.Fatalf("Failed to scan test directory: %v", err)
+	}
+	filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	os.WriteFile(filepath.Join(filmDir, "description.txt"), []byte("TMDB description"), 0644)
+	os.WriteFile(filepath.Join(filmDir, "genre.txt"), []byte("Science Fiction, Thriller"), 0644)
+
+	tmpl := template.Must(template.New("detail.html").Parse(`{{.Media.Title}}|{{.Description}}|{{.TMDBDescription}}`))
+	app := NewApp(mediaList, tmpl, testDir, "")
+
+	form := url.Values{
+		"title":       {"War of the Worlds: Extended"},
+		"description": {"My own description"},
+		"genres":      {"Science Fiction, Thriller"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/media/war-of-the-worlds-2025/edit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	app.EditMetadataHandler(w, req)
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("EditMetadataHandler() status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+	if location := w.Header().Get("Location"); location != "/media/war-of-the-worlds-extended-2025" {
+		t.Errorf("EditMetadataHandler() redirect = %q, want new slug", location)
+	}
+
+	overrides := loadOverrides(filmDir)
+	if overrides.Title != "War of the Worlds: Extended" || overrides.Description != "My own description" {
+		t.Errorf("Saved overrides = %+v", overrides)
+	}
+	if len(overrides.Genres) != 0 {
+		t.Errorf("Unchanged genres should not be overridden, got %v", overrides.Genres)
+	}
+
+	// A metadata fetch rewrites the TMDB files but never the local edits
+	os.WriteFile(filepath.Join(filmDir, "description.txt"), []byte("Updated TMDB description"), 0644)
+	req = httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-extended-2025", nil)
+	w = httptest.NewRecorder()
+	app.DetailHandler(w, req)
+	if body := w.Body.String(); body != "War of the Worlds: Extended|My own description|Updated TMDB description" {
+		t.Errorf("DetailHandler() body = %q", body)
+	}
+
+	// Reverting removes all local edits
+	form = url.Values{"action": {"reset"}}
+	req = httptest.NewRequest(http.MethodPost, "/media/war-of-the-worlds-extended-2025/edit", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	app.EditMetadataHandler(w, req)
+
+	if location := w.Header().Get("Location"); location != "/media/war-of-the-worlds-2025" {
+		t.Errorf("EditMetadataHandler() reset redirect = %q, want original slug", location)
+	}
+	if _, err := os.Stat(filepath.Join(filmDir, overridesFileName)); !os.IsNotExist(err) {
+		t.Error("Expected overrides file to be removed after reset")
+	}
+}
+
+func TestEditMetadataHandlerValidation(t *testing.T) {
+	testDir := setupTestData(t)
+	mediaList, _ := NewScanner(testDir).Scan()
+	tmpl := template.Must(template.New("test").Parse("test"))
+	app := NewApp(mediaList, tmpl, testDir, "")
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		form           url.Values
+		expectedStatus int
+	}{
+		{"GET not allowed", http.MethodGet, "/media/better-call-saul/edit", nil, http.StatusMethodNotAllowed},
+		{"Unknown media", http.MethodPost, "/media/nonexistent/edit", url.Values{"title": {"x"}}, http.StatusNotFound},
+		{"Title with newline", http.MethodPost, "/media/better-call-saul/edit", url.Values{"title": {"Better\nCall"}}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			app.EditMetadataHandler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("EditMetadataHandler() status = %v, want %v", w.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -195,6 +196,13 @@ func GenerateDiskDirName(diskType string, seriesNum, diskNum int, mediaType Medi
 // ExecuteImport performs the actual import operation
 // Moves the source directory to the destination with validation
 func ExecuteImport(session *ImportSession, mediaDir string) error {
+	return ExecuteImportWithProgress(context.Background(), session, mediaDir, nil)
+}
+
+// ExecuteImportWithProgress performs the import, reporting bytes moved to progress.
+// Moves between filesystems copy the data, which can take a long time for large discs.
+// Cancelling ctx stops the copy and leaves the source where it was.
+func ExecuteImportWithProgress(ctx context.Context, session *ImportSession, mediaDir string, progress MoveProgressFunc) error {
 	if session == nil {
 		return fmt.Errorf("import session is nil")
 	}
@@ -249,14 +257,19 @@ func ExecuteImport(session *ImportSession, mediaDir string) error {
 	}
 
 	// Create media directory if it doesn't exist
+	createdMediaDir := false
 	if _, err := os.Stat(destMediaPath); os.IsNotExist(err) {
 		if err := os.MkdirAll(destMediaPath, 0755); err != nil {
 			return fmt.Errorf("failed to create media directory: %w", err)
 		}
+		createdMediaDir = true
 	}
 
 	// Move the source directory to the destination
-	if err := os.Rename(session.SourceDir.Path, destDiskPath); err != nil {
+	if err := moveDir(ctx, session.SourceDir.Path, destDiskPath, progress); err != nil {
+		if createdMediaDir {
+			os.Remove(destMediaPath) // Only removes it while it's still empty
+		}
 		return fmt.Errorf("failed to move directory: %w", err)
 	}
 
@@ -64,11 +64,8 @@ func (app *App) ImportListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	// Scan import directory
 	imports, err := app.importScanner.Scan()
@@ -180,11 +177,8 @@ func (app *App) ImportStep1Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	data := struct {
 		Session   *ImportSession
@@ -242,14 +236,14 @@ func (app *App) ImportStep2Handler(w http.ResponseWriter, r *http.Request) {
 	// Perform search if query is provided
 	if query != "" && app.tmdbClient != nil {
 		if session.MediaKind == Film {
-			movieResults, err := app.tmdbClient.SearchMovies(query, year)
+			movieResults, err := app.tmdbClient.SearchMovies(r.Context(), query, year)
 			if err != nil {
 				searchErr = err
 			} else {
 				results = movieResults
 			}
 		} else if session.MediaKind == TV {
-			tvResults, err := app.tmdbClient.SearchTV(query)
+			tvResults, err := app.tmdbClient.SearchTV(r.Context(), query)
 			if err != nil {
 				searchErr = err
 			} else {
@@ -261,14 +255,11 @@ func (app *App) ImportStep2Handler(w http.ResponseWriter, r *http.Request) {
 	// Prepare error message
 	var errorMsg string
 	if searchErr != nil {
-		errorMsg = fmt.Sprintf("Search error: %v", searchErr)
+		errorMsg = tmdbErrorMessage(searchErr)
 	}
 
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	data := struct {
 		Session       *ImportSession
@@ -319,9 +310,9 @@ func (app *App) ImportStep2ConfirmHandler(w http.ResponseWriter, r *http.Request
 
 	// Fetch metadata from TMDB
 	if session.MediaKind == Film {
-		movie, err := app.tmdbClient.FetchMovieMetadata(tmdbID)
+		movie, err := app.tmdbClient.FetchMovieMetadata(r.Context(), tmdbID)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to fetch movie metadata: %v", err), http.StatusInternalServerError)
+			http.Error(w, tmdbErrorMessage(err), tmdbErrorStatus(err))
 			return
 		}
 		session.TMDBID = tmdbID
@@ -339,9 +330,9 @@ func (app *App) ImportStep2ConfirmHandler(w http.ResponseWriter, r *http.Request
 			session.TMDBGenres[i] = genre.Name
 		}
 	} else if session.MediaKind == TV {
-		tv, err := app.tmdbClient.FetchTVMetadata(tmdbID)
+		tv, err := app.tmdbClient.FetchTVMetadata(r.Context(), tmdbID)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to fetch TV metadata: %v", err), http.StatusInternalServerError)
+			http.Error(w, tmdbErrorMessage(err), tmdbErrorStatus(err))
 			return
 		}
 		session.TMDBID = tmdbID
@@ -403,11 +394,8 @@ func (app *App) ImportStep3Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	data := struct {
 		Session   *ImportSession
@@ -496,11 +484,8 @@ func (app *App) ImportStep4Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	data := struct {
 		Session   *ImportSession
@@ -571,17 +556,14 @@ func (app *App) ImportStep5Handler(w http.ResponseWriter, r *http.Request) {
 
 	// Get compatible existing media (same type)
 	var compatibleMedia []Media
-	for _, media := range app.mediaList {
+	for _, media := range app.allMedia() {
 		if media.Type == session.MediaKind {
 			compatibleMedia = append(compatibleMedia, media)
 		}
 	}
 
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	data := struct {
 		Session         *ImportSession
@@ -615,11 +597,8 @@ func (app *App) ImportConfirmHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	// Generate preview of destination path
 	finalTitle := session.Title
@@ -687,61 +666,28 @@ func (app *App) ImportExecuteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Execute the import
-	err := ExecuteImport(session, app.mediaDir)
-	if err != nil {
-		log.Printf("Import failed: %v", err)
-		http.Error(w, fmt.Sprintf("Import failed: %v", err), http.StatusInternalServerError)
-		return
+	// Determine the final title (prefer TMDB title if available)
+	finalTitle := session.Title
+	if session.TMDBTitle != "" {
+		finalTitle = session.TMDBTitle
 	}
 
-	// Fetch and save TMDB metadata if available
-	if session.TMDBID != "" && app.tmdbClient != nil {
-		// Determine the media path
-		var mediaPath string
-		if session.AddToExisting {
-			mediaPath = session.ExistingMediaPath
-		} else {
-			finalTitle := session.Title
-			if session.TMDBTitle != "" {
-				finalTitle = session.TMDBTitle
-			}
-			finalYear := session.Year
-			if session.TMDBYear > 0 {
-				finalYear = session.TMDBYear
-			}
-			mediaDir := GenerateMediaDirName(finalTitle, finalYear, session.MediaKind)
-			mediaPath = app.mediaDir + "/" + mediaDir
-		}
-
-		// Create a temporary Media object for metadata fetching
-		media := &Media{
-			Type:   session.MediaKind,
-			TMDBID: session.TMDBID,
-			Path:   mediaPath,
-		}
-
-		err = app.tmdbClient.FetchAndSaveMetadata(media)
-		if err != nil {
-			log.Printf("Warning: Failed to fetch metadata: %v", err)
-			// Don't fail the import, just log the warning
-		}
+	// Moving a disc between filesystems can take a long time, so run the
+	// import in the background and let the browser follow its progress
+	job, err := app.jobs.Enqueue(jobTypeImport, "Import "+finalTitle, importJobParams{SessionID: sessionID, Session: session})
+	if err != nil {
+		log.Printf("Failed to queue import: %v", err)
+		http.Error(w, "Failed to start import", http.StatusInternalServerError)
+		return
 	}
 
-	// Clean up session
-	importSessionStore.Delete(sessionID)
-
-	// Redirect to success page
-	http.Redirect(w, r, "/import/success", http.StatusSeeOther)
+	app.respondWithJob(w, r, job)
 }
 
 // ImportSuccessHandler shows the import success page
 func (app *App) ImportSuccessHandler(w http.ResponseWriter, r *http.Request) {
-	// Reload templates in dev mode
-	tmpl := app.templates
-	if app.devMode {
-		tmpl = app.loadTemplates()
-	}
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
 
 	err := tmpl.ExecuteTemplate(w, "import_success.html", nil)
 	if err != nil {
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -20,7 +19,7 @@ func TestIntegrationScanAndServe(t *testing.T) {
 	}
 
 	// Load templates
-	tmpl, err := template.ParseFiles("templates/index.html")
+	tmpl, err := parseTemplates(templateFS(""))
 	if err != nil {
 		t.Fatalf("Failed to parse template: %v", err)
 	}
@@ -81,7 +80,7 @@ func TestIntegrationWithEmptyDirectory(t *testing.T) {
 	}
 
 	// Load templates
-	tmpl, err := template.ParseFiles("templates/index.html")
+	tmpl, err := parseTemplates(templateFS(""))
 	if err != nil {
 		t.Fatalf("Failed to parse template: %v", err)
 	}
@@ -242,7 +241,7 @@ func TestIntegrationEndToEnd(t *testing.T) {
 	}
 
 	// Load templates
-	tmpl, err := template.ParseFiles("templates/index.html")
+	tmpl, err := parseTemplates(templateFS(""))
 	if err != nil {
 		t.Fatalf("Template parsing failed: %v", err)
 	}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sseKeepAliveInterval is how often a comment is sent on idle event streams
+// so proxies don't close the connection during long steps
+const sseKeepAliveInterval = 15 * time.Second
+
+// respondWithJob answers a request that started a job. Scripts asking for JSON
+// get the job's URLs; plain form posts are redirected to the job's progress page.
+func (app *App) respondWithJob(w http.ResponseWriter, r *http.Request, job *Job) {
+	progress := job.Progress()
+	pageURL := "/jobs/" + url.PathEscape(progress.ID)
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"id":         progress.ID,
+			"page_url":   pageURL,
+			"events_url": pageURL + "/events",
+		})
+		return
+	}
+
+	http.Redirect(w, r, pageURL, http.StatusSeeOther)
+}
+
+// wantsJSON reports whether a script asked for JSON rather than a page
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// JobsHandler lists running, queued and finished jobs. Scripts asking for
+// JSON get the list, optionally filtered with ?status=.
+func (app *App) JobsHandler(w http.ResponseWriter, r *http.Request) {
+	jobs := app.jobs.List()
+
+	if wantsJSON(r) {
+		list := make([]JobProgress, 0, len(jobs))
+		for _, job := range jobs {
+			if status := r.URL.Query().Get("status"); status == "" || string(job.Status) == status {
+				list = append(list, job)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	// Group jobs for the page, newest first
+	var data struct {
+		Running  []JobProgress
+		Queued   []JobProgress
+		Failed   []JobProgress
+		Finished []JobProgress
+	}
+	for i := len(jobs) - 1; i >= 0; i-- {
+		switch jobs[i].Status {
+		case JobRunning:
+			data.Running = append(data.Running, jobs[i])
+		case JobQueued:
+			data.Queued = append([]JobProgress{jobs[i]}, data.Queued...) // Next to run first
+		case JobFailed:
+			data.Failed = append(data.Failed, jobs[i])
+		default:
+			data.Finished = append(data.Finished, jobs[i])
+		}
+	}
+
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
+	err := tmpl.ExecuteTemplate(w, "jobs.html", data)
+	if err != nil {
+		log.Printf("Error rendering jobs template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// CancelJobHandler cancels a queued or running job: /jobs/{id}/cancel
+func (app *App) CancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/cancel")
+	if err := app.jobs.Cancel(id); errors.Is(err, ErrJobNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Canceled " + id})
+		return
+	}
+	http.Redirect(w, r, safeRedirectTarget(r.FormValue("next")), http.StatusSeeOther)
+}
+
+// JobHandler shows the progress page for a job, or its progress as JSON
+func (app *App) JobHandler(w http.ResponseWriter, r *http.Request) {
+	// Load templates (reloaded in dev mode)
+	tmpl := app.templatesFor(r)
+
+	// Extract job ID from URL: /jobs/{id}
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+	job, ok := app.jobs.Get(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job.Progress())
+		return
+	}
+
+	err := tmpl.ExecuteTemplate(w, "job.html", job.Progress())
+	if err != nil {
+		log.Printf("Error rendering job template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// JobEventsHandler streams a job's progress as Server-Sent Events.
+// A "progress" event is sent with the current state and after every change,
+// followed by a single "done" event once the job finishes.
+func (app *App) JobEventsHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract job ID from URL: /jobs/{id}/events
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/events")
+	job, ok := app.jobs.Get(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable buffering in nginx
+
+	// Subscribe before reading the first snapshot so no change is missed
+	updates, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		progress := job.Progress()
+		event := "progress"
+		if progress.Done() {
+			event = "done"
+		}
+		if err := writeSSEEvent(w, event, progress); err != nil {
+			return
+		}
+		flusher.Flush()
+		if progress.Done() {
+			return
+		}
+
+		select {
+		case <-updates:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSEEvent writes a named Server-Sent Event with a JSON payload
+func writeSSEEvent(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+// RescanHandler starts a background rescan of the media directory
+func (app *App) RescanHandler(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, err := app.jobs.Enqueue(jobTypeScan, "Rescan library", struct{}{})
+	if err != nil {
+		log.Printf("Failed to queue rescan: %v", err)
+		http.Error(w, "Failed to start rescan", http.StatusInternalServerError)
+		return
+	}
+
+	app.respondWithJob(w, r, job)
+}
+
+// rescan scans the media directory with scanner and replaces the media list,
+// reporting each directory scanned to job
+func (app *App) rescan(job *Job, scanner *Scanner) error {
+	scanner.SetProgressFunc(func(done, total int, current string) {
+		job.Update(func(p *JobProgress) {
+			p.ItemsDone, p.ItemsTotal = done, total
+			p.Step = "Scanning " + current
+		})
+	})
+
+	mediaList, err := scanner.Scan()
+	if err != nil {
+		return fmt.Errorf("failed to scan media directory: %w", err)
+	}
+	app.setMediaList(mediaList)
+	log.Printf("Rescan found %d media items", len(mediaList))
+	return nil
+}
+
+// MediaJobHandler queues a job for a media item: /media/{slug}/verify checks
+// its disks and /media/{slug}/sizes measures them again
+func (app *App) MediaJobHandler(w http.ResponseWriter, r *http.Request) {
+	media := app.mediaForRemote(w, r)
+	if media == nil {
+		return
+	}
+
+	var job *Job
+	var err error
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/verify"):
+		job, err = app.queueMediaJob(jobTypeVerify, "Verify "+media.Title, media)
+	case strings.HasSuffix(r.URL.Path, "/sizes"):
+		job, err = app.queueMediaJob(jobTypeSize, "Disk sizes for "+media.Title, media)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to queue job for %s: %v", media.Title, err)
+		http.Error(w, "Failed to start job", http.StatusInternalServerError)
+		return
+	}
+
+	app.respondWithJob(w, r, job)
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sseEvent is a parsed Server-Sent Event
+type sseEvent struct {
+	Name string
+	Data JobProgress
+}
+
+// readSSEEvents reads events from an event stream until it closes
+func readSSEEvents(t *testing.T, body string) []sseEvent {
+	t.Helper()
+
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.Data); err != nil {
+				t.Fatalf("Invalid event data %q: %v", line, err)
+			}
+		case line == "" && current.Name != "":
+			events = append(events, current)
+			current = sseEvent{}
+		}
+	}
+	return events
+}
+
+func TestJobEventsHandler(t *testing.T) {
+	app := NewApp(nil, nil, t.TempDir(), "")
+
+	release := make(chan struct{})
+	job := app.jobs.Start("test", "Test job", func(job *Job) (string, error) {
+		job.SetStep("Moving files")
+		job.SetBytes(50, 100)
+		<-release
+		return "/done", nil
+	})
+
+	// The stream ends once the job finishes
+	server := httptest.NewServer(http.HandlerFunc(app.JobEventsHandler))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/jobs/" + job.Progress().ID + "/events")
+	if err != nil {
+		t.Fatalf("GET events error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	close(release)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read event stream: %v", err)
+	}
+
+	events := readSSEEvents(t, string(body))
+	if len(events) < 2 {
+		t.Fatalf("Got %d events, want at least 2: %q", len(events), body)
+	}
+	if events[0].Name != "progress" || events[0].Data.Status != JobRunning {
+		t.Errorf("First event = %s %v, want running progress", events[0].Name, events[0].Data.Status)
+	}
+	last := events[len(events)-1]
+	if last.Name != "done" || last.Data.Status != JobSucceeded || last.Data.ResultURL != "/done" {
+		t.Errorf("Last event = %s %+v, want done with result", last.Name, last.Data)
+	}
+
+	// A finished job's stream sends the final state immediately
+	resp2, err := http.Get(server.URL + "/jobs/" + job.Progress().ID + "/events")
+	if err != nil {
+		t.Fatalf("GET events error = %v", err)
+	}
+	defer resp2.Body.Close()
+	replay, _ := io.ReadAll(resp2.Body)
+	if events := readSSEEvents(t, string(replay)); len(events) != 1 || events[0].Name != "done" {
+		t.Errorf("Replay events = %+v, want a single done event", events)
+	}
+}
+
+func TestJobEventsHandlerUnknownJob(t *testing.T) {
+	app := NewApp(nil, nil, t.TempDir(), "")
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/job-404/events", nil)
+	w := httptest.NewRecorder()
+	app.JobEventsHandler(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("JobEventsHandler() status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestJobHandler(t *testing.T) {
+	tmpl, err := parseTemplates(templateFS(""))
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+	app := NewApp(nil, tmpl, t.TempDir(), "")
+
+	job := app.jobs.Start("scan", "Rescan library", func(job *Job) (string, error) {
+		return "/", nil
+	})
+	waitForJob(t, job)
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/"+job.Progress().ID, nil)
+	w := httptest.NewRecorder()
+	app.JobHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("JobHandler() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, expected := range []string{"Rescan library", "Finished", "Continue", "/static/jobs.js?v="} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("JobHandler() body does not contain %q", expected)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/jobs/job-404", nil)
+	w = httptest.NewRecorder()
+	app.JobHandler(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("JobHandler() unknown job status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestRescanHandler(t *testing.T) {
+	testDir := setupTestData(t)
+	app := NewApp(nil, nil, testDir, "")
+
+	// Form posts are redirected to the progress page
+	req := httptest.NewRequest(http.MethodPost, "/rescan", nil)
+	w := httptest.NewRecorder()
+	app.RescanHandler(w, req)
+
+	if w.Code != http.StatusSeeOther || !strings.HasPrefix(w.Header().Get("Location"), "/jobs/") {
+		t.Fatalf("RescanHandler() = %v %q, want redirect to job page", w.Code, w.Header().Get("Location"))
+	}
+
+	job, ok := app.jobs.Get(strings.TrimPrefix(w.Header().Get("Location"), "/jobs/"))
+	if !ok {
+		t.Fatal("Rescan job not found")
+	}
+	progress := waitForJob(t, job)
+	if progress.Status != JobSucceeded {
+		t.Fatalf("Rescan job status = %v (%s), want succeeded", progress.Status, progress.Error)
+	}
+	if progress.ItemsTotal == 0 || progress.ItemsDone != progress.ItemsTotal {
+		t.Errorf("Rescan items = %d/%d, want all scanned", progress.ItemsDone, progress.ItemsTotal)
+	}
+	if app.findMediaBySlug("war-of-the-worlds-2025") == nil {
+		t.Error("Media list not replaced after rescan")
+	}
+
+	// Scripts get the job URLs as JSON
+	req = httptest.NewRequest(http.MethodPost, "/rescan", nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	app.RescanHandler(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("RescanHandler() JSON status = %v, want %v", w.Code, http.StatusAccepted)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if !strings.HasSuffix(resp["events_url"], "/events") || resp["id"] == "" {
+		t.Errorf("RescanHandler() JSON = %v, want id and events_url", resp)
+	}
+
+	// Only POST starts a rescan
+	req = httptest.NewRequest(http.MethodGet, "/rescan", nil)
+	w = httptest.NewRecorder()
+	app.RescanHandler(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("RescanHandler() GET status = %v, want %v", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestImportExecuteHandlerRunsJob(t *testing.T) {
+	tmpDir := t.TempDir()
+	mediaDir := filepath.Join(tmpDir, "media")
+	sourceDir := filepath.Join(tmpDir, "import", "source-disk")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(mediaDir, 0755)
+	os.WriteFile(filepath.Join(sourceDir, "test.txt"), []byte("test"), 0644)
+
+	sessionID := importSessionStore.Create(&ImportSession{
+		SourceDir: &ImportDirectory{Name: "source-disk", Path: sourceDir},
+		MediaKind: Film,
+		Title:     "Test Film",
+		Year:      2020,
+		DiskType:  DiskTypeBluRay,
+	})
+
+	app := NewApp(nil, nil, mediaDir, filepath.Join(tmpDir, "import"))
+
+	form := url.Values{"session": {sessionID}}
+	req := httptest.NewRequest(http.MethodPost, "/import/execute", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	app.ImportExecuteHandler(w, req)
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("ImportExecuteHandler() status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+	job, ok := app.jobs.Get(strings.TrimPrefix(w.Header().Get("Location"), "/jobs/"))
+	if !ok {
+		t.Fatalf("Import job not found for %q", w.Header().Get("Location"))
+	}
+
+	progress := waitForJob(t, job)
+	if progress.Status != JobSucceeded || progress.ResultURL != "/import/success" {
+		t.Fatalf("Import job = %v %q (%s), want success", progress.Status, progress.ResultURL, progress.Error)
+	}
+	if _, err := os.Stat(filepath.Join(mediaDir, "Test Film (2020) [Film]", "Disk [Blu-Ray]", "test.txt")); err != nil {
+		t.Errorf("Imported file missing: %v", err)
+	}
+	if _, ok := importSessionStore.Get(sessionID); ok {
+		t.Error("Import session not cleaned up")
+	}
+
+	// The library is refreshed so the import shows up immediately
+	if app.findMediaBySlug("test-film-2020") == nil {
+		t.Error("Imported media not in media list")
+	}
+}
+
+func TestJobsHandler(t *testing.T) {
+	tmpl, err := parseTemplates(templateFS(""))
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+	app := NewApp(nil, tmpl, t.TempDir(), "")
+	app.jobs.SetConcurrency(1)
+
+	release := make(chan struct{})
+	defer close(release)
+	failed := app.jobs.Start("test", "Broken job", func(job *Job) (string, error) {
+		job.Logf("Looking for the disk")
+		return "", errors.New("disk missing")
+	})
+	waitForJob(t, failed)
+	running := app.jobs.Start("test", "Long job", func(job *Job) (string, error) {
+		<-release
+		return "", nil
+	})
+	app.jobs.Start("test", "Waiting job", func(job *Job) (string, error) { return "", nil })
+	waitForStatus(t, running, JobRunning)
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	w := httptest.NewRecorder()
+	app.JobsHandler(w, req)
+	body := w.Body.String()
+	for _, expected := range []string{"Long job", "Waiting job", "Broken job", "disk missing", "Looking for the disk", "Cancel"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("JobsHandler() body does not contain %q", expected)
+		}
+	}
+
+	// Scripts get the jobs as JSON, optionally filtered by status
+	req = httptest.NewRequest(http.MethodGet, "/jobs?status=failed", nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	app.JobsHandler(w, req)
+	var jobs []JobProgress
+	if err := json.Unmarshal(w.Body.Bytes(), &jobs); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Title != "Broken job" || len(jobs[0].Log) != 1 {
+		t.Errorf("JobsHandler() failed jobs = %+v, want the broken job with its log", jobs)
+	}
+
+	// A single job as JSON
+	req = httptest.NewRequest(http.MethodGet, "/jobs/"+running.Progress().ID, nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	app.JobHandler(w, req)
+	var progress JobProgress
+	if err := json.Unmarshal(w.Body.Bytes(), &progress); err != nil || progress.Status != JobRunning {
+		t.Errorf("JobHandler() JSON = %q, want running job", w.Body.String())
+	}
+}
+
+func TestCancelJobHandler(t *testing.T) {
+	app := NewApp(nil, nil, t.TempDir(), "")
+	job := app.jobs.Start("test", "Long job", func(job *Job) (string, error) {
+		<-job.Context().Done()
+		return "", job.Context().Err()
+	})
+
+	form := url.Values{"next": {"/jobs"}}
+	req := httptest.NewRequest(http.MethodPost, "/jobs/"+job.Progress().ID+"/cancel", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	app.CancelJobHandler(w, req)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/jobs" {
+		t.Fatalf("CancelJobHandler() = %v %q, want redirect to /jobs", w.Code, w.Header().Get("Location"))
+	}
+	if progress := waitForJob(t, job); progress.Status != JobCanceled {
+		t.Errorf("Job status = %v, want %v", progress.Status, JobCanceled)
+	}
+
+	// Finished and unknown jobs
+	for path, want := range map[string]int{
+		"/jobs/" + job.Progress().ID + "/cancel": http.StatusConflict,
+		"/jobs/job-999/cancel":                   http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		w := httptest.NewRecorder()
+		app.CancelJobHandler(w, req)
+		if w.Code != want {
+			t.Errorf("CancelJobHandler(%s) status = %v, want %v", path, w.Code, want)
+		}
+	}
+}
+
+func TestMediaJobHandler(t *testing.T) {
+	testDir := setupTestData(t)
+	mediaList, err := NewScanner(testDir).Scan()
+	if err != nil {
+		t.Fatalf("Failed to scan test data: %v", err)
+	}
+	app := NewApp(mediaList, nil, testDir, "")
+
+	post := func(path string) *Job {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		w := httptest.NewRecorder()
+		app.MediaJobHandler(w, req)
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("MediaJobHandler(%s) status = %v, want %v", path, w.Code, http.StatusSeeOther)
+		}
+		job, ok := app.jobs.Get(strings.TrimPrefix(w.Header().Get("Location"), "/jobs/"))
+		if !ok {
+			t.Fatalf("Job not found for %q", w.Header().Get("Location"))
+		}
+		return job
+	}
+
+	// The test disks are empty, so verification reports them
+	progress := waitForJob(t, post("/media/war-of-the-worlds-2025/verify"))
+	if progress.Status != JobFailed || !strings.Contains(strings.Join(progress.Log, "\n"), "missing BDMV/index.bdmv") {
+		t.Errorf("Verify job = %v %q, want failure listing the missing index", progress.Status, progress.Log)
+	}
+
+	// Sizes are measured and cached
+	disk := app.findMediaBySlug("war-of-the-worlds-2025").Disks[0]
+	os.WriteFile(filepath.Join(disk.Path, "movie.m2ts"), make([]byte, 2048), 0644)
+	progress = waitForJob(t, post("/media/war-of-the-worlds-2025/sizes"))
+	if progress.Status != JobSucceeded || progress.ResultURL != "/media/war-of-the-worlds-2025" {
+		t.Fatalf("Size job = %v %q (%s), want success", progress.Status, progress.ResultURL, progress.Error)
+	}
+	if cache := loadSizeCache(filepath.Dir(disk.Path)); cache[filepath.Base(disk.Path)] != 2048 {
+		t.Errorf("Size cache = %v, want 2048 bytes for the disk", cache)
+	}
+	if got := app.findMediaBySlug("war-of-the-worlds-2025").Disks[0].SizeGB; got <= 0 {
+		t.Errorf("Disk size = %v, want it updated", got)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+// Kinds of queued job
+const (
+	jobTypeScan     = "scan"
+	jobTypeMetadata = "metadata"
+	jobTypeSize     = "size"
+	jobTypeVerify   = "verify"
+	jobTypeImport   = "import"
+	jobTypeRefresh  = "refresh"
+	jobTypeMatch    = "match"
+)
+
+// mediaJobParams identifies the media item a job works on. The directory is
+// used rather than the slug, which changes when the title is edited.
+type mediaJobParams struct {
+	Path string `json:"path"`
+}
+
+// importJobParams carries an import's choices, so a queued import survives
+// a restart even though import sessions don't
+type importJobParams struct {
+	SessionID string         `json:"session_id"`
+	Session   *ImportSession `json:"session"`
+}
+
+// registerJobTypes makes the app's jobs available to the job queue
+func (app *App) registerJobTypes() {
+	app.jobs.RegisterType(jobTypeScan, JobType{Run: app.runScanJob, Concurrency: 1, Resumable: true})
+	app.jobs.RegisterType(jobTypeMetadata, JobType{
+		Run:         app.runMetadataJob,
+		MaxAttempts: 3,
+		RetryDelay:  30 * time.Second,
+		Concurrency: 2, // Be gentle with TMDB
+		Resumable:   true,
+	})
+	app.jobs.RegisterType(jobTypeRefresh, JobType{
+		Run:         app.runRefreshJob,
+		MaxAttempts: 3,
+		RetryDelay:  30 * time.Second,
+		Concurrency: 2,
+		Resumable:   true,
+	})
+	app.jobs.RegisterType(jobTypeMatch, JobType{Run: app.runMatchJob, Concurrency: 1, Resumable: true})
+	app.jobs.RegisterType(jobTypeSize, JobType{Run: app.runSizeJob, Concurrency: 1, Resumable: true})
+	app.jobs.RegisterType(jobTypeVerify, JobType{Run: app.runVerifyJob, Concurrency: 1, Resumable: true})
+
+	// An interrupted move can't simply be started again, so imports aren't resumable
+	app.jobs.RegisterType(jobTypeImport, JobType{Run: app.runImportJob, Concurrency: 1})
+}
+
+// queueMediaJob queues a job working on a media item
+func (app *App) queueMediaJob(kind, title string, media *Media) (*Job, error) {
+	return app.jobs.Enqueue(kind, title, mediaJobParams{Path: media.Path})
+}
+
+// queueFollowUpJobs queues size jobs for media directories a scan skipped
+// sizing, and metadata jobs for media with a TMDB ID but missing metadata
+func (app *App) queueFollowUpJobs(unsized []string) {
+	for _, path := range unsized {
+		if media := app.findMediaByPath(path); media != nil {
+			if _, err := app.queueMediaJob(jobTypeSize, "Disk sizes for "+media.Title, media); err != nil {
+				log.Printf("Warning: Failed to queue job: %v", err)
+			}
+		}
+	}
+
+	if app.tmdbClient == nil {
+		return
+	}
+	for _, media := range app.allMedia() {
+		if media.TMDBID != "" && !hasAllMetadata(media.Path) {
+			if _, err := app.queueMediaJob(jobTypeMetadata, "Metadata for "+media.Title, &media); err != nil {
+				log.Printf("Warning: Failed to queue job: %v", err)
+			}
+		}
+	}
+}
+
+// mediaForJob returns a copy of the media item a job works on
+func (app *App) mediaForJob(job *Job) (*Media, error) {
+	var params mediaJobParams
+	if err := job.Params(&params); err != nil {
+		return nil, err
+	}
+	media := app.findMediaByPath(params.Path)
+	if media == nil {
+		return nil, fmt.Errorf("%s is no longer in the library", filepath.Base(params.Path))
+	}
+	return media, nil
+}
+
+// runScanJob rescans the media directory
+func (app *App) runScanJob(job *Job) (string, error) {
+	job.SetStep("Scanning media directory")
+	scanner := NewScanner(app.mediaDir)
+	scanner.SetDeferSizes(true)
+	if err := app.rescan(job, scanner); err != nil {
+		return "", err
+	}
+	app.queueFollowUpJobs(scanner.Unsized())
+	return "/", nil
+}
+
+// runMetadataJob downloads a media item's missing TMDB metadata
+func (app *App) runMetadataJob(job *Job) (string, error) {
+	media, err := app.mediaForJob(job)
+	if err != nil {
+		return "", err
+	}
+	if app.tmdbClient == nil {
+		return "", errors.New("TMDB is not configured")
+	}
+
+	job.SetStep("Downloading metadata from TMDB")
+	if err := app.tmdbClient.FetchAndSaveMetadata(job.Context(), media); err != nil {
+		return "", fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	job.Logf("Saved metadata for %s", media.Title)
+	return "/media/" + url.PathEscape(media.Slug()), nil
+}
+
+// runSizeJob measures a media item's disks and updates its size cache
+func (app *App) runSizeJob(job *Job) (string, error) {
+	media, err := app.mediaForJob(job)
+	if err != nil {
+		return "", err
+	}
+
+	cache := loadSizeCache(media.Path)
+	disks := media.Disks
+	for i := range disks {
+		if err := job.Context().Err(); err != nil {
+			return "", err
+		}
+		job.Update(func(p *JobProgress) {
+			p.Step = "Measuring " + disks[i].Name
+			p.ItemsDone, p.ItemsTotal = i, len(disks)
+		})
+
+		size, err := calculateDirSize(disks[i].Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to measure %s: %w", disks[i].Name, err)
+		}
+		cache[filepath.Base(disks[i].Path)] = size
+		app.setDiskSize(disks[i].Path, float64(size)/(1024*1024*1024))
+		job.Logf("%s is %.1f GB", disks[i].Name, float64(size)/(1024*1024*1024))
+	}
+	job.SetItems(len(disks), len(disks))
+
+	if err := saveSizeCache(media.Path, cache); err != nil {
+		return "", fmt.Errorf("failed to save size cache: %w", err)
+	}
+	return "/media/" + url.PathEscape(media.Slug()), nil
+}
+
+// runVerifyJob checks that every disk of a media item has the files players need
+func (app *App) runVerifyJob(job *Job) (string, error) {
+	media, err := app.mediaForJob(job)
+	if err != nil {
+		return "", err
+	}
+
+	problems := 0
+	for i := range media.Disks {
+		if err := job.Context().Err(); err != nil {
+			return "", err
+		}
+		disk := &media.Disks[i]
+		job.Update(func(p *JobProgress) {
+			p.Step = "Verifying " + disk.Name
+			p.ItemsDone, p.ItemsTotal = i, len(media.Disks)
+		})
+
+		found := verifyDisk(disk)
+		for _, problem := range found {
+			job.Logf("%s: %s", disk.Name, problem)
+		}
+		if len(found) == 0 {
+			job.Logf("%s: OK", disk.Name)
+		}
+		problems += len(found)
+	}
+	job.SetItems(len(media.Disks), len(media.Disks))
+
+	if problems > 0 {
+		return "", fmt.Errorf("found %d problems, see the log", problems)
+	}
+	return "/media/" + url.PathEscape(media.Slug()), nil
+}
+
+// runImportJob moves a disk into the library and refreshes the library
+func (app *App) runImportJob(job *Job) (string, error) {
+	var params importJobParams
+	if err := job.Params(&params); err != nil {
+		return "", err
+	}
+	if params.Session == nil {
+		return "", errors.New("import job has no session")
+	}
+	if err := job.Context().Err(); err != nil {
+		return "", err
+	}
+
+	job.SetStep("Moving files")
+	if err := ExecuteImportWithProgress(job.Context(), params.Session, app.mediaDir, job.SetBytes); err != nil {
+		return "", fmt.Errorf("import failed: %w", err)
+	}
+	job.Logf("Moved %s into the library", params.Session.SourceDir.Name)
+
+	// Make the new disk visible in the library, then fetch its metadata
+	job.SetStep("Refreshing library")
+	scanner := NewScanner(app.mediaDir)
+	scanner.SetDeferSizes(true)
+	if err := app.rescan(job, scanner); err != nil {
+		job.Logf("Warning: %v", err)
+	}
+	app.queueFollowUpJobs(scanner.Unsized())
+
+	importSessionStore.Delete(params.SessionID)
+	return "/import/success", nil
+}
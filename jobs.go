@@ -0,0 +1,632 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobStatus represents the lifecycle state of a background job
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// JobProgress is a snapshot of a job's progress, sent to subscribers as it changes
+type JobProgress struct {
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	Title      string    `json:"title"`
+	Status     JobStatus `json:"status"`
+	Step       string    `json:"step"`                  // Human readable description of the current step
+	BytesDone  int64     `json:"bytes_done,omitempty"`  // Bytes moved so far
+	BytesTotal int64     `json:"bytes_total,omitempty"` // Total bytes to move
+	ItemsDone  int       `json:"items_done,omitempty"`  // Items processed so far
+	ItemsTotal int       `json:"items_total,omitempty"` // Total items to process
+	ResultURL  string    `json:"result_url,omitempty"`  // Page to show when the job succeeds
+	Error      string    `json:"error,omitempty"`
+	QueuedAt   time.Time `json:"queued_at"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+
+	Attempt     int       `json:"attempt,omitempty"`      // Runs so far, counting the current one
+	MaxAttempts int       `json:"max_attempts,omitempty"` // Runs allowed before the job fails
+	RetryAt     time.Time `json:"retry_at,omitempty"`     // When a failed run will be retried
+	Log         []string  `json:"log,omitempty"`          // Timestamped messages from the job
+
+	Params json.RawMessage `json:"params,omitempty"` // Arguments of registered job types
+}
+
+// Done reports whether the job has finished
+func (p JobProgress) Done() bool {
+	return p.Status == JobSucceeded || p.Status == JobFailed || p.Status == JobCanceled
+}
+
+// Percent returns the completion percentage, preferring byte counts over item counts.
+// Returns -1 when the total is unknown.
+func (p JobProgress) Percent() int {
+	switch {
+	case p.BytesTotal > 0:
+		return int(p.BytesDone * 100 / p.BytesTotal)
+	case p.ItemsTotal > 0:
+		return p.ItemsDone * 100 / p.ItemsTotal
+	default:
+		return -1
+	}
+}
+
+// Job is a long-running operation whose progress can be watched
+type Job struct {
+	mu          sync.Mutex
+	progress    JobProgress
+	subscribers map[chan struct{}]struct{}
+
+	fn     JobFunc
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newJob creates a job from its progress, ready to be queued
+func newJob(progress JobProgress, fn JobFunc) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Job{
+		progress:    progress,
+		subscribers: make(map[chan struct{}]struct{}),
+		fn:          fn,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Context returns a context that is canceled when the job is canceled.
+// Long-running jobs should check it between steps.
+func (j *Job) Context() context.Context {
+	return j.ctx
+}
+
+// Params decodes the job's parameters into v
+func (j *Job) Params(v interface{}) error {
+	params := j.Progress().Params
+	if len(params) == 0 {
+		return errors.New("job has no parameters")
+	}
+	return json.Unmarshal(params, v)
+}
+
+// maxJobLogLines bounds each job's log so a chatty job can't grow without limit
+const maxJobLogLines = 200
+
+// Logf adds a timestamped message to the job's log and the server log
+func (j *Job) Logf(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Printf("Job %s: %s", j.Progress().ID, message)
+	j.Update(func(p *JobProgress) {
+		p.Log = append(p.Log, time.Now().Format("15:04:05")+" "+message)
+		if len(p.Log) > maxJobLogLines {
+			p.Log = p.Log[len(p.Log)-maxJobLogLines:]
+		}
+	})
+}
+
+// Progress returns a snapshot of the job's current progress
+func (j *Job) Progress() JobProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress
+}
+
+// Update applies a change to the job's progress and notifies subscribers
+func (j *Job) Update(update func(p *JobProgress)) {
+	j.mu.Lock()
+	update(&j.progress)
+	j.notifyLocked()
+	j.mu.Unlock()
+}
+
+// SetStep records the current step, resetting byte and item counters
+func (j *Job) SetStep(step string) {
+	j.Update(func(p *JobProgress) {
+		p.Step = step
+		p.BytesDone, p.BytesTotal = 0, 0
+		p.ItemsDone, p.ItemsTotal = 0, 0
+	})
+}
+
+// SetBytes records the number of bytes moved so far
+func (j *Job) SetBytes(done, total int64) {
+	j.Update(func(p *JobProgress) {
+		p.BytesDone, p.BytesTotal = done, total
+	})
+}
+
+// SetItems records the number of items processed so far
+func (j *Job) SetItems(done, total int) {
+	j.Update(func(p *JobProgress) {
+		p.ItemsDone, p.ItemsTotal = done, total
+	})
+}
+
+// Subscribe returns a channel that receives a signal whenever the job's progress
+// changes, and a function to stop the subscription. Signals are coalesced, so
+// subscribers should read Progress() after each one rather than count them.
+func (j *Job) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	j.mu.Lock()
+	j.subscribers[ch] = struct{}{}
+	j.mu.Unlock()
+
+	return ch, func() {
+		j.mu.Lock()
+		delete(j.subscribers, ch)
+		j.mu.Unlock()
+	}
+}
+
+// notifyLocked signals all subscribers without blocking. Callers must hold j.mu.
+func (j *Job) notifyLocked() {
+	for ch := range j.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default: // A signal is already pending
+		}
+	}
+}
+
+// finishedJobRetention is how long finished jobs stay listed and available
+// to late subscribers
+const finishedJobRetention = 24 * time.Hour
+
+// defaultJobConcurrency is how many jobs run at once unless configured otherwise
+const defaultJobConcurrency = 4
+
+// ErrJobNotFound is returned for operations on unknown jobs
+var ErrJobNotFound = errors.New("job not found")
+
+// JobFunc performs the work of a job, reporting progress through job.
+// It returns the URL of the page to show once the job succeeds.
+type JobFunc func(job *Job) (resultURL string, err error)
+
+// JobType describes a kind of job that can be queued by name with
+// parameters, so queued jobs survive a restart
+type JobType struct {
+	Run         JobFunc
+	MaxAttempts int           // Runs before the job fails; 0 means a single run
+	RetryDelay  time.Duration // Delay before the first retry, doubling after each
+	Concurrency int           // Jobs of this type running at once; 0 for no limit of its own
+	Resumable   bool          // Safe to run again from the start if a restart interrupted it
+}
+
+// JobManager queues background jobs, runs them within concurrency limits
+// and keeps track of them
+type JobManager struct {
+	mu          sync.RWMutex
+	jobs        map[string]*Job
+	queue       []*Job // Jobs waiting to run, oldest first
+	running     map[string]int
+	types       map[string]JobType
+	counter     uint64
+	concurrency int
+	statePath   string // File the queue is saved to; empty keeps it in memory
+
+	saveMu    sync.Mutex  // Held while writing the state file, so saves land in order
+	saveTimer *time.Timer // Pending save of the state file, if any
+}
+
+// NewJobManager creates a new job manager that keeps its jobs in memory
+func NewJobManager() *JobManager {
+	return &JobManager{
+		jobs:        make(map[string]*Job),
+		running:     make(map[string]int),
+		types:       make(map[string]JobType),
+		concurrency: defaultJobConcurrency,
+	}
+}
+
+// SetConcurrency sets how many jobs run at once. Zero or less means no limit.
+func (m *JobManager) SetConcurrency(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.concurrency = n
+	m.dispatchLocked()
+}
+
+// RegisterType makes a kind of job available to Enqueue and to queues
+// loaded from disk
+func (m *JobManager) RegisterType(kind string, jobType JobType) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.types[kind] = jobType
+}
+
+// Start queues fn as a one-off job. Unlike registered job types it can't be
+// resumed after a restart.
+func (m *JobManager) Start(kind, title string, fn JobFunc) *Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enqueueLocked(kind, title, nil, fn, 1)
+}
+
+// Enqueue queues a job of a registered type. params is saved as JSON with the
+// job and passed to it through Job.Params. If the same job is already queued
+// or running, that job is returned instead of queueing it twice.
+func (m *JobManager) Enqueue(kind, title string, params interface{}) (*Job, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters for %s job: %w", kind, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobType, ok := m.types[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown job type %q", kind)
+	}
+	for _, job := range m.jobs {
+		progress := job.Progress()
+		if progress.Kind == kind && !progress.Done() && bytes.Equal(progress.Params, data) {
+			return job, nil
+		}
+	}
+	return m.enqueueLocked(kind, title, data, jobType.Run, jobType.MaxAttempts), nil
+}
+
+// enqueueLocked adds a new job to the queue and starts it if a slot is free.
+// Callers must hold m.mu.
+func (m *JobManager) enqueueLocked(kind, title string, params json.RawMessage, fn JobFunc, maxAttempts int) *Job {
+	m.counter++
+	job := newJob(JobProgress{
+		ID:          fmt.Sprintf("job-%d", m.counter),
+		Kind:        kind,
+		Title:       title,
+		Status:      JobQueued,
+		Step:        "Waiting to start",
+		QueuedAt:    time.Now(),
+		MaxAttempts: max(maxAttempts, 1),
+		Params:      params,
+	}, fn)
+
+	m.pruneLocked()
+	m.jobs[job.progress.ID] = job
+	m.queue = append(m.queue, job)
+	m.dispatchLocked()
+	m.scheduleSaveLocked()
+	return job
+}
+
+// dispatch starts queued jobs that are ready to run
+func (m *JobManager) dispatch() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dispatchLocked()
+}
+
+// dispatchLocked starts queued jobs, oldest first, while the concurrency
+// limits allow. Callers must hold m.mu.
+func (m *JobManager) dispatchLocked() {
+	now := time.Now()
+	waiting := m.queue[:0]
+	for _, job := range m.queue {
+		if m.canStartLocked(job, now) {
+			m.startLocked(job)
+		} else {
+			waiting = append(waiting, job)
+		}
+	}
+	clear(m.queue[len(waiting):])
+	m.queue = waiting
+}
+
+// canStartLocked reports whether a queued job may start now. Callers must hold m.mu.
+func (m *JobManager) canStartLocked(job *Job, now time.Time) bool {
+	progress := job.Progress()
+	if now.Before(progress.RetryAt) {
+		return false
+	}
+
+	total := 0
+	for _, n := range m.running {
+		total += n
+	}
+	if m.concurrency > 0 && total >= m.concurrency {
+		return false
+	}
+	limit := m.types[progress.Kind].Concurrency
+	return limit <= 0 || m.running[progress.Kind] < limit
+}
+
+// startLocked runs a queued job in the background. Callers must hold m.mu.
+func (m *JobManager) startLocked(job *Job) {
+	kind := job.progress.Kind
+	m.running[kind]++
+	job.Update(func(p *JobProgress) {
+		p.Status = JobRunning
+		p.Step = "Starting"
+		p.Attempt++
+		p.StartedAt = time.Now()
+		p.RetryAt = time.Time{}
+		p.Error = ""
+	})
+	m.scheduleSaveLocked()
+
+	go func() {
+		resultURL, err := runJob(job, job.fn)
+		m.finish(job, resultURL, err)
+	}()
+}
+
+// finish records the outcome of a job's run, queueing a retry if it failed
+// and has attempts left
+func (m *JobManager) finish(job *Job, resultURL string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	progress := job.Progress()
+	m.running[progress.Kind]--
+
+	switch {
+	case err == nil:
+		job.Update(func(p *JobProgress) {
+			p.Status = JobSucceeded
+			p.Step = "Finished"
+			p.ResultURL = resultURL
+			p.FinishedAt = time.Now()
+		})
+		log.Printf("Job %s (%s) finished", progress.ID, progress.Title)
+	case job.ctx.Err() != nil:
+		m.cancelLocked(job)
+	case progress.Attempt < progress.MaxAttempts:
+		delay := m.types[progress.Kind].RetryDelay << (progress.Attempt - 1)
+		job.Logf("Attempt %d failed: %v", progress.Attempt, err)
+		job.Update(func(p *JobProgress) {
+			p.Status = JobQueued
+			p.Step = "Waiting to retry"
+			p.Error = err.Error()
+			p.RetryAt = time.Now().Add(delay)
+		})
+		m.queue = append(m.queue, job)
+		time.AfterFunc(delay, m.dispatch)
+	default:
+		job.Update(func(p *JobProgress) {
+			p.Status = JobFailed
+			p.Error = err.Error()
+			p.FinishedAt = time.Now()
+		})
+		log.Printf("Job %s (%s) failed: %v", progress.ID, progress.Title, err)
+	}
+
+	m.scheduleSaveLocked()
+	m.dispatchLocked()
+}
+
+// Cancel stops a job. Queued jobs are dropped straight away; running jobs
+// are asked to stop through their context and end once they notice.
+func (m *JobManager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	switch job.Progress().Status {
+	case JobQueued:
+		for i, queued := range m.queue {
+			if queued == job {
+				m.queue = append(m.queue[:i], m.queue[i+1:]...)
+				break
+			}
+		}
+		job.cancel()
+		m.cancelLocked(job)
+		m.scheduleSaveLocked()
+	case JobRunning:
+		job.cancel()
+		job.Logf("Cancellation requested")
+	default:
+		return fmt.Errorf("job %s has already finished", id)
+	}
+	return nil
+}
+
+// cancelLocked marks a job canceled. Callers must hold m.mu.
+func (m *JobManager) cancelLocked(job *Job) {
+	job.Update(func(p *JobProgress) {
+		p.Status = JobCanceled
+		p.Step = "Canceled"
+		p.RetryAt = time.Time{}
+		p.FinishedAt = time.Now()
+	})
+	log.Printf("Job %s (%s) canceled", job.progress.ID, job.progress.Title)
+}
+
+// pruneLocked forgets jobs that finished long ago. Callers must hold m.mu.
+func (m *JobManager) pruneLocked() {
+	cutoff := time.Now().Add(-finishedJobRetention)
+	for id, job := range m.jobs {
+		progress := job.Progress()
+		if progress.Done() && progress.FinishedAt.Before(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// runJob calls fn, turning a panic into a job failure so it can't take down the server
+func runJob(job *Job, fn JobFunc) (resultURL string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(job)
+}
+
+// Get returns the job with the given ID
+func (m *JobManager) Get(id string) (*Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// List returns a snapshot of every job, oldest first
+func (m *JobManager) List() []JobProgress {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]JobProgress, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		list = append(list, job.Progress())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return jobNumber(list[i].ID) < jobNumber(list[j].ID)
+	})
+	return list
+}
+
+// jobNumber returns the sequence number in a job ID
+func jobNumber(id string) uint64 {
+	n, _ := strconv.ParseUint(strings.TrimPrefix(id, "job-"), 10, 64)
+	return n
+}
+
+// defaultJobStateFile returns where the job queue is saved by default
+func defaultJobStateFile() string {
+	if configDir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(configDir, "shelf", "jobs.json")
+	}
+	return filepath.Join(os.TempDir(), "shelf-jobs.json")
+}
+
+// jobState is the JSON layout of the saved job queue
+type jobState struct {
+	Counter uint64        `json:"counter"`
+	Jobs    []JobProgress `json:"jobs"`
+}
+
+// Load restores jobs saved in path and saves every later change there.
+// Queued jobs are queued again, as are running jobs of resumable types;
+// other interrupted jobs are marked failed. Job types must be registered first.
+func (m *JobManager) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read job state: %w", err)
+	}
+	var state jobState
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("failed to parse job state: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.statePath = path
+	m.counter = max(m.counter, state.Counter)
+	for _, progress := range state.Jobs {
+		m.counter = max(m.counter, jobNumber(progress.ID))
+		jobType, registered := m.types[progress.Kind]
+		job := newJob(progress, jobType.Run)
+		m.jobs[progress.ID] = job
+		if progress.Done() {
+			continue
+		}
+
+		requeue := registered && (progress.Status == JobQueued || jobType.Resumable)
+		if !requeue {
+			job.Update(func(p *JobProgress) {
+				p.Status = JobFailed
+				p.Error = "interrupted by a restart"
+				p.FinishedAt = time.Now()
+			})
+			continue
+		}
+		if progress.Status == JobRunning {
+			job.Logf("Interrupted by a restart, running again")
+			job.Update(func(p *JobProgress) {
+				p.Status = JobQueued
+				p.Step = "Waiting to start"
+			})
+		}
+		m.queue = append(m.queue, job)
+		if wait := time.Until(progress.RetryAt); wait > 0 {
+			time.AfterFunc(wait, m.dispatch)
+		}
+	}
+	sort.Slice(m.queue, func(i, j int) bool {
+		return jobNumber(m.queue[i].progress.ID) < jobNumber(m.queue[j].progress.ID)
+	})
+
+	m.pruneLocked()
+	m.dispatchLocked()
+	m.scheduleSaveLocked()
+	return nil
+}
+
+// jobStateSaveDelay gathers the changes made in quick succession, such as a
+// job finishing and the next one starting, into one save of the job state
+const jobStateSaveDelay = time.Second
+
+// scheduleSaveLocked marks the job state as changed, saving it shortly if
+// there is a state file. Callers must hold m.mu.
+func (m *JobManager) scheduleSaveLocked() {
+	if m.statePath == "" || m.saveTimer != nil {
+		return
+	}
+	m.saveTimer = time.AfterFunc(jobStateSaveDelay, m.Flush)
+}
+
+// Flush writes every job to the state file now, if there is one, rather than
+// waiting for the scheduled save. The file is written outside m.mu, so jobs
+// aren't held up by the disk, and replaced atomically so a crash can't leave
+// it half written.
+func (m *JobManager) Flush() {
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	m.mu.Lock()
+	if m.saveTimer != nil {
+		m.saveTimer.Stop()
+		m.saveTimer = nil
+	}
+	path := m.statePath
+	state := jobState{Counter: m.counter}
+	for _, job := range m.jobs {
+		state.Jobs = append(state.Jobs, job.Progress())
+	}
+	m.mu.Unlock()
+	if path == "" {
+		return
+	}
+
+	sort.Slice(state.Jobs, func(i, j int) bool {
+		return jobNumber(state.Jobs[i].ID) < jobNumber(state.Jobs[j].ID)
+	})
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = writeFileAtomic(path, data, 0644)
+		}
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to save job state: %v", err)
+	}
+}
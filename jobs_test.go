@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForJob blocks until the job finishes and returns its final progress
+func waitForJob(t *testing.T, job *Job) JobProgress {
+	t.Helper()
+
+	updates, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		if progress := job.Progress(); progress.Done() {
+			return progress
+		}
+		select {
+		case <-updates:
+		case <-timeout:
+			t.Fatalf("Job %s did not finish", job.Progress().ID)
+		}
+	}
+}
+
+func TestJobManagerSuccess(t *testing.T) {
+	manager := NewJobManager()
+
+	job := manager.Start("test", "Test job", func(job *Job) (string, error) {
+		job.SetStep("Working")
+		job.SetItems(1, 2)
+		job.SetItems(2, 2)
+		return "/done", nil
+	})
+
+	progress := waitForJob(t, job)
+	if progress.Status != JobSucceeded {
+		t.Errorf("Status = %v, want %v", progress.Status, JobSucceeded)
+	}
+	if progress.ResultURL != "/done" {
+		t.Errorf("ResultURL = %q, want /done", progress.ResultURL)
+	}
+	if progress.ItemsDone != 2 || progress.Percent() != 100 {
+		t.Errorf("Items = %d, Percent() = %d, want 2 and 100", progress.ItemsDone, progress.Percent())
+	}
+	if progress.FinishedAt.IsZero() {
+		t.Error("FinishedAt not set")
+	}
+
+	got, ok := manager.Get(progress.ID)
+	if !ok || got != job {
+		t.Errorf("Get(%q) = %v, %v, want the started job", progress.ID, got, ok)
+	}
+	if _, ok := manager.Get("job-999"); ok {
+		t.Error("Get() found a job that was never started")
+	}
+}
+
+func TestJobManagerFailure(t *testing.T) {
+	manager := NewJobManager()
+
+	job := manager.Start("test", "Failing job", func(job *Job) (string, error) {
+		return "/ignored", errors.New("disk full")
+	})
+
+	progress := waitForJob(t, job)
+	if progress.Status != JobFailed || progress.Error != "disk full" {
+		t.Errorf("Status = %v, Error = %q, want failed with disk full", progress.Status, progress.Error)
+	}
+	if progress.ResultURL != "" {
+		t.Errorf("ResultURL = %q, want empty for failed job", progress.ResultURL)
+	}
+}
+
+func TestJobManagerRecoversPanic(t *testing.T) {
+	manager := NewJobManager()
+
+	job := manager.Start("test", "Panicking job", func(job *Job) (string, error) {
+		panic("boom")
+	})
+
+	progress := waitForJob(t, job)
+	if progress.Status != JobFailed {
+		t.Errorf("Status = %v, want %v", progress.Status, JobFailed)
+	}
+}
+
+func TestJobProgressPercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress JobProgress
+		want     int
+	}{
+		{"Bytes", JobProgress{BytesDone: 25, BytesTotal: 100, ItemsDone: 1, ItemsTotal: 2}, 25},
+		{"Items", JobProgress{ItemsDone: 1, ItemsTotal: 4}, 25},
+		{"Unknown", JobProgress{}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.progress.Percent(); got != tt.want {
+				t.Errorf("Percent() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// waitForStatus blocks until the job reaches status
+func waitForStatus(t *testing.T, job *Job, status JobStatus) JobProgress {
+	t.Helper()
+
+	updates, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		if progress := job.Progress(); progress.Status == status {
+			return progress
+		}
+		select {
+		case <-updates:
+		case <-timeout:
+			t.Fatalf("Job %s is %s, want %s", job.Progress().ID, job.Progress().Status, status)
+		}
+	}
+}
+
+func TestJobManagerConcurrencyLimit(t *testing.T) {
+	manager := NewJobManager()
+	manager.SetConcurrency(1)
+
+	release := make(chan struct{})
+	blocking := func(job *Job) (string, error) {
+		<-release
+		return "", nil
+	}
+	first := manager.Start("test", "First", blocking)
+	second := manager.Start("test", "Second", blocking)
+
+	waitForStatus(t, first, JobRunning)
+	if got := second.Progress().Status; got != JobQueued {
+		t.Errorf("Second job status = %v, want %v while the first runs", got, JobQueued)
+	}
+
+	release <- struct{}{}
+	waitForJob(t, first)
+	waitForStatus(t, second, JobRunning)
+	close(release)
+	waitForJob(t, second)
+}
+
+func TestJobManagerTypeConcurrency(t *testing.T) {
+	manager := NewJobManager()
+	release := make(chan struct{})
+	manager.RegisterType("slow", JobType{
+		Run: func(job *Job) (string, error) {
+			<-release
+			return "", nil
+		},
+		Concurrency: 1,
+	})
+
+	first, _ := manager.Enqueue("slow", "First", map[string]int{"n": 1})
+	second, _ := manager.Enqueue("slow", "Second", map[string]int{"n": 2})
+	other := manager.Start("test", "Other", func(job *Job) (string, error) { return "", nil })
+
+	// Other kinds aren't held up by the limit
+	waitForJob(t, other)
+	waitForStatus(t, first, JobRunning)
+	if got := second.Progress().Status; got != JobQueued {
+		t.Errorf("Second job status = %v, want %v", got, JobQueued)
+	}
+	close(release)
+	waitForJob(t, second)
+}
+
+func TestJobManagerRetries(t *testing.T) {
+	manager := NewJobManager()
+	var runs atomic.Int32
+	manager.RegisterType("flaky", JobType{
+		Run: func(job *Job) (string, error) {
+			if runs.Add(1) == 1 {
+				return "", errors.New("connection reset")
+			}
+			return "/done", nil
+		},
+		MaxAttempts: 3,
+		RetryDelay:  time.Millisecond,
+	})
+
+	job, err := manager.Enqueue("flaky", "Flaky job", nil)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	progress := waitForJob(t, job)
+	if progress.Status != JobSucceeded || progress.Attempt != 2 || progress.Error != "" {
+		t.Errorf("Job = %v after %d attempts (%q), want success on the second", progress.Status, progress.Attempt, progress.Error)
+	}
+	if len(progress.Log) == 0 || !strings.Contains(progress.Log[0], "Attempt 1 failed: connection reset") {
+		t.Errorf("Log = %q, want the failed attempt", progress.Log)
+	}
+
+	// Jobs fail once their attempts run out
+	manager.RegisterType("broken", JobType{
+		Run:         func(job *Job) (string, error) { return "", errors.New("still broken") },
+		MaxAttempts: 2,
+		RetryDelay:  time.Millisecond,
+	})
+	job, _ = manager.Enqueue("broken", "Broken job", nil)
+	progress = waitForJob(t, job)
+	if progress.Status != JobFailed || progress.Attempt != 2 || progress.Error != "still broken" {
+		t.Errorf("Job = %v after %d attempts (%q), want failure after 2", progress.Status, progress.Attempt, progress.Error)
+	}
+}
+
+func TestJobManagerCancel(t *testing.T) {
+	manager := NewJobManager()
+	manager.SetConcurrency(1)
+
+	running := manager.Start("test", "Running", func(job *Job) (string, error) {
+		<-job.Context().Done()
+		return "", job.Context().Err()
+	})
+	queued := manager.Start("test", "Queued", func(job *Job) (string, error) {
+		t.Error("Canceled job ran")
+		return "", nil
+	})
+	waitForStatus(t, running, JobRunning)
+
+	if err := manager.Cancel(queued.Progress().ID); err != nil {
+		t.Fatalf("Cancel() queued error = %v", err)
+	}
+	if got := queued.Progress().Status; got != JobCanceled {
+		t.Errorf("Queued job status = %v, want %v", got, JobCanceled)
+	}
+
+	if err := manager.Cancel(running.Progress().ID); err != nil {
+		t.Fatalf("Cancel() running error = %v", err)
+	}
+	if progress := waitForJob(t, running); progress.Status != JobCanceled {
+		t.Errorf("Running job status = %v, want %v", progress.Status, JobCanceled)
+	}
+
+	if err := manager.Cancel(running.Progress().ID); err == nil {
+		t.Error("Cancel() finished job expected error, got nil")
+	}
+	if err := manager.Cancel("job-999"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Cancel() unknown job error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestJobManagerEnqueue(t *testing.T) {
+	manager := NewJobManager()
+	manager.SetConcurrency(1)
+	release := make(chan struct{})
+	defer close(release)
+	manager.RegisterType("media", JobType{Run: func(job *Job) (string, error) {
+		<-release
+		return "", nil
+	}})
+
+	if _, err := manager.Enqueue("missing", "Unknown", nil); err == nil {
+		t.Error("Enqueue() unknown type expected error, got nil")
+	}
+
+	// The same job isn't queued twice
+	first, _ := manager.Enqueue("media", "Film", mediaJobParams{Path: "/media/Film"})
+	again, _ := manager.Enqueue("media", "Film", mediaJobParams{Path: "/media/Film"})
+	other, _ := manager.Enqueue("media", "Other", mediaJobParams{Path: "/media/Other"})
+	if again != first || other == first {
+		t.Error("Enqueue() should only reuse jobs with the same parameters")
+	}
+
+	var params mediaJobParams
+	if err := first.Params(&params); err != nil || params.Path != "/media/Film" {
+		t.Errorf("Params() = %+v, %v", params, err)
+	}
+	if list := manager.List(); len(list) != 2 || list[0].ID != first.Progress().ID {
+		t.Errorf("List() = %+v, want both jobs oldest first", list)
+	}
+}
+
+func TestJobManagerLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	now := time.Now()
+	state := jobState{Counter: 7, Jobs: []JobProgress{
+		{ID: "job-1", Kind: "resumable", Title: "Queued", Status: JobQueued, QueuedAt: now, MaxAttempts: 1, Params: json.RawMessage(`{"n":1}`)},
+		{ID: "job-2", Kind: "resumable", Title: "Interrupted", Status: JobRunning, QueuedAt: now, Attempt: 1, MaxAttempts: 2, Params: json.RawMessage(`{"n":2}`)},
+		{ID: "job-3", Kind: "once", Title: "Interrupted once", Status: JobRunning, QueuedAt: now, Attempt: 1, MaxAttempts: 1},
+		{ID: "job-4", Kind: "adhoc", Title: "Closure", Status: JobQueued, QueuedAt: now, MaxAttempts: 1},
+		{ID: "job-5", Kind: "resumable", Title: "Done", Status: JobSucceeded, QueuedAt: now, FinishedAt: now},
+		{ID: "job-6", Kind: "resumable", Title: "Old", Status: JobFailed, QueuedAt: now, FinishedAt: now.Add(-2 * finishedJobRetention)},
+	}}
+	data, _ := json.Marshal(state)
+	os.WriteFile(path, data, 0644)
+
+	manager := NewJobManager()
+	var ran sync.Map
+	manager.RegisterType("resumable", JobType{
+		Run: func(job *Job) (string, error) {
+			ran.Store(job.Progress().ID, true)
+			return "", nil
+		},
+		MaxAttempts: 2,
+		Resumable:   true,
+	})
+	manager.RegisterType("once", JobType{Run: func(job *Job) (string, error) {
+		t.Error("Interrupted job of a non-resumable type ran again")
+		return "", nil
+	}})
+	if err := manager.Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for _, id := range []string{"job-1", "job-2"} {
+		job, ok := manager.Get(id)
+		if !ok {
+			t.Fatalf("Get(%s) not found after Load()", id)
+		}
+		if progress := waitForJob(t, job); progress.Status != JobSucceeded {
+			t.Errorf("%s status = %v, want it run again", id, progress.Status)
+		}
+	}
+	job2, _ := manager.Get("job-2")
+	if progress := job2.Progress(); progress.Attempt != 2 || len(progress.Log) == 0 {
+		t.Errorf("Interrupted job attempt = %d, log = %q, want attempt 2 with a note", progress.Attempt, progress.Log)
+	}
+	for _, id := range []string{"job-3", "job-4"} {
+		job, _ := manager.Get(id)
+		if progress := job.Progress(); progress.Status != JobFailed || !strings.Contains(progress.Error, "restart") {
+			t.Errorf("%s = %v %q, want failed by the restart", id, progress.Status, progress.Error)
+		}
+	}
+	if _, ok := manager.Get("job-5"); !ok {
+		t.Error("Recently finished job was not restored")
+	}
+	if _, ok := manager.Get("job-6"); ok {
+		t.Error("Long finished job was restored")
+	}
+
+	// New jobs continue the numbering and are saved
+	job := manager.Start("test", "New", func(job *Job) (string, error) { return "", nil })
+	if id := job.Progress().ID; id != "job-8" {
+		t.Errorf("New job ID = %q, want job-8", id)
+	}
+	waitForJob(t, job)
+	manager.Flush()
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Job state not saved: %v", err)
+	}
+	var reloaded jobState
+	if err := json.Unmarshal(saved, &reloaded); err != nil {
+		t.Fatalf("Invalid job state: %v", err)
+	}
+	if reloaded.Counter != 8 || len(reloaded.Jobs) != 6 {
+		t.Errorf("Saved state has counter %d and %d jobs, want 8 and 6", reloaded.Counter, len(reloaded.Jobs))
+	}
+
+	// A corrupt state file is reported
+	os.WriteFile(path, []byte("{"), 0644)
+	if err := NewJobManager().Load(path); err == nil {
+		t.Error("Load() corrupt state expected error, got nil")
+	}
+}
+
+func TestJobManagerSavesInBackground(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	manager := NewJobManager()
+	if err := manager.Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	t.Cleanup(manager.Flush) // Stops the pending save before the directory goes
+
+	job := manager.Start("test", "Saved", func(job *Job) (string, error) { return "", nil })
+	waitForJob(t, job)
+
+	// Changes are saved shortly after, without a flush
+	deadline := time.Now().Add(5 * jobStateSaveDelay)
+	for {
+		var state jobState
+		if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &state) == nil &&
+			len(state.Jobs) == 1 && state.Jobs[0].Status == JobSucceeded {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Finished job was not saved")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// kodiTimeout bounds each JSON-RPC call so an unreachable box doesn't hang the page
+const kodiTimeout = 10 * time.Second
+
+// KodiInstance is a Kodi box that can play disks from the shared media
+type KodiInstance struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"` // JSON-RPC endpoint, e.g. http://livingroom:8080/jsonrpc
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// PathMapping replaces PLAY_URL_PREFIX for this box when set,
+	// e.g. {"from": "/srv/media", "to": "smb://nas/media"}
+	PathMapping *PathMapping `json:"path_mapping,omitempty"`
+}
+
+// kodiFile is the JSON layout of the Kodi instances file
+type kodiFile struct {
+	Instances []KodiInstance `json:"kodi"`
+}
+
+// LoadKodiInstances reads Kodi instances from a JSON file
+func LoadKodiInstances(path string) ([]KodiInstance, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Kodi file: %w", err)
+	}
+
+	var file kodiFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse Kodi file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, instance := range file.Instances {
+		if instance.Name == "" {
+			return nil, fmt.Errorf("Kodi file contains an instance without a name")
+		}
+		if seen[instance.Name] {
+			return nil, fmt.Errorf("Kodi file contains duplicate instance %q", instance.Name)
+		}
+		seen[instance.Name] = true
+
+		u, err := url.Parse(instance.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("Kodi instance %q has invalid URL %q", instance.Name, instance.URL)
+		}
+	}
+	return file.Instances, nil
+}
+
+// KodiClient calls a Kodi instance's JSON-RPC API
+type KodiClient struct {
+	instance   KodiInstance
+	httpClient *http.Client
+	requestID  atomic.Int64
+}
+
+// NewKodiClient creates a client for a Kodi instance
+func NewKodiClient(instance KodiInstance) *KodiClient {
+	return &KodiClient{
+		instance:   instance,
+		httpClient: &http.Client{Timeout: kodiTimeout},
+	}
+}
+
+// Name returns the instance's name
+func (c *KodiClient) Name() string {
+	return c.instance.Name
+}
+
+// kodiRequest is a JSON-RPC 2.0 request
+type kodiRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	ID      int64       `json:"id"`
+}
+
+// kodiResponse is a JSON-RPC 2.0 response
+type kodiResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// call invokes a JSON-RPC method and decodes its result into result, if not nil
+func (c *KodiClient) call(ctx context.Context, method string, params, result interface{}) error {
+	body, err := json.Marshal(kodiRequest{JSONRPC: "2.0", Method: method, Params: params, ID: c.requestID.Add(1)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.instance.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.instance.Username != "" {
+		req.SetBasicAuth(c.instance.Username, c.instance.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Kodi %q: %w", c.instance.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Kodi %q returned status %d", c.instance.Name, resp.StatusCode)
+	}
+
+	var response kodiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("invalid response from Kodi %q: %w", c.instance.Name, err)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("Kodi %q rejected %s: %s", c.instance.Name, method, response.Error.Message)
+	}
+	if result != nil {
+		return json.Unmarshal(response.Result, result)
+	}
+	return nil
+}
+
+// Ping checks that Kodi is running and answering JSON-RPC calls
+func (c *KodiClient) Ping(ctx context.Context) error {
+	var pong string
+	if err := c.call(ctx, "JSONRPC.Ping", nil, &pong); err != nil {
+		return err
+	}
+	if pong != "pong" {
+		return fmt.Errorf("unexpected ping reply from Kodi %q: %q", c.instance.Name, pong)
+	}
+	return nil
+}
+
+// Play starts playing a disk on Kodi. Disk paths are mapped with the
+// instance's path mapping, or prefixed with prefix when it has none.
+func (c *KodiClient) Play(ctx context.Context, d *Disk, prefix string) error {
+	mapping := PathMapping{To: prefix}
+	if c.instance.PathMapping != nil {
+		mapping = *c.instance.PathMapping
+	}
+	params := map[string]interface{}{
+		"item": kodiPlayItem(d, mapping),
+	}
+	return c.call(ctx, "Player.Open", params, nil)
+}
+
+// kodiPlayItem returns the item Player.Open is given to play a disk. Disc
+// backups are opened through their index files, and other disks as a folder
+// whose videos Kodi plays in turn.
+func kodiPlayItem(d *Disk, mapping PathMapping) map[string]string {
+	if d.FormatKind() == FormatFile {
+		return map[string]string{"directory": kodiPlayPath(d, mapping)}
+	}
+	return map[string]string{"file": kodiPlayPath(d, mapping)}
+}
+
+// kodiPlayPath returns the path Kodi opens to play a disk. Kodi plays disc
+// backups through their index files rather than the folder itself, and
+// other disks' folders end in a separator, as Kodi's folders do.
+func kodiPlayPath(d *Disk, mapping PathMapping) string {
+	path := mapping.Apply(d.Path)
+	separator := "/"
+	if mapping.Style == PathStyleWindows {
+		separator = `\`
+	}
+	path = strings.TrimSuffix(path, separator)
+
+	switch d.FormatKind() {
+	case FormatBluRay:
+		return path + separator + "BDMV" + separator + "index.bdmv"
+	case FormatDVD:
+		return path + separator + "VIDEO_TS" + separator + "VIDEO_TS.IFO"
+	default:
+		return path + separator
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubKodi is a stub Kodi JSON-RPC server that records the calls it receives
+type stubKodi struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []kodiRequest
+	params   []json.RawMessage
+}
+
+// newStubKodi starts a stub Kodi requiring the given basic auth credentials
+func newStubKodi(t *testing.T, username, password string) *stubKodi {
+	t.Helper()
+
+	stub := &stubKodi{}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != username || pass != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var request struct {
+			kodiRequest
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.JSONRPC != "2.0" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		stub.mu.Lock()
+		stub.requests = append(stub.requests, request.kodiRequest)
+		stub.params = append(stub.params, request.Params)
+		stub.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch request.Method {
+		case "JSONRPC.Ping":
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": "pong"})
+		case "Player.Open":
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": "OK"})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0", "id": request.ID,
+				"error": map[string]interface{}{"code": -32601, "message": "Method not found."},
+			})
+		}
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+// lastOpenedItem returns the item passed to the last Player.Open call
+func (s *stubKodi) lastOpenedItem(t *testing.T) map[string]string {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.requests) - 1; i >= 0; i-- {
+		if s.requests[i].Method == "Player.Open" {
+			var params struct {
+				Item map[string]string `json:"item"`
+			}
+			json.Unmarshal(s.params[i], &params)
+			return params.Item
+		}
+	}
+	t.Fatal("Player.Open was not called")
+	return nil
+}
+
+func TestKodiPlayPath(t *testing.T) {
+	smb := PathMapping{From: "/srv/media", To: "smb://nas/media"}
+	windows := PathMapping{From: "/srv/media", To: `\\nas\media`, Style: PathStyleWindows}
+
+	tests := []struct {
+		name    string
+		disk    Disk
+		mapping PathMapping
+		want    string
+	}{
+		{"Blu-ray", Disk{Format: "Blu-Ray", Path: "/srv/media/Film/Disk [Blu-Ray]"}, smb, "smb://nas/media/Film/Disk [Blu-Ray]/BDMV/index.bdmv"},
+		{"DVD", Disk{Format: "DVD", Path: "/srv/media/Film/Disk [DVD]"}, smb, "smb://nas/media/Film/Disk [DVD]/VIDEO_TS/VIDEO_TS.IFO"},
+		{"file", Disk{Format: "MKV", Path: "/srv/media/Film/Disk [MKV]"}, smb, "smb://nas/media/Film/Disk [MKV]/"},
+		{"prefix", Disk{Format: "DVD", Path: "/Film/Disk"}, PathMapping{To: "nfs://nas"}, "nfs://nas/Film/Disk/VIDEO_TS/VIDEO_TS.IFO"},
+		{"windows", Disk{Format: "Blu-Ray", Path: "/srv/media/Film/Disk"}, windows, `\\nas\media\Film\Disk\BDMV\index.bdmv`},
+		{"windows file", Disk{Format: "MP4", Path: "/srv/media/Film/Disk"}, windows, `\\nas\media\Film\Disk\`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kodiPlayPath(&tt.disk, tt.mapping); got != tt.want {
+				t.Errorf("kodiPlayPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKodiClient(t *testing.T) {
+	stub := newStubKodi(t, "kodi", "secret")
+	client := NewKodiClient(KodiInstance{
+		Name:        "Living room",
+		URL:         stub.URL + "/jsonrpc",
+		Username:    "kodi",
+		Password:    "secret",
+		PathMapping: &PathMapping{From: "/srv/media", To: "smb://nas/media"},
+	})
+	ctx := context.Background()
+
+	if err := client.Ping(ctx); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+
+	disk := Disk{Format: "Blu-Ray", Path: "/srv/media/Film/Disk [Blu-Ray]"}
+	if err := client.Play(ctx, &disk, "/ignored"); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if got, want := stub.lastOpenedItem(t), map[string]string{"file": "smb://nas/media/Film/Disk [Blu-Ray]/BDMV/index.bdmv"}; !maps.Equal(got, want) {
+		t.Errorf("Player.Open item = %v, want %v", got, want)
+	}
+
+	// Instances without a mapping use the play URL prefix
+	client = NewKodiClient(KodiInstance{Name: "Bedroom", URL: stub.URL, Username: "kodi", Password: "secret"})
+	if err := client.Play(ctx, &Disk{Format: "DVD", Path: "/Film/Disk"}, "smb://nas"); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if got, want := stub.lastOpenedItem(t), map[string]string{"file": "smb://nas/Film/Disk/VIDEO_TS/VIDEO_TS.IFO"}; !maps.Equal(got, want) {
+		t.Errorf("Player.Open item = %v, want %v", got, want)
+	}
+
+	// Other disks are opened as a folder, not as a file
+	if err := client.Play(ctx, &Disk{Format: "MKV", Path: "/Film/Disk [MKV]"}, "smb://nas"); err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if got, want := stub.lastOpenedItem(t), map[string]string{"directory": "smb://nas/Film/Disk [MKV]/"}; !maps.Equal(got, want) {
+		t.Errorf("Player.Open item = %v, want %v", got, want)
+	}
+
+	// JSON-RPC errors are reported
+	if err := client.call(ctx, "Player.Eject", nil, nil); err == nil || !strings.Contains(err.Error(), "Method not found") {
+		t.Errorf("call() unknown method error = %v, want Kodi's error", err)
+	}
+
+	// Wrong credentials
+	client = NewKodiClient(KodiInstance{Name: "Bedroom", URL: stub.URL, Username: "kodi", Password: "wrong"})
+	if err := client.Ping(ctx); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Ping() wrong password error = %v, want status 401", err)
+	}
+
+	// Unreachable
+	client = NewKodiClient(KodiInstance{Name: "Offline", URL: "http://127.0.0.1:1/jsonrpc"})
+	if err := client.Ping(ctx); err == nil {
+		t.Error("Ping() unreachable expected error, got nil")
+	}
+}
+
+func TestLoadKodiInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kodi.json")
+	os.WriteFile(path, []byte(`{"kodi": [
+		{"name": "Living room", "url": "http://livingroom:8080/jsonrpc", "username": "kodi", "password": "secret",
+		 "path_mapping": {"from": "/srv/media", "to": "smb://nas/media"}}
+	]}`), 0600)
+
+	instances, err := LoadKodiInstances(path)
+	if err != nil {
+		t.Fatalf("LoadKodiInstances() error = %v", err)
+	}
+	if len(instances) != 1 || instances[0].Name != "Living room" || instances[0].PathMapping == nil || instances[0].PathMapping.To != "smb://nas/media" {
+		t.Errorf("LoadKodiInstances() = %+v", instances)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid JSON", "{"},
+		{"missing name", `{"kodi": [{"url": "http://kodi:8080/jsonrpc"}]}`},
+		{"duplicate name", `{"kodi": [{"name": "a", "url": "http://a/jsonrpc"}, {"name": "a", "url": "http://b/jsonrpc"}]}`},
+		{"missing URL", `{"kodi": [{"name": "a"}]}`},
+		{"invalid scheme", `{"kodi": [{"name": "a", "url": "ftp://kodi/jsonrpc"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "kodi.json")
+			os.WriteFile(path, []byte(tt.content), 0600)
+			if _, err := LoadKodiInstances(path); err == nil {
+				t.Error("LoadKodiInstances() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestKodiHandlers(t *testing.T) {
+	tmpl, err := parseTemplates(templateFS(""))
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+	testDir := setupTestData(t)
+	mediaList, err := NewScanner(testDir).Scan()
+	if err != nil {
+		t.Fatalf("Failed to scan test data: %v", err)
+	}
+	app := NewApp(mediaList, tmpl, testDir, "")
+	app.SetPlayURLPrefix("smb://nas")
+
+	stub := newStubKodi(t, "", "")
+	app.SetKodiClients([]*KodiClient{
+		NewKodiClient(KodiInstance{Name: "Living room", URL: stub.URL}),
+		NewKodiClient(KodiInstance{Name: "Offline", URL: "http://127.0.0.1:1/jsonrpc"}),
+	})
+
+	// The detail page offers each instance
+	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025", nil)
+	w := httptest.NewRecorder()
+	app.DetailHandler(w, req)
+	if body := w.Body.String(); !strings.Contains(body, "Play on…") || !strings.Contains(body, `<option value="Living room">`) {
+		t.Error("Detail page does not offer the Kodi instances")
+	}
+
+	// Playing a disk opens it on the chosen instance
+	form := url.Values{"instance": {"Living room"}, "disk": {"0"}}
+	req = httptest.NewRequest(http.MethodPost, "/media/war-of-the-worlds-2025/kodi", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	app.KodiHandler(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Living room") {
+		t.Fatalf("KodiHandler() = %v %q, want success message", w.Code, w.Body.String())
+	}
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+	if got, want := stub.lastOpenedItem(t), kodiPlayItem(&media.Disks[0], PathMapping{To: "smb://nas"}); !maps.Equal(got, want) {
+		t.Errorf("Player.Open item = %v, want %v", got, want)
+	}
+
+	// Unknown instances are refused
+	form.Set("instance", "Kitchen")
+	req = httptest.NewRequest(http.MethodPost, "/media/war-of-the-worlds-2025/kodi", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	app.KodiHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("KodiHandler() unknown instance status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+
+	// The status check reports each instance
+	req = httptest.NewRequest(http.MethodGet, "/kodi/status", nil)
+	w = httptest.NewRecorder()
+	app.KodiStatusHandler(w, req)
+	var statuses []KodiStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("Invalid status JSON: %v", err)
+	}
+	if len(statuses) != 2 || !statuses[0].Online || statuses[1].Online || statuses[1].Error == "" {
+		t.Errorf("KodiStatusHandler() = %+v, want living room online and offline box offline", statuses)
+	}
+}
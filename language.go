@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// defaultFallbackLanguage fills in overviews TMDB hasn't translated
+	defaultFallbackLanguage = "en-US"
+
+	// metadataLanguageCookieName is the cookie holding the browser's chosen
+	// metadata language
+	metadataLanguageCookieName = "shelf_metadata_language"
+)
+
+// languageTagPattern matches the language tags TMDB accepts, e.g. "de" or "de-DE"
+var languageTagPattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+// regionPattern matches an ISO 3166-1 country code, e.g. "DE"
+var regionPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// languageNames names common languages in their own language, for the
+// language picker
+var languageNames = map[string]string{
+	"cs": "Čeština",
+	"da": "Dansk",
+	"de": "Deutsch",
+	"en": "English",
+	"es": "Español",
+	"fi": "Suomi",
+	"fr": "Français",
+	"it": "Italiano",
+	"ja": "日本語",
+	"ko": "한국어",
+	"nl": "Nederlands",
+	"no": "Norsk",
+	"pl": "Polski",
+	"pt": "Português",
+	"ru": "Русский",
+	"sv": "Svenska",
+	"tr": "Türkçe",
+	"zh": "中文",
+}
+
+// ParseLanguageList parses a comma-separated list of language tags such as
+// "de-DE,en-US", dropping duplicates
+func ParseLanguageList(text string) ([]string, error) {
+	var languages []string
+	for _, tag := range strings.Split(text, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if !languageTagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid language %q, expected a tag like de or de-DE", tag)
+		}
+		if !slices.Contains(languages, tag) {
+			languages = append(languages, tag)
+		}
+	}
+	return languages, nil
+}
+
+// ValidateRegion checks a region is an upper-case country code such as "DE"
+func ValidateRegion(region string) error {
+	if !regionPattern.MatchString(region) {
+		return fmt.Errorf("invalid region %q, expected a country code like DE", region)
+	}
+	return nil
+}
+
+// LanguageName returns a language tag's name for display, e.g. "Deutsch (AT)"
+// for "de-AT"
+func LanguageName(tag string) string {
+	code, region, _ := strings.Cut(tag, "-")
+	name, ok := languageNames[code]
+	if !ok {
+		return tag
+	}
+	if region != "" {
+		return name + " (" + region + ")"
+	}
+	return name
+}
+
+// ReleaseDatesResponse lists a movie's release dates in each country
+type ReleaseDatesResponse struct {
+	Results []struct {
+		Country      string `json:"iso_3166_1"`
+		ReleaseDates []struct {
+			ReleaseDate string `json:"release_date"`
+			Type        int    `json:"type"`
+		} `json:"release_dates"`
+	} `json:"results"`
+}
+
+// RegionalDate returns the date the movie opened in the region's cinemas, as
+// YYYY-MM-DD, falling back to its earliest release there of any kind
+func (r *ReleaseDatesResponse) RegionalDate(region string) string {
+	if r == nil || region == "" {
+		return ""
+	}
+
+	// TMDB release types: 1 premiere, 2 limited theatrical, 3 theatrical,
+	// 4 digital, 5 physical, 6 TV
+	var theatrical, earliest string
+	for _, country := range r.Results {
+		if country.Country != region {
+			continue
+		}
+		for _, release := range country.ReleaseDates {
+			if len(release.ReleaseDate) < 10 {
+				continue
+			}
+			date := release.ReleaseDate[:10]
+			if (release.Type == 2 || release.Type == 3) && (theatrical == "" || date < theatrical) {
+				theatrical = date
+			}
+			if earliest == "" || date < earliest {
+				earliest = date
+			}
+		}
+	}
+	if theatrical != "" {
+		return theatrical
+	}
+	return earliest
+}
+
+// Translation is a movie or TV show's title and overview in one language
+type Translation struct {
+	Language string `json:"iso_639_1"`
+	Country  string `json:"iso_3166_1"`
+	Data     struct {
+		Title    string `json:"title"` // Movies
+		Name     string `json:"name"`  // TV shows
+		Overview string `json:"overview"`
+	} `json:"data"`
+}
+
+// Tag returns the translation's language tag, e.g. "de-DE"
+func (t Translation) Tag() string {
+	if t.Country == "" {
+		return t.Language
+	}
+	return t.Language + "-" + t.Country
+}
+
+// Title returns the translated movie title or TV show name, which is empty
+// when it is the same as the original
+func (t Translation) Title() string {
+	if t.Data.Title != "" {
+		return t.Data.Title
+	}
+	return t.Data.Name
+}
+
+// TranslationsResponse represents the TMDB API response for a movie or TV
+// show's translations
+type TranslationsResponse struct {
+	ID           int           `json:"id"`
+	Translations []Translation `json:"translations"`
+}
+
+// FetchTranslations fetches a movie or TV show's titles and overviews in every
+// language TMDB has them in
+func (c *TMDBClient) FetchTranslations(ctx context.Context, tmdbID string, mediaType MediaType) ([]Translation, error) {
+	var kind string
+	switch mediaType {
+	case Film:
+		kind = "movie"
+	case TV:
+		kind = "tv"
+	default:
+		return nil, fmt.Errorf("unknown media type: %v", mediaType)
+	}
+
+	translationsURL := fmt.Sprintf("%s/%s/%s/translations?api_key=%s", c.apiBaseURL, kind, url.PathEscape(tmdbID), c.apiKey)
+
+	var translations TranslationsResponse
+	if err := c.getJSON(ctx, translationsURL, &translations); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s %s translations: %w", kind, tmdbID, err)
+	}
+
+	return translations.Translations, nil
+}
+
+// findTranslation returns the translation for a language tag, preferring an
+// exact match and otherwise taking any region's translation of the language
+func findTranslation(translations []Translation, tag string) *Translation {
+	code, _, _ := strings.Cut(tag, "-")
+	var match *Translation
+	for i := range translations {
+		if translations[i].Tag() == tag {
+			return &translations[i]
+		}
+		if match == nil && translations[i].Language == code {
+			match = &translations[i]
+		}
+	}
+	return match
+}
+
+// languageFileName returns the file holding a language variant of a metadata
+// file, e.g. "title.de-DE.txt" for "title.txt"
+func languageFileName(name, language string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + language + ext
+}
+
+// loadLanguageFile reads a language variant of a metadata file
+func (m *Media) loadLanguageFile(name, language string) string {
+	if !languageTagPattern.MatchString(language) {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(m.Path, languageFileName(name, language)))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// LoadTitleIn returns the TMDB title saved in the given language, if any
+func (m *Media) LoadTitleIn(language string) string {
+	return m.loadLanguageFile("title.txt", language)
+}
+
+// LoadDescriptionIn returns the TMDB description saved in the given language, if any
+func (m *Media) LoadDescriptionIn(language string) string {
+	return m.loadLanguageFile("description.txt", language)
+}
+
+// hasLanguageVariants reports whether a title or description has been saved
+// in every variant language
+func (c *TMDBClient) hasLanguageVariants(media *Media) bool {
+	for _, language := range c.variants {
+		if media.LoadTitleIn(language) == "" && media.LoadDescriptionIn(language) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// saveLanguageVariants saves the media item's title and description in each
+// variant language TMDB has a translation for, returning how many files
+// changed. Existing files are kept unless overwrite is set.
+func (c *TMDBClient) saveLanguageVariants(ctx context.Context, media *Media, overwrite bool) (int, error) {
+	if len(c.variants) == 0 || media.TMDBID == "" || (!overwrite && c.hasLanguageVariants(media)) {
+		return 0, nil
+	}
+
+	translations, err := c.FetchTranslations(ctx, media.TMDBID, media.Type)
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	for _, language := range c.variants {
+		translation := findTranslation(translations, language)
+		if translation == nil {
+			log.Printf("No %s translation on TMDB for %s", language, media.Title)
+			continue
+		}
+		for name, text := range map[string]string{"title.txt": translation.Title(), "description.txt": translation.Data.Overview} {
+			if text == "" {
+				continue
+			}
+			existing := media.loadLanguageFile(name, language)
+			if existing == text || (existing != "" && !overwrite) {
+				continue
+			}
+			path := filepath.Join(media.Path, languageFileName(name, language))
+			if err := writeFileAtomic(path, []byte(text), 0644); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+	return written, nil
+}
+
+// SetMetadataLanguages sets the language metadata is saved in and the further
+// languages viewers can choose to see titles and descriptions in
+func (app *App) SetMetadataLanguages(primary string, variants []string) {
+	app.metadataLanguage = primary
+	app.languageVariants = variants
+}
+
+// metadataLanguageOption is a language the library can be shown in
+type metadataLanguageOption struct {
+	Tag  string // Empty for the language metadata is saved in
+	Name string
+}
+
+// metadataLanguageOptions lists the languages viewers can choose between, or
+// nothing when there are no variants to choose
+func (app *App) metadataLanguageOptions() []metadataLanguageOption {
+	if len(app.languageVariants) == 0 {
+		return nil
+	}
+	primary := "Default"
+	if app.metadataLanguage != "" {
+		primary = LanguageName(app.metadataLanguage)
+	}
+	options := []metadataLanguageOption{{Tag: "", Name: primary}}
+	for _, tag := range app.languageVariants {
+		options = append(options, metadataLanguageOption{Tag: tag, Name: LanguageName(tag)})
+	}
+	return options
+}
+
+// metadataLanguageFor returns the variant language a request shows titles and
+// descriptions in: the "lang" query parameter, then the browser's choice, or
+// empty for the language metadata is saved in
+func (app *App) metadataLanguageFor(r *http.Request) string {
+	if r.URL.Query().Has("lang") {
+		if lang := r.URL.Query().Get("lang"); slices.Contains(app.languageVariants, lang) {
+			return lang
+		}
+		return ""
+	}
+	if cookie, err := r.Cookie(metadataLanguageCookieName); err == nil && slices.Contains(app.languageVariants, cookie.Value) {
+		return cookie.Value
+	}
+	return ""
+}
+
+// localizeMedia shows a copy of the media item's title in the given variant
+// language, unless its title was edited locally
+func localizeMedia(media Media, language string) Media {
+	if language == "" || media.LoadOverrides().Title != "" {
+		return media
+	}
+	if title := media.LoadTitleIn(language); title != "" {
+		media.LocalTitle = title
+	}
+	return media
+}
+
+// localizedDescription returns the media item's description in the given
+// variant language, unless it was edited locally or hasn't been translated
+func localizedDescription(media *Media, language string) string {
+	if language != "" && media.LoadOverrides().Description == "" {
+		if description := media.LoadDescriptionIn(language); description != "" {
+			return description
+		}
+	}
+	return media.LoadDescription()
+}
+
+// MetadataLanguageHandler remembers which language this browser shows titles
+// and descriptions in, then returns to the page the choice was made on
+func (app *App) MetadataLanguageHandler(w http.ResponseWriter, r *http.Request) {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	language := r.FormValue("lang")
+	cookie := &http.Cookie{
+		Name:     metadataLanguageCookieName,
+		Value:    language,
+		Path:     "/",
+		MaxAge:   pathProfileCookieMaxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if language == "" {
+		// Back to the language metadata is saved in
+		cookie.MaxAge = -1
+	} else if !slices.Contains(app.languageVariants, language) {
+		http.Error(w, "Unknown language", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, cookie)
+
+	http.Redirect(w, r, safeRedirectTarget(r.FormValue("next")), http.StatusSeeOther)
+}
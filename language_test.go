@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLanguageList(t *testing.T) {
+	languages, err := ParseLanguageList(" de-DE, fr ,,de-DE")
+	if err != nil {
+		t.Fatalf("ParseLanguageList() error = %v", err)
+	}
+	if strings.Join(languages, ",") != "de-DE,fr" {
+		t.Errorf("ParseLanguageList() = %v, want [de-DE fr]", languages)
+	}
+
+	for _, text := range []string{"german", "de_DE", "DE", "de-de"} {
+		if _, err := ParseLanguageList(text); err == nil {
+			t.Errorf("ParseLanguageList(%q) accepted an invalid tag", text)
+		}
+	}
+
+	if err := ValidateRegion("DE"); err != nil {
+		t.Errorf("ValidateRegion(DE) error = %v", err)
+	}
+	for _, region := range []string{"de", "DEU", ""} {
+		if err := ValidateRegion(region); err == nil {
+			t.Errorf("ValidateRegion(%q) accepted an invalid region", region)
+		}
+	}
+}
+
+func TestLanguageName(t *testing.T) {
+	tests := map[string]string{
+		"de":    "Deutsch",
+		"de-AT": "Deutsch (AT)",
+		"fr-FR": "Français (FR)",
+		"xx-YY": "xx-YY",
+	}
+	for tag, want := range tests {
+		if got := LanguageName(tag); got != want {
+			t.Errorf("LanguageName(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestFetchMetadataInLanguage(t *testing.T) {
+	client := newFakeTMDBClient(t, builtinTMDBFixtures(t))
+	ctx := context.Background()
+
+	client.SetLanguage("de-DE", defaultFallbackLanguage, "DE")
+	movie, err := client.FetchMovieMetadata(ctx, "603")
+	if err != nil {
+		t.Fatalf("FetchMovieMetadata() error = %v", err)
+	}
+	if movie.Title != "Matrix" || !strings.HasPrefix(movie.Overview, "Der Hacker Neo") {
+		t.Errorf("German movie = %q: %q", movie.Title, movie.Overview)
+	}
+	// The German cinema release, not the later DVD or the US premiere
+	if movie.ReleaseDate != "1999-06-17" {
+		t.Errorf("German release date = %q, want 1999-06-17", movie.ReleaseDate)
+	}
+
+	// The German overview is missing, so the English one fills in
+	tv, err := client.FetchTVMetadata(ctx, "1396")
+	if err != nil {
+		t.Fatalf("FetchTVMetadata() error = %v", err)
+	}
+	if tv.Name != "Breaking Bad" || !strings.HasPrefix(tv.Overview, "When Walter White") {
+		t.Errorf("German TV show = %q: %q, want the English overview", tv.Name, tv.Overview)
+	}
+
+	// Without a fallback the missing overview stays missing
+	client.SetLanguage("de-DE", "", "")
+	if tv, err := client.FetchTVMetadata(ctx, "1396"); err != nil || tv.Overview != "" {
+		t.Errorf("FetchTVMetadata() without fallback = %q, %v", tv.Overview, err)
+	}
+
+	// Without a region the movie keeps its original release date
+	client.SetLanguage("", "", "")
+	if movie, err := client.FetchMovieMetadata(ctx, "603"); err != nil || movie.ReleaseDate != "1999-03-31" {
+		t.Errorf("FetchMovieMetadata() without region = %q, %v", movie.ReleaseDate, err)
+	}
+}
+
+func TestFetchLogoInLanguage(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		language := r.URL.Query().Get("include_image_language")
+		requested = append(requested, language)
+		w.Header().Set("Content-Type", "application/json")
+		switch language {
+		case "fr,null":
+			w.Write([]byte(`{"id": 603, "logos": [{"file_path": "/fr.png", "iso_639_1": "fr"}]}`))
+		case "en,null":
+			w.Write([]byte(`{"id": 603, "logos": [{"file_path": "/en.png", "iso_639_1": "en"}]}`))
+		default:
+			w.Write([]byte(`{"id": 603, "logos": []}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewTMDBClient("")
+	client.SetBaseURLs(server.URL, server.URL)
+	media := &Media{Title: "The Matrix", Type: Film, TMDBID: "603"}
+
+	tests := []struct {
+		language  string
+		fallback  string
+		want      string
+		requested string
+	}{
+		{"fr-FR", defaultFallbackLanguage, "/fr.png", "fr,null"},
+		{"de-DE", defaultFallbackLanguage, "/en.png", "de,null; en,null"},
+		{"de-DE", "", "", "de,null"},
+		{"", "", "/en.png", "en,null"},
+		{"en-GB", defaultFallbackLanguage, "/en.png", "en,null"},
+	}
+	for _, tt := range tests {
+		requested = nil
+		client.SetLanguage(tt.language, tt.fallback, "")
+		logo, err := client.fetchLogo(context.Background(), media)
+		if err != nil || logo != tt.want {
+			t.Errorf("fetchLogo() in %q = %q, %v, want %q", tt.language, logo, err, tt.want)
+		}
+		if got := strings.Join(requested, "; "); got != tt.requested {
+			t.Errorf("fetchLogo() in %q requested %q, want %q", tt.language, got, tt.requested)
+		}
+	}
+}
+
+func TestSaveLanguageVariants(t *testing.T) {
+	client := newFakeTMDBClient(t, builtinTMDBFixtures(t))
+	client.SetLanguageVariants([]string{"de-DE", "fr-FR", "ja-JP"})
+	ctx := context.Background()
+
+	media := &Media{Title: "The Matrix", Type: Film, Path: t.TempDir(), TMDBID: "603"}
+	written, err := client.saveLanguageVariants(ctx, media, false)
+	if err != nil {
+		t.Fatalf("saveLanguageVariants() error = %v", err)
+	}
+	// German title and description, and the French description
+	if written != 3 {
+		t.Errorf("saveLanguageVariants() wrote %d files, want 3", written)
+	}
+	if title := media.LoadTitleIn("de-DE"); title != "Matrix" {
+		t.Errorf("German title = %q, want Matrix", title)
+	}
+	if description := media.LoadDescriptionIn("fr-FR"); !strings.HasPrefix(description, "Programmeur anonyme") {
+		t.Errorf("French description = %q", description)
+	}
+	if _, err := os.Stat(filepath.Join(media.Path, "title.fr-FR.txt")); !os.IsNotExist(err) {
+		t.Errorf("Saved an empty French title")
+	}
+
+	// Edited translations are kept unless overwriting
+	os.WriteFile(filepath.Join(media.Path, "title.de-DE.txt"), []byte("Die Matrix"), 0644)
+	if written, err := client.saveLanguageVariants(ctx, media, false); err != nil || written != 0 {
+		t.Errorf("saveLanguageVariants() again = %d, %v, want nothing written", written, err)
+	}
+	if written, err := client.saveLanguageVariants(ctx, media, true); err != nil || written != 1 {
+		t.Errorf("saveLanguageVariants() overwriting = %d, %v, want 1", written, err)
+	}
+	if title := media.LoadTitleIn("de-DE"); title != "Matrix" {
+		t.Errorf("German title after overwriting = %q, want Matrix", title)
+	}
+
+	if title := media.LoadTitleIn("../title"); title != "" {
+		t.Errorf("LoadTitleIn() read a file outside the variants: %q", title)
+	}
+}
+
+// newLanguageTestApp returns an app offering German titles, with War of the
+// Worlds translated
+func newLanguageTestApp(t *testing.T) *App {
+	t.Helper()
+
+	app, _, _ := setupAppWithMockTMDB(t, func(testDir string) {
+		filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+		os.WriteFile(filepath.Join(filmDir, "title.de-DE.txt"), []byte("Krieg der Welten"), 0644)
+		os.WriteFile(filepath.Join(filmDir, "description.txt"), []byte("Aliens invade."), 0644)
+		os.WriteFile(filepath.Join(filmDir, "description.de-DE.txt"), []byte("Außerirdische greifen an."), 0644)
+	})
+	app.SetMetadataLanguages("en-US", []string{"de-DE"})
+	return app
+}
+
+func TestMetadataLanguageFor(t *testing.T) {
+	app := newLanguageTestApp(t)
+	cookie := &http.Cookie{Name: metadataLanguageCookieName, Value: "de-DE"}
+
+	tests := []struct {
+		name   string
+		target string
+		cookie *http.Cookie
+		want   string
+	}{
+		{"default", "/", nil, ""},
+		{"query", "/?lang=de-DE", nil, "de-DE"},
+		{"cookie", "/", cookie, "de-DE"},
+		{"query overrides cookie", "/?lang=", cookie, ""},
+		{"unknown query", "/?lang=fr-FR", nil, ""},
+		{"unknown cookie", "/", &http.Cookie{Name: metadataLanguageCookieName, Value: "fr-FR"}, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.cookie != nil {
+			req.AddCookie(tt.cookie)
+		}
+		if got := app.metadataLanguageFor(req); got != tt.want {
+			t.Errorf("%s: metadataLanguageFor() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMetadataLanguageHandler(t *testing.T) {
+	app := newLanguageTestApp(t)
+
+	choose := func(language string) *httptest.ResponseRecorder {
+		form := url.Values{"lang": {language}, "next": {"/media/war-of-the-worlds-2025"}}
+		req := httptest.NewRequest(http.MethodPost, "/metadata-language", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		app.MetadataLanguageHandler(w, req)
+		return w
+	}
+
+	w := choose("de-DE")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/media/war-of-the-worlds-2025" {
+		t.Fatalf("MetadataLanguageHandler() = %v %q, want redirect back", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != metadataLanguageCookieName || cookies[0].Value != "de-DE" {
+		t.Fatalf("MetadataLanguageHandler() cookies = %+v, want language cookie", cookies)
+	}
+
+	// The library and detail pages show the German title and description
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	rec := httptest.NewRecorder()
+	app.IndexHandler(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, "Krieg der Welten") {
+		t.Errorf("Index page does not show the German title")
+	}
+	if !strings.Contains(body, `<option value="de-DE" selected>`) {
+		t.Errorf("Index page does not show the chosen language")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	app.DetailHandler(rec, req)
+	body = rec.Body.String()
+	if !strings.Contains(body, "Krieg der Welten") || !strings.Contains(body, "Außerirdische greifen an.") {
+		t.Errorf("Detail page does not show the German title and description")
+	}
+
+	// Asking for the default language in the URL overrides the cookie
+	req = httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025?lang=", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	app.DetailHandler(rec, req)
+	body = rec.Body.String()
+	if strings.Contains(body, "Krieg der Welten") || !strings.Contains(body, "Aliens invade.") {
+		t.Errorf("Detail page with ?lang= is not in the default language")
+	}
+
+	// Choosing the default clears the cookie
+	w = choose("")
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("Choosing default cookies = %+v, want cleared cookie", cookies)
+	}
+
+	if w := choose("fr-FR"); w.Code != http.StatusBadRequest {
+		t.Errorf("Unknown language status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
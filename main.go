@@ -1,13 +1,18 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
-	"html/template"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // printHelp prints the help message showing all configuration options
@@ -19,6 +24,14 @@ Usage:
   ./shelf -help     Show this help message
   ./shelf --help    Show this help message
   ./shelf -h        Show this help message
+  ./shelf hash-password
+                    Read a password from stdin and print its bcrypt hash for AUTH_USERS_FILE
+  ./shelf fake-tmdb [-addr :8091] [-fixtures dir]
+                    Serve canned TMDB responses for testing and demos, see TMDB_API_URL.
+                    The fixtures directory holds movie/{id}.json, tv/{id}.json, optional
+                    {movie,tv}/{id}/images.json, {movie,tv}/{id}/translations.json,
+                    collection/{id}.json and images/{file}; searches match the fixtures'
+                    titles. Default: built-in fixtures
 
 Configuration:
   The application is configured using environment variables:
@@ -41,16 +54,162 @@ Configuration:
       If not set, poster and metadata fetching will be disabled
       Get your API key at: https://www.themoviedb.org/settings/api
 
+  TMDB_API_URL
+      Base URL of the TMDB API (optional)
+      Set to a shelf fake-tmdb server to work offline; no TMDB_API_KEY is needed then
+      Example: http://localhost:8091/3
+      Default: https://api.themoviedb.org/3
+
+  TMDB_IMAGE_URL
+      Base URL of the TMDB image server, followed by a size and file name (optional)
+      Example: http://localhost:8091/t/p
+      Default: https://image.tmdb.org/t/p
+
+  TMDB_LANGUAGE
+      Language titles, overviews and genres are fetched in, as a TMDB language tag (optional)
+      Example: de-DE
+      Default: empty (TMDB's default, English)
+
+  TMDB_FALLBACK_LANGUAGE
+      Language overviews are fetched in when TMDB has no translation in TMDB_LANGUAGE (optional)
+      Default: en-US
+
+  TMDB_REGION
+      Country whose release dates are used for years, and searches prefer (optional)
+      Example: DE
+      Default: empty (TMDB's primary release date)
+
+  TMDB_LANGUAGE_VARIANTS
+      Comma-separated further languages to save titles and descriptions in (optional)
+      Each browser chooses which to show on the library page, and ?lang=en-US picks one
+      for a single page
+      Example: en-US,fr-FR
+      Default: empty (one language only)
+
+  TMDB_CACHE_DIR
+      Directory TMDB responses are cached in, so lookups aren't repeated (optional)
+      Clear it from the TMDB Matches page; Refresh Metadata always asks TMDB
+      Default: shelf/tmdb in the user cache directory
+
+  TMDB_CACHE_TTL
+      How long cached TMDB responses are used for, as a Go duration (optional)
+      Set to 0 to disable the cache
+      Default: 24h
+
+  TMDB_CACHE_MAX_SIZE
+      Largest size of the TMDB cache in megabytes; the oldest responses are removed first (optional)
+      Default: 100
+
   DEV_MODE
       Development mode - templates will be reloaded on every request (optional)
-      Set to "true" to enable
+      Set to "true" to enable; reloading requires TEMPLATE_DIR
       Default: false
 
+  TEMPLATE_DIR
+      Directory of templates overriding the built-in ones (optional)
+      Templates missing from the directory fall back to the built-in versions
+      Files in its static/ directory are served under /static/ alongside the
+      built-in ones, and can be linked from templates with {{asset "name"}}
+      Default: empty (use built-in templates)
+
   PLAY_URL_PREFIX
       URL prefix for VLC play commands (optional)
       Used to construct full paths for network shares or mount points
       Default: empty (assumes local paths)
 
+  PLAYERS_FILE
+      JSON file of player profiles offered on the detail page (optional)
+      Format: {"players": [{"name": "mpc-hc", "commands": {"bluray": "mpc-hc64.exe \"{path}\"",
+               "dvd": "mpc-hc64.exe \"{path}\"", "file": "mpc-hc64.exe \"{path}\""},
+               "path_mapping": {"from": "/srv/media", "to": "Z:/media"}}]}
+      Commands are keyed by disc format: "bluray", "dvd" or "file" (used for any other format)
+      {path} is replaced with the disk path and {name} with the disk name
+      Players without a path_mapping use PLAY_URL_PREFIX
+      Default: built-in VLC and MPV commands
+
+  PATH_PROFILES_FILE
+      JSON file of path mappings each browser can choose for its play commands (optional)
+      Format: {"path_profiles": [{"name": "Windows", "from": "/srv/media",
+               "to": "\\\\nas\\media", "style": "windows"}]}
+      "from" is replaced with "to"; "style" is "posix" or "windows" and sets the
+      separators and quoting; the choice is remembered in a cookie per browser
+      Default: everyone uses PLAY_URL_PREFIX
+
+  MPV_IPC
+      MPV JSON IPC endpoint for playing disks from the detail page (optional)
+      A socket path given to mpv --input-ipc-server, or tcp://host:port
+      Example: /tmp/mpv-socket
+      Default: empty (disabled)
+
+  MPV_PATH_PROFILE
+      Name of a PATH_PROFILES_FILE profile for the machine running MPV (optional)
+      Default: PLAY_URL_PREFIX
+
+  KODI_FILE
+      JSON file of Kodi instances disks can be played on (optional)
+      Format: {"kodi": [{"name": "Living room", "url": "http://livingroom:8080/jsonrpc",
+               "username": "kodi", "password": "secret",
+               "path_mapping": {"from": "/srv/media", "to": "smb://nas/media"}}]}
+      Instances without a path_mapping use PLAY_URL_PREFIX
+      Default: empty (disabled)
+
+  STREAM_SECRET
+      Key signing the stream URLs of file-based disks (MKV, MP4...) (optional)
+      Signed URLs let phones and remote players stream without signing in
+      Default: random, so stream URLs stop working when shelf restarts
+
+  STREAM_URL_LIFETIME
+      How long signed stream URLs work for, as a Go duration (optional)
+      Default: 6h
+
+  JOB_STATE_FILE
+      File the background job queue is saved to, so queued jobs survive a restart (optional)
+      TMDB matches waiting for review are saved beside it in matches.json
+      Default: shelf/jobs.json in the user config directory
+
+  JOB_CONCURRENCY
+      How many background jobs (scans, metadata downloads, imports...) run at once (optional)
+      Default: 4
+
+  METADATA_REFRESH_SCHEDULE
+      Cron schedule for re-fetching titles, overviews and genres from TMDB (optional)
+      Five fields: minute hour day-of-month month day-of-week, or @daily, @weekly...
+      Fields edited in the browser are never overwritten; see /jobs for what changed
+      Requires TMDB_API_KEY
+      Example: "0 4 * * 0" (Sundays at 04:00)
+      Default: empty (no scheduled refresh)
+
+  THUMBNAIL_CACHE_DIR
+      Directory for cached poster and artwork thumbnails (optional)
+      Default: shelf/thumbnails in the user cache directory
+
+  AUTH_USERS_FILE
+      JSON file of local user accounts (optional)
+      Format: {"users": [{"username": "sam", "password_hash": "<bcrypt hash>", "role": "admin"}]}
+      Create hashes with: ./shelf hash-password
+      Roles: "viewer" browses and copies play commands, "curator" can also set
+      TMDB IDs, posters and metadata and play on MPV and Kodi, "admin" can also
+      import media (default: viewer)
+      If neither this nor AUTH_TRUSTED_HEADER is set, authentication is disabled
+
+  AUTH_TRUSTED_HEADER
+      Header set by an SSO reverse proxy with the signed-in username (optional)
+      Example: X-Forwarded-User
+      Requires AUTH_TRUSTED_PROXIES
+
+  AUTH_TRUSTED_PROXIES
+      Comma-separated proxy IP addresses or CIDR ranges allowed to set AUTH_TRUSTED_HEADER
+      Example: 127.0.0.1,10.0.0.0/8
+
+  AUTH_DEFAULT_ROLE
+      Role of AUTH_TRUSTED_HEADER users who aren't in AUTH_USERS_FILE (optional)
+      Default: viewer
+
+  AUTH_ANONYMOUS_ACCESS
+      What visitors who aren't signed in can do (optional)
+      "none" sends them to the login page, "read-only" lets them browse the library
+      Default: none
+
 Examples:
   # Start with defaults
   ./shelf
@@ -61,14 +220,35 @@ Examples:
   # Start with TMDB metadata fetching enabled
   TMDB_API_KEY=your_api_key_here ./shelf
 
-  # Start in development mode
-  DEV_MODE=true ./shelf
+  # Start against a fake TMDB server with its built-in fixtures
+  ./shelf fake-tmdb &
+  TMDB_API_URL=http://localhost:8091/3 TMDB_IMAGE_URL=http://localhost:8091/t/p ./shelf
+
+  # Start in development mode, reloading templates from the source tree
+  DEV_MODE=true TEMPLATE_DIR=templates ./shelf
+
+  # Start with local accounts and a read-only view for visitors
+  AUTH_USERS_FILE=/etc/shelf/users.json AUTH_ANONYMOUS_ACCESS=read-only ./shelf
 
   # Start with network path prefix for VLC play commands
   PLAY_URL_PREFIX=/mnt/media ./shelf
 `)
 }
 
+// runHashPassword reads a password from in and writes its bcrypt hash to out
+func runHashPassword(in io.Reader, out io.Writer) error {
+	password, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	hash, err := HashPassword(strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, hash)
+	return err
+}
+
 // shouldShowHelp checks if help flag is present in command-line arguments
 func shouldShowHelp(args []string) bool {
 	for _, arg := range args {
@@ -86,6 +266,22 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Generate a password hash for the users file
+	if len(os.Args) > 1 && os.Args[1] == "hash-password" {
+		if err := runHashPassword(os.Stdin, os.Stdout); err != nil {
+			log.Fatalf("Failed to hash password: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	// Stand in for TMDB offline
+	if len(os.Args) > 1 && os.Args[1] == "fake-tmdb" {
+		if err := runFakeTMDB(os.Args[2:]); err != nil {
+			log.Fatalf("Fake TMDB server failed: %v", err)
+		}
+		os.Exit(0)
+	}
+
 	// Read configuration from environment variables
 	mediaDir := os.Getenv("MEDIA_DIR")
 	if mediaDir == "" {
@@ -103,13 +299,46 @@ func main() {
 	}
 
 	tmdbAPIKey := os.Getenv("TMDB_API_KEY")
-	if tmdbAPIKey == "" {
+	tmdbAPIURL := os.Getenv("TMDB_API_URL")
+	tmdbImageURL := os.Getenv("TMDB_IMAGE_URL")
+	if tmdbAPIKey == "" && tmdbAPIURL == "" {
 		log.Println("Warning: TMDB_API_KEY not set, poster fetching will be disabled")
 	}
 
+	// Metadata language, its fallback and the region release dates come from
+	var metadataLanguage string
+	if languages, err := ParseLanguageList(os.Getenv("TMDB_LANGUAGE")); err != nil || len(languages) > 1 {
+		log.Fatalf("Invalid TMDB_LANGUAGE %q, expected one language tag like de-DE", os.Getenv("TMDB_LANGUAGE"))
+	} else if len(languages) == 1 {
+		metadataLanguage = languages[0]
+	}
+	fallbackLanguage := defaultFallbackLanguage
+	if value := os.Getenv("TMDB_FALLBACK_LANGUAGE"); value != "" {
+		if languages, err := ParseLanguageList(value); err != nil || len(languages) != 1 {
+			log.Fatalf("Invalid TMDB_FALLBACK_LANGUAGE %q, expected one language tag like en-US", value)
+		}
+		fallbackLanguage = value
+	}
+	region := os.Getenv("TMDB_REGION")
+	if region != "" {
+		if err := ValidateRegion(region); err != nil {
+			log.Fatalf("Invalid TMDB_REGION: %v", err)
+		}
+	}
+	languageVariants, err := ParseLanguageList(os.Getenv("TMDB_LANGUAGE_VARIANTS"))
+	if err != nil {
+		log.Fatalf("Invalid TMDB_LANGUAGE_VARIANTS: %v", err)
+	}
+	languageVariants = slices.DeleteFunc(languageVariants, func(tag string) bool { return tag == metadataLanguage })
+
+	templateDir := os.Getenv("TEMPLATE_DIR")
+
 	devMode := os.Getenv("DEV_MODE") == "true"
 	if devMode {
 		log.Println("Development mode enabled - templates will be reloaded on every request")
+		if templateDir == "" {
+			log.Println("Warning: TEMPLATE_DIR not set, embedded templates cannot be hot-reloaded")
+		}
 	}
 
 	playURLPrefix := os.Getenv("PLAY_URL_PREFIX")
@@ -117,6 +346,78 @@ func main() {
 		playURLPrefix = "" // Empty by default, assumes local paths
 	}
 
+	players := DefaultPlayerProfiles()
+	if playersFile := os.Getenv("PLAYERS_FILE"); playersFile != "" {
+		loaded, err := LoadPlayerProfiles(playersFile)
+		if err != nil {
+			log.Fatalf("Invalid player profiles: %v", err)
+		}
+		players = loaded
+	}
+
+	var pathProfiles []PathProfile
+	if pathProfilesFile := os.Getenv("PATH_PROFILES_FILE"); pathProfilesFile != "" {
+		loaded, err := LoadPathProfiles(pathProfilesFile)
+		if err != nil {
+			log.Fatalf("Invalid path profiles: %v", err)
+		}
+		pathProfiles = loaded
+	}
+
+	var mpvClient *MPVClient
+	mpvPathMapping := PathMapping{To: playURLPrefix}
+	if mpvIPC := os.Getenv("MPV_IPC"); mpvIPC != "" {
+		client, err := NewMPVClient(mpvIPC)
+		if err != nil {
+			log.Fatalf("Invalid MPV_IPC: %v", err)
+		}
+		mpvClient = client
+		if name := os.Getenv("MPV_PATH_PROFILE"); name != "" {
+			profile := FindPathProfile(pathProfiles, name)
+			if profile == nil {
+				log.Fatalf("MPV_PATH_PROFILE %q not found in PATH_PROFILES_FILE", name)
+			}
+			mpvPathMapping = profile.PathMapping
+		}
+	}
+
+	var kodiClients []*KodiClient
+	if kodiFile := os.Getenv("KODI_FILE"); kodiFile != "" {
+		instances, err := LoadKodiInstances(kodiFile)
+		if err != nil {
+			log.Fatalf("Invalid Kodi instances: %v", err)
+		}
+		for _, instance := range instances {
+			kodiClients = append(kodiClients, NewKodiClient(instance))
+		}
+	}
+
+	streamLifetime := defaultStreamURLLifetime
+	if value := os.Getenv("STREAM_URL_LIFETIME"); value != "" {
+		lifetime, err := time.ParseDuration(value)
+		if err != nil || lifetime <= 0 {
+			log.Fatalf("Invalid STREAM_URL_LIFETIME %q", value)
+		}
+		streamLifetime = lifetime
+	}
+	streams, err := NewStreamSigner([]byte(os.Getenv("STREAM_SECRET")), streamLifetime)
+	if err != nil {
+		log.Fatalf("Failed to set up streaming: %v", err)
+	}
+
+	thumbnailCacheDir := os.Getenv("THUMBNAIL_CACHE_DIR")
+	if thumbnailCacheDir == "" {
+		thumbnailCacheDir = defaultThumbnailCacheDir()
+	}
+
+	auth, err := authenticatorFromEnv(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid authentication configuration: %v", err)
+	}
+	if auth == nil {
+		log.Println("Warning: AUTH_USERS_FILE and AUTH_TRUSTED_HEADER not set, anyone who can reach the server can change the library")
+	}
+
 	// Validate media directory exists
 	info, err := os.Stat(mediaDir)
 	if err != nil {
@@ -129,17 +430,53 @@ func main() {
 		log.Fatalf("Media path is not a directory: %s", mediaDir)
 	}
 
-	// Create scanner with optional TMDB client
-	var scanner *Scanner
+	// Create TMDB client if configured
 	var tmdbClient *TMDBClient
-	if tmdbAPIKey != "" {
-		log.Println("TMDB API key configured, poster fetching enabled")
+	if tmdbAPIKey != "" || tmdbAPIURL != "" {
+		log.Println("TMDB configured, poster fetching enabled")
 		tmdbClient = NewTMDBClient(tmdbAPIKey)
-		scanner = NewScannerWithTMDB(mediaDir, tmdbClient)
-	} else {
-		scanner = NewScanner(mediaDir)
+		tmdbClient.SetBaseURLs(tmdbAPIURL, tmdbImageURL)
+		if tmdbAPIURL != "" {
+			log.Printf("Using TMDB API at %s", tmdbAPIURL)
+		}
+		tmdbClient.SetLanguage(metadataLanguage, fallbackLanguage, region)
+		tmdbClient.SetLanguageVariants(languageVariants)
+
+		// Cache responses unless TMDB_CACHE_TTL is 0
+		cacheTTL := defaultTMDBCacheTTL
+		if value := os.Getenv("TMDB_CACHE_TTL"); value != "" {
+			cacheTTL, err = time.ParseDuration(value)
+			if err != nil || cacheTTL < 0 {
+				log.Fatalf("Invalid TMDB_CACHE_TTL %q", value)
+			}
+		}
+		cacheMaxSize := int64(defaultTMDBCacheMaxSize)
+		if value := os.Getenv("TMDB_CACHE_MAX_SIZE"); value != "" {
+			megabytes, err := strconv.Atoi(value)
+			if err != nil || megabytes < 1 {
+				log.Fatalf("Invalid TMDB_CACHE_MAX_SIZE %q", value)
+			}
+			cacheMaxSize = int64(megabytes) << 20
+		}
+		cacheDir := os.Getenv("TMDB_CACHE_DIR")
+		if cacheDir == "" {
+			cacheDir = defaultTMDBCacheDir()
+		}
+		if cacheTTL > 0 {
+			cache, err := NewTMDBCache(cacheDir, cacheTTL, cacheMaxSize)
+			if err != nil {
+				log.Printf("Warning: TMDB responses won't be cached: %v", err)
+			} else {
+				tmdbClient.SetCache(cache)
+			}
+		}
 	}
 
+	// Sizes of new disks and missing metadata are filled in by background jobs
+	// once the server is up
+	scanner := NewScanner(mediaDir)
+	scanner.SetDeferSizes(true)
+
 	// Scan media directory
 	log.Printf("Scanning media directory: %s", mediaDir)
 	mediaList, err := scanner.Scan()
@@ -148,21 +485,17 @@ func main() {
 	}
 	log.Printf("Found %d media items", len(mediaList))
 
-	// Load templates
-	tmpl, err := template.ParseFiles(
-		"templates/index.html",
-		"templates/detail.html",
-		"templates/search.html",
-		"templates/confirm.html",
-		"templates/import_list.html",
-		"templates/import_step1.html",
-		"templates/import_step2.html",
-		"templates/import_step3.html",
-		"templates/import_step4.html",
-		"templates/import_step5.html",
-		"templates/import_confirm.html",
-		"templates/import_success.html",
-	)
+	// Serve static files from TEMPLATE_DIR's static directory too
+	if templateDir != "" {
+		assets, err := NewStaticAssets(staticFS(templateDir))
+		if err != nil {
+			log.Fatalf("Failed to load static files: %v", err)
+		}
+		staticAssets = assets
+	}
+
+	// Load templates, preferring any found in TEMPLATE_DIR
+	tmpl, err := parseTemplates(templateFS(templateDir))
 	if err != nil {
 		log.Fatalf("Failed to load templates: %v", err)
 	}
@@ -170,54 +503,74 @@ func main() {
 	// Create app
 	app := NewApp(mediaList, tmpl, mediaDir, importDir)
 	app.SetDevMode(devMode)
+	app.SetTemplateDir(templateDir)
+	if auth != nil {
+		app.SetAuthenticator(auth)
+	}
 	app.SetPlayURLPrefix(playURLPrefix)
+	app.SetPlayerProfiles(players)
+	app.SetPathProfiles(pathProfiles)
+	app.SetMetadataLanguages(metadataLanguage, languageVariants)
+	app.SetKodiClients(kodiClients)
+	app.SetStreamSigner(streams)
+	if mpvClient != nil {
+		log.Printf("Sending disks to MPV at %s", os.Getenv("MPV_IPC"))
+		app.SetMPVClient(mpvClient, mpvPathMapping)
+	}
+
+	// Set thumbnail cache, serving full-size artwork if it can't be created
+	thumbnails, err := NewThumbnailCache(thumbnailCacheDir)
+	if err != nil {
+		log.Printf("Warning: Thumbnail cache disabled: %v", err)
+	} else {
+		app.SetThumbnailCache(thumbnails)
+	}
 
 	// Set TMDB client if available
 	if tmdbClient != nil {
 		app.SetTMDBClient(tmdbClient)
 	}
 
-	// Setup HTTP routes
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", app.IndexHandler)
-	mux.HandleFunc("/posters/", app.PosterHandler)
-
-	// Import routes
-	mux.HandleFunc("/import", app.ImportListHandler)
-	mux.HandleFunc("/import/start", app.ImportStartHandler)
-	mux.HandleFunc("/import/step1", app.ImportStep1Handler)
-	mux.HandleFunc("/import/step2", app.ImportStep2Handler)
-	mux.HandleFunc("/import/step2/confirm", app.ImportStep2ConfirmHandler)
-	mux.HandleFunc("/import/step3", app.ImportStep3Handler)
-	mux.HandleFunc("/import/step4", app.ImportStep4Handler)
-	mux.HandleFunc("/import/step5", app.ImportStep5Handler)
-	mux.HandleFunc("/import/confirm", app.ImportConfirmHandler)
-	mux.HandleFunc("/import/execute", app.ImportExecuteHandler)
-	mux.HandleFunc("/import/success", app.ImportSuccessHandler)
-
-	// TMDB routes (must come before the general /media/ route)
-	mux.HandleFunc("/media/", func(w http.ResponseWriter, r *http.Request) {
-		path := r.URL.Path
-		// Route to specific handlers based on path suffix
-		if strings.HasSuffix(path, "/search-tmdb") {
-			app.SearchTMDBHandler(w, r)
-		} else if strings.HasSuffix(path, "/confirm-tmdb") {
-			app.ConfirmTMDBHandler(w, r)
-		} else if strings.HasSuffix(path, "/set-tmdb") {
-			app.SaveTMDBHandler(w, r)
+	// Restore the job queue, then queue jobs for whatever the scan left out
+	jobStateFile := os.Getenv("JOB_STATE_FILE")
+	if jobStateFile == "" {
+		jobStateFile = defaultJobStateFile()
+	}
+	if value := os.Getenv("JOB_CONCURRENCY"); value != "" {
+		concurrency, err := strconv.Atoi(value)
+		if err != nil || concurrency < 1 {
+			log.Fatalf("Invalid JOB_CONCURRENCY %q", value)
+		}
+		app.jobs.SetConcurrency(concurrency)
+	}
+	if err := app.matches.Load(filepath.Join(filepath.Dir(jobStateFile), matchQueueFileName)); err != nil {
+		log.Printf("Warning: TMDB match reviews won't survive a restart: %v", err)
+	}
+	if err := app.jobs.Load(jobStateFile); err != nil {
+		log.Printf("Warning: Job queue won't survive a restart: %v", err)
+	}
+	app.queueFollowUpJobs(scanner.Unsized())
+
+	if spec := os.Getenv("METADATA_REFRESH_SCHEDULE"); spec != "" {
+		schedule, err := ParseCronSchedule(spec)
+		if err != nil {
+			log.Fatalf("Invalid METADATA_REFRESH_SCHEDULE: %v", err)
+		}
+		if tmdbClient == nil {
+			log.Println("Warning: METADATA_REFRESH_SCHEDULE needs TMDB_API_KEY, scheduled refresh disabled")
 		} else {
-			// Default to detail handler
-			app.DetailHandler(w, r)
+			log.Printf("Metadata refresh scheduled for %q, next at %s", schedule, schedule.Next(time.Now()).Format(time.RFC1123))
+			app.StartMetadataRefresh(context.Background(), schedule)
 		}
-	})
+	}
 
-	// Serve static files (CSS, etc.)
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	// Setup HTTP routes
+	handler := app.routes()
 
 	// Start server
 	addr := fmt.Sprintf(":%s", port)
 	log.Printf("Starting server on http://localhost%s", addr)
-	if err := http.ListenAndServe(addr, mux); err != nil {
+	if err := http.ListenAndServe(addr, handler); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
@@ -32,6 +32,30 @@ func TestPrintHelp(t *testing.T) {
 		{"DEV_MODE description", "Development mode"},
 		{"PLAY_URL_PREFIX env var", "PLAY_URL_PREFIX"},
 		{"PLAY_URL_PREFIX description", "URL prefix for VLC play commands"},
+		{"AUTH_USERS_FILE env var", "AUTH_USERS_FILE"},
+		{"AUTH_TRUSTED_HEADER env var", "AUTH_TRUSTED_HEADER"},
+		{"AUTH_ANONYMOUS_ACCESS env var", "AUTH_ANONYMOUS_ACCESS"},
+		{"hash-password command", "hash-password"},
+		{"AUTH_DEFAULT_ROLE env var", "AUTH_DEFAULT_ROLE"},
+		{"PLAYERS_FILE env var", "PLAYERS_FILE"},
+		{"PATH_PROFILES_FILE env var", "PATH_PROFILES_FILE"},
+		{"MPV_IPC env var", "MPV_IPC"},
+		{"KODI_FILE env var", "KODI_FILE"},
+		{"STREAM_SECRET env var", "STREAM_SECRET"},
+		{"STREAM_URL_LIFETIME env var", "STREAM_URL_LIFETIME"},
+		{"JOB_STATE_FILE env var", "JOB_STATE_FILE"},
+		{"JOB_CONCURRENCY env var", "JOB_CONCURRENCY"},
+		{"METADATA_REFRESH_SCHEDULE env var", "METADATA_REFRESH_SCHEDULE"},
+		{"TMDB_API_URL env var", "TMDB_API_URL"},
+		{"TMDB_IMAGE_URL env var", "TMDB_IMAGE_URL"},
+		{"fake-tmdb command", "fake-tmdb"},
+		{"TMDB_LANGUAGE env var", "TMDB_LANGUAGE"},
+		{"TMDB_FALLBACK_LANGUAGE env var", "TMDB_FALLBACK_LANGUAGE"},
+		{"TMDB_REGION env var", "TMDB_REGION"},
+		{"TMDB_LANGUAGE_VARIANTS env var", "TMDB_LANGUAGE_VARIANTS"},
+		{"TMDB_CACHE_DIR env var", "TMDB_CACHE_DIR"},
+		{"TMDB_CACHE_TTL env var", "TMDB_CACHE_TTL"},
+		{"TMDB_CACHE_MAX_SIZE env var", "TMDB_CACHE_MAX_SIZE"},
 	}
 
 	for _, tt := range tests {
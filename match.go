@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// defaultMatchThreshold is the score a candidate needs to be accepted
+	// without review
+	defaultMatchThreshold = 0.85
+
+	// matchMargin is how far the best candidate must lead the next one to be
+	// accepted, so remakes and same-named shows always go to review
+	matchMargin = 0.1
+
+	// maxMatchCandidates is how many candidates are kept for review
+	maxMatchCandidates = 5
+
+	// matchQueueFileName is the review queue file, kept beside the job queue
+	matchQueueFileName = "matches.json"
+)
+
+// MatchCandidate is a TMDB search result scored against a media item
+type MatchCandidate struct {
+	TMDBID     int     `json:"tmdb_id"`
+	Title      string  `json:"title"`
+	Date       string  `json:"date,omitempty"`
+	Overview   string  `json:"overview,omitempty"`
+	PosterPath string  `json:"poster_path,omitempty"`
+	Popularity float64 `json:"popularity"`
+	Score      float64 `json:"score"`
+}
+
+// Year returns the year of the candidate's release or first air date, or 0
+func (c MatchCandidate) Year() int {
+	if len(c.Date) < 4 {
+		return 0
+	}
+	year, _ := strconv.Atoi(c.Date[:4])
+	return year
+}
+
+// Percent returns the score as a whole percentage for display
+func (c MatchCandidate) Percent() int {
+	return int(c.Score*100 + 0.5)
+}
+
+// normalizeTitle reduces a title to lower-case words so punctuation, "&" and
+// a leading "The" don't count against a match
+func normalizeTitle(title string) string {
+	title = strings.ToLower(strings.ReplaceAll(title, "&", " and "))
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 1 && words[0] == "the" {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// titleSimilarity scores two titles from 0 (nothing alike) to 1 (the same
+// once normalized) by edit distance
+func titleSimilarity(a, b string) float64 {
+	ra, rb := []rune(normalizeTitle(a)), []rune(normalizeTitle(b))
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein returns the number of single-rune edits turning a into b
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// scoreCandidates scores candidates against a title and year (0 if unknown)
+// and sorts them best first. Title similarity counts most, then the year,
+// then popularity relative to the most popular candidate.
+func scoreCandidates(title string, year int, candidates []MatchCandidate) {
+	maxPopularity := 0.0
+	for _, c := range candidates {
+		maxPopularity = max(maxPopularity, c.Popularity)
+	}
+
+	for i := range candidates {
+		c := &candidates[i]
+		similarity := titleSimilarity(title, c.Title)
+		popularity := 0.0
+		if maxPopularity > 0 {
+			popularity = c.Popularity / maxPopularity
+		}
+
+		if year == 0 {
+			c.Score = 0.85*similarity + 0.15*popularity
+			continue
+		}
+
+		// Release dates differ between countries, so a year out still counts a little
+		yearScore := 0.0
+		switch diff := c.Year() - year; {
+		case c.Year() == 0:
+			yearScore = 0.3
+		case diff == 0:
+			yearScore = 1
+		case diff == 1 || diff == -1:
+			yearScore = 0.6
+		}
+		c.Score = 0.6*similarity + 0.3*yearScore + 0.1*popularity
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+}
+
+// chooseMatch returns the candidate to accept without review, or the reason
+// the candidates need reviewing. Candidates must be scored and sorted.
+func chooseMatch(candidates []MatchCandidate, threshold float64) (*MatchCandidate, string) {
+	if len(candidates) == 0 {
+		return nil, "No results on TMDB"
+	}
+	best := &candidates[0]
+	if best.Score < threshold {
+		return nil, fmt.Sprintf("Best match scored %d%%, below the %d%% threshold", best.Percent(), int(threshold*100+0.5))
+	}
+	if len(candidates) > 1 && best.Score-candidates[1].Score < matchMargin {
+		return nil, "Several candidates scored alike"
+	}
+	return best, ""
+}
+
+// searchCandidates searches TMDB for a media item and returns scored
+// candidates, best first. Films are searched by year first, falling back to
+// any year when nothing was released in that one.
+func (c *TMDBClient) searchCandidates(ctx context.Context, media *Media) ([]MatchCandidate, error) {
+	title := media.ResolveTitle()
+
+	var candidates []MatchCandidate
+	if media.Type == Film {
+		results, err := c.SearchMovies(ctx, title, media.Year)
+		if err == nil && len(results) == 0 && media.Year > 0 {
+			results, err = c.SearchMovies(ctx, title, 0)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			candidates = append(candidates, MatchCandidate{
+				TMDBID: r.ID, Title: r.Title, Date: r.ReleaseDate,
+				Overview: r.Overview, PosterPath: r.PosterPath, Popularity: r.Popularity,
+			})
+		}
+	} else {
+		results, err := c.SearchTV(ctx, title)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			candidates = append(candidates, MatchCandidate{
+				TMDBID: r.ID, Title: r.Name, Date: r.FirstAirDate,
+				Overview: r.Overview, PosterPath: r.PosterPath, Popularity: r.Popularity,
+			})
+		}
+	}
+
+	scoreCandidates(title, media.Year, candidates)
+	return candidates, nil
+}
+
+// MatchReview is a media item whose TMDB match needs confirming by hand
+type MatchReview struct {
+	Path       string           `json:"path"`
+	Candidates []MatchCandidate `json:"candidates"`
+	Reason     string           `json:"reason"`
+	QueuedAt   time.Time        `json:"queued_at"`
+}
+
+// MatchQueue holds the media items waiting for their TMDB match to be
+// reviewed, keyed by directory. It is saved to a file when one is set.
+type MatchQueue struct {
+	mu       sync.Mutex
+	reviews  map[string]MatchReview
+	filePath string
+}
+
+// NewMatchQueue creates an empty review queue
+func NewMatchQueue() *MatchQueue {
+	return &MatchQueue{reviews: make(map[string]MatchReview)}
+}
+
+// Load reads the queue from path and saves it there from now on.
+// A missing file is not an error.
+func (q *MatchQueue) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read match reviews: %w", err)
+	}
+	var reviews []MatchReview
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &reviews); err != nil {
+			return fmt.Errorf("failed to parse match reviews: %w", err)
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.filePath = path
+	for _, review := range reviews {
+		q.reviews[review.Path] = review
+	}
+	return nil
+}
+
+// Put adds a review, replacing any earlier one for the same media item
+func (q *MatchQueue) Put(review MatchReview) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.reviews[review.Path] = review
+	q.saveLocked()
+}
+
+// Get returns the review for the media item in path
+func (q *MatchQueue) Get(path string) (MatchReview, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	review, ok := q.reviews[path]
+	return review, ok
+}
+
+// Remove drops the review for the media item in path, if there is one
+func (q *MatchQueue) Remove(path string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.reviews[path]; ok {
+		delete(q.reviews, path)
+		q.saveLocked()
+	}
+}
+
+// List returns the reviews in directory order
+func (q *MatchQueue) List() []MatchReview {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	list := make([]MatchReview, 0, len(q.reviews))
+	for _, review := range q.reviews {
+		list = append(list, review)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Path < list[j].Path
+	})
+	return list
+}
+
+// saveLocked writes the queue to its file, if it has one. Callers must hold q.mu.
+func (q *MatchQueue) saveLocked() {
+	if q.filePath == "" {
+		return
+	}
+
+	list := make([]MatchReview, 0, len(q.reviews))
+	for _, review := range q.reviews {
+		list = append(list, review)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Path < list[j].Path
+	})
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(q.filePath), 0755); err == nil {
+			err = writeFileAtomic(q.filePath, data, 0644)
+		}
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to save match reviews: %v", err)
+	}
+}
+
+// matchJobParams carries the score a match job accepts without review
+type matchJobParams struct {
+	Threshold float64 `json:"threshold"`
+}
+
+// assignTMDBID saves a media item's TMDB ID, takes it out of the review
+// queue and queues a download of its metadata
+func (app *App) assignTMDBID(media *Media, tmdbID string) error {
+	if err := WriteTMDBID(tmdbID, media.Path); err != nil {
+		return err
+	}
+	app.setMediaTMDBID(media.Path, tmdbID)
+	app.matches.Remove(media.Path)
+
+	media = app.findMediaByPath(media.Path)
+	if media == nil {
+		return nil
+	}
+	if _, err := app.queueMediaJob(jobTypeMetadata, "Metadata for "+media.Title, media); err != nil {
+		log.Printf("Warning: Failed to queue metadata download for %s: %v", media.Title, err)
+	}
+	return nil
+}
+
+// setMediaTMDBID records the TMDB ID of the media item in mediaPath
+func (app *App) setMediaTMDBID(mediaPath, tmdbID string) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	for i := range app.mediaList {
+		if app.mediaList[i].Path == mediaPath {
+			app.mediaList[i].TMDBID = tmdbID
+		}
+	}
+}
+
+// runMatchJob searches TMDB for every media item without a TMDB ID. Matches
+// scoring above the threshold are saved; the rest go to the review queue.
+func (app *App) runMatchJob(job *Job) (string, error) {
+	var params matchJobParams
+	if err := job.Params(&params); err != nil {
+		return "", err
+	}
+	if params.Threshold <= 0 {
+		params.Threshold = defaultMatchThreshold
+	}
+	if app.tmdbClient == nil {
+		return "", errors.New("TMDB is not configured")
+	}
+
+	var unmatched []Media
+	for _, media := range app.allMedia() {
+		if media.TMDBID == "" {
+			unmatched = append(unmatched, media)
+		}
+	}
+
+	matched, reviews, failed := 0, 0, 0
+	for i := range unmatched {
+		if err := job.Context().Err(); err != nil {
+			return "", err
+		}
+		media := &unmatched[i]
+		job.Update(func(p *JobProgress) {
+			p.Step = "Searching for " + media.DisplayTitle()
+			p.ItemsDone, p.ItemsTotal = i, len(unmatched)
+		})
+
+		candidates, err := app.tmdbClient.searchCandidates(job.Context(), media)
+		if err != nil {
+			job.Logf("%s: search failed: %v", media.DisplayTitle(), err)
+			failed++
+			continue
+		}
+
+		best, reason := chooseMatch(candidates, params.Threshold)
+		if best != nil {
+			if err := app.assignTMDBID(media, strconv.Itoa(best.TMDBID)); err != nil {
+				job.Logf("%s: failed to save TMDB ID: %v", media.DisplayTitle(), err)
+				failed++
+				continue
+			}
+			job.Logf("%s: matched %s (%d) with %d%%", media.DisplayTitle(), best.Title, best.TMDBID, best.Percent())
+			matched++
+			continue
+		}
+
+		app.matches.Put(MatchReview{
+			Path:       media.Path,
+			Candidates: candidates[:min(len(candidates), maxMatchCandidates)],
+			Reason:     reason,
+			QueuedAt:   time.Now(),
+		})
+		job.Logf("%s: needs review: %s", media.DisplayTitle(), reason)
+		reviews++
+	}
+	job.SetItems(len(unmatched), len(unmatched))
+	job.Logf("Matched %d, %d to review, %d failed", matched, reviews, failed)
+
+	if failed > 0 && matched == 0 && reviews == 0 {
+		return "", fmt.Errorf("every search failed, see the log")
+	}
+	return "/matches", nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// matchReviewItem is a review queue entry with its media item
+type matchReviewItem struct {
+	Media  *Media
+	Review MatchReview
+}
+
+// pendingMatchReviews returns the review queue's entries whose media item is
+// still in the library and still unmatched, dropping the rest
+func (app *App) pendingMatchReviews() []matchReviewItem {
+	var items []matchReviewItem
+	for _, review := range app.matches.List() {
+		media := app.findMediaByPath(review.Path)
+		if media == nil || media.TMDBID != "" {
+			app.matches.Remove(review.Path)
+			continue
+		}
+		items = append(items, matchReviewItem{Media: media, Review: review})
+	}
+	return items
+}
+
+// MatchesHandler shows the TMDB matches waiting for review, each with its
+// scored candidates. Scripts asking for JSON get the list.
+func (app *App) MatchesHandler(w http.ResponseWriter, r *http.Request) {
+	items := app.pendingMatchReviews()
+
+	if wantsJSON(r) {
+		type reviewJSON struct {
+			Slug string `json:"slug"`
+			MatchReview
+		}
+		list := make([]reviewJSON, 0, len(items))
+		for _, item := range items {
+			list = append(list, reviewJSON{Slug: item.Media.Slug(), MatchReview: item.Review})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	unmatched := 0
+	for _, media := range app.allMedia() {
+		if media.TMDBID == "" {
+			unmatched++
+		}
+	}
+
+	data := struct {
+		Items         []matchReviewItem
+		Unmatched     int
+		Threshold     int
+		TMDBAvailable bool
+		Cached        bool
+		CacheEntries  int
+		CacheMB       float64
+	}{
+		Items:         items,
+		Unmatched:     unmatched,
+		Threshold:     int(defaultMatchThreshold * 100),
+		TMDBAvailable: app.tmdbClient != nil,
+	}
+	if app.tmdbClient != nil && app.tmdbClient.cache != nil {
+		entries, size := app.tmdbClient.cache.Stats()
+		data.Cached, data.CacheEntries, data.CacheMB = true, entries, float64(size)/(1<<20)
+	}
+
+	if err := app.templatesFor(r).ExecuteTemplate(w, "matches.html", data); err != nil {
+		log.Printf("Error rendering matches template: %v", err)
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	}
+}
+
+// RunMatchHandler queues a search for TMDB matches for every unmatched
+// media item. The optional "threshold" is the percentage score accepted
+// without review.
+func (app *App) RunMatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if app.tmdbClient == nil {
+		http.Error(w, "TMDB API is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	params := matchJobParams{Threshold: defaultMatchThreshold}
+	if value := r.FormValue("threshold"); value != "" {
+		percent, err := strconv.Atoi(value)
+		if err != nil || percent < 1 || percent > 100 {
+			http.Error(w, "Threshold must be a percentage from 1 to 100", http.StatusBadRequest)
+			return
+		}
+		params.Threshold = float64(percent) / 100
+	}
+
+	job, err := app.jobs.Enqueue(jobTypeMatch, "Match unmatched media to TMDB", params)
+	if err != nil {
+		log.Printf("Failed to queue TMDB matching: %v", err)
+		http.Error(w, "Failed to start matching", http.StatusInternalServerError)
+		return
+	}
+	app.respondWithJob(w, r, job)
+}
+
+// ConfirmMatchHandler accepts one of a reviewed item's candidates
+func (app *App) ConfirmMatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	media, review, ok := app.matchReviewFor(w, r)
+	if !ok {
+		return
+	}
+
+	// Only the candidates offered for review can be confirmed here
+	tmdbID, err := strconv.Atoi(r.FormValue("tmdb_id"))
+	if err != nil || !slices.ContainsFunc(review.Candidates, func(c MatchCandidate) bool { return c.TMDBID == tmdbID }) {
+		http.Error(w, "TMDB ID is not one of the candidates", http.StatusBadRequest)
+		return
+	}
+
+	if err := app.assignTMDBID(media, strconv.Itoa(tmdbID)); err != nil {
+		log.Printf("Failed to write TMDB ID for %s: %v", media.Title, err)
+		http.Error(w, "Failed to save TMDB ID", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Confirmed TMDB match %d for %s", tmdbID, media.Title)
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Matched %s to TMDB %d", media.DisplayTitle(), tmdbID)})
+		return
+	}
+	http.Redirect(w, r, "/matches", http.StatusSeeOther)
+}
+
+// DismissMatchHandler drops an item from the review queue, leaving it unmatched
+func (app *App) DismissMatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	media, _, ok := app.matchReviewFor(w, r)
+	if !ok {
+		return
+	}
+	app.matches.Remove(media.Path)
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Dismissed " + media.DisplayTitle()})
+		return
+	}
+	http.Redirect(w, r, "/matches", http.StatusSeeOther)
+}
+
+// matchReviewFor finds the media item named by the "slug" form value and its
+// review, writing a 404 if either is missing
+func (app *App) matchReviewFor(w http.ResponseWriter, r *http.Request) (*Media, MatchReview, bool) {
+	media := app.findMediaBySlug(r.FormValue("slug"))
+	if media == nil {
+		http.NotFound(w, r)
+		return nil, MatchReview{}, false
+	}
+	review, ok := app.matches.Get(media.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return nil, MatchReview{}, false
+	}
+	return media, review, true
+}
+
+// ClearTMDBCacheHandler forgets every cached TMDB response, so the next
+// searches and lookups go to TMDB
+func (app *App) ClearTMDBCacheHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if app.tmdbClient == nil || app.tmdbClient.cache == nil {
+		http.Error(w, "TMDB responses aren't cached", http.StatusNotFound)
+		return
+	}
+
+	if err := app.tmdbClient.cache.Clear(); err != nil {
+		log.Printf("Failed to clear TMDB cache: %v", err)
+		http.Error(w, "Failed to clear the TMDB cache", http.StatusInternalServerError)
+		return
+	}
+	log.Println("Cleared TMDB cache")
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Cleared the TMDB cache"})
+		return
+	}
+	http.Redirect(w, r, "/matches", http.StatusSeeOther)
+}
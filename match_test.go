@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newMatchTestApp returns an app with the mock TMDB server and an unmatched
+// film the mock can find, and the media directory
+func newMatchTestApp(t *testing.T) (*App, string) {
+	t.Helper()
+
+	app, _, testDir := setupAppWithMockTMDB(t, func(testDir string) {
+		if err := os.MkdirAll(filepath.Join(testDir, "Fight Club (1999) [Film]", "Disk [Blu-Ray]"), 0755); err != nil {
+			t.Fatalf("Failed to create film: %v", err)
+		}
+	})
+	return app, testDir
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Fight Club", "Fight Club", 1},
+		{"The Matrix", "Matrix", 1},
+		{"Fast & Furious", "Fast and Furious", 1},
+		{"Spider-Man: No Way Home", "spider man no way home", 1},
+		{"Alien", "Aliens", 1 - 1.0/6},
+		{"", "Anything", 0},
+	}
+	for _, tt := range tests {
+		if got := titleSimilarity(tt.a, tt.b); got < tt.want-0.001 || got > tt.want+0.001 {
+			t.Errorf("titleSimilarity(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestScoreCandidates(t *testing.T) {
+	candidates := []MatchCandidate{
+		{TMDBID: 1, Title: "Dune", Date: "1984-12-14", Popularity: 20},
+		{TMDBID: 2, Title: "Dune: Part Two", Date: "2024-02-27", Popularity: 100},
+		{TMDBID: 3, Title: "Dune", Date: "2021-09-15", Popularity: 80},
+	}
+	scoreCandidates("Dune", 2021, candidates)
+
+	// The exact title and year beats a more popular sequel and an older film
+	if candidates[0].TMDBID != 3 {
+		t.Fatalf("Best candidate = %+v, want the 2021 film", candidates[0])
+	}
+	if best, reason := chooseMatch(candidates, defaultMatchThreshold); best == nil || best.TMDBID != 3 {
+		t.Errorf("chooseMatch() = %v, %q, want the 2021 film", best, reason)
+	}
+
+	// Without a year, two films with the same title are too close to call
+	scoreCandidates("Dune", 0, candidates)
+	if best, reason := chooseMatch(candidates, defaultMatchThreshold); best != nil || reason != "Several candidates scored alike" {
+		t.Errorf("chooseMatch() without year = %v, %q, want a review", best, reason)
+	}
+
+	if best, reason := chooseMatch(nil, defaultMatchThreshold); best != nil || reason != "No results on TMDB" {
+		t.Errorf("chooseMatch(nil) = %v, %q", best, reason)
+	}
+
+	weak := []MatchCandidate{{TMDBID: 4, Title: "Something Else", Date: "2021-01-01"}}
+	scoreCandidates("Dune", 2021, weak)
+	if best, reason := chooseMatch(weak, defaultMatchThreshold); best != nil || !strings.Contains(reason, "below the 85% threshold") {
+		t.Errorf("chooseMatch() weak = %v, %q, want below threshold", best, reason)
+	}
+}
+
+func TestMatchQueuePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", matchQueueFileName)
+
+	queue := NewMatchQueue()
+	if err := queue.Load(path); err != nil {
+		t.Fatalf("Load() of a missing file error = %v", err)
+	}
+	queue.Put(MatchReview{Path: "/media/B", Reason: "No results on TMDB"})
+	queue.Put(MatchReview{Path: "/media/A", Candidates: []MatchCandidate{{TMDBID: 7, Title: "A", Score: 0.5}}})
+	queue.Put(MatchReview{Path: "/media/C"})
+	queue.Remove("/media/C")
+
+	reloaded := NewMatchQueue()
+	if err := reloaded.Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	list := reloaded.List()
+	if len(list) != 2 || list[0].Path != "/media/A" || list[1].Path != "/media/B" {
+		t.Fatalf("List() = %+v, want A and B", list)
+	}
+	if list[0].Candidates[0].TMDBID != 7 || list[1].Reason != "No results on TMDB" {
+		t.Errorf("Reloaded reviews = %+v", list)
+	}
+}
+
+func TestMatchJob(t *testing.T) {
+	app, testDir := newMatchTestApp(t)
+
+	job, err := app.jobs.Enqueue(jobTypeMatch, "Match", matchJobParams{Threshold: defaultMatchThreshold})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	progress := waitForJob(t, job)
+	if progress.Status != JobSucceeded || progress.ResultURL != "/matches" {
+		t.Fatalf("Match job = %s %q (%s)", progress.Status, progress.ResultURL, progress.Error)
+	}
+
+	// Fight Club has one exact result, so it is matched without review
+	id, _ := os.ReadFile(filepath.Join(testDir, "Fight Club (1999) [Film]", "tmdb.txt"))
+	if strings.TrimSpace(string(id)) != "550" {
+		t.Errorf("Fight Club tmdb.txt = %q, want 550", id)
+	}
+	if media := app.findMediaBySlug("fight-club-1999"); media == nil || media.TMDBID != "550" {
+		t.Errorf("Library entry = %+v, want TMDB ID 550", media)
+	}
+
+	// Nothing is found for No TMDB, so it waits for review
+	review, ok := app.matches.Get(filepath.Join(testDir, "No TMDB (2021) [Film]"))
+	if !ok || review.Reason != "No results on TMDB" {
+		t.Errorf("Review = %+v, %v, want No TMDB queued", review, ok)
+	}
+	if _, ok := app.matches.Get(filepath.Join(testDir, "Fight Club (1999) [Film]")); ok {
+		t.Error("Matched film is in the review queue")
+	}
+
+	log := strings.Join(progress.Log, "\n")
+	for _, expected := range []string{"Fight Club (1999): matched Fight Club (550)", "No TMDB (2021): needs review", "Matched 1, 1 to review, 0 failed"} {
+		if !strings.Contains(log, expected) {
+			t.Errorf("Job log missing %q:\n%s", expected, log)
+		}
+	}
+}
+
+func TestMatchReviewHandlers(t *testing.T) {
+	app, testDir := newMatchTestApp(t)
+	filmDir := filepath.Join(testDir, "Fight Club (1999) [Film]")
+	app.matches.Put(MatchReview{
+		Path:       filmDir,
+		Reason:     "Several candidates scored alike",
+		Candidates: []MatchCandidate{{TMDBID: 550, Title: "Fight Club", Date: "1999-10-15", Score: 0.9}, {TMDBID: 551, Title: "Fight Club 2", Score: 0.85}},
+	})
+	app.matches.Put(MatchReview{Path: filepath.Join(testDir, "No TMDB (2021) [Film]"), Reason: "No results on TMDB"})
+	handler := app.routes()
+
+	req := httptest.NewRequest(http.MethodGet, "/matches", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	body := w.Body.String()
+	for _, expected := range []string{"Several candidates scored alike", `name="tmdb_id" value="550"`, "90%", "No results on TMDB", `action="/matches/run"`} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Review page missing %q", expected)
+		}
+	}
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		addCSRFToken(t, req)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Only offered candidates can be confirmed
+	if w := post("/matches/confirm", url.Values{"slug": {"fight-club-1999"}, "tmdb_id": {"999"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Confirm of another ID status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+	if w := post("/matches/confirm", url.Values{"slug": {"fight-club-1999"}, "tmdb_id": {"550"}}); w.Code != http.StatusSeeOther {
+		t.Fatalf("Confirm status = %v: %s", w.Code, w.Body.String())
+	}
+	if id, _ := os.ReadFile(filepath.Join(filmDir, "tmdb.txt")); strings.TrimSpace(string(id)) != "550" {
+		t.Errorf("tmdb.txt = %q, want 550", id)
+	}
+	if _, ok := app.matches.Get(filmDir); ok {
+		t.Error("Confirmed item is still waiting for review")
+	}
+
+	if w := post("/matches/dismiss", url.Values{"slug": {"no-tmdb-2021"}}); w.Code != http.StatusSeeOther {
+		t.Fatalf("Dismiss status = %v: %s", w.Code, w.Body.String())
+	}
+	if w := post("/matches/dismiss", url.Values{"slug": {"no-tmdb-2021"}}); w.Code != http.StatusNotFound {
+		t.Errorf("Second dismiss status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/matches", nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	var list []json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 0 {
+		t.Errorf("Review queue JSON = %s, want empty", w.Body.String())
+	}
+}
+
+func TestRunMatchHandler(t *testing.T) {
+	app, _ := newMatchTestApp(t)
+
+	form := url.Values{"threshold": {"150"}}
+	req := httptest.NewRequest(http.MethodPost, "/matches/run", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	app.RunMatchHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Invalid threshold status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+
+	form = url.Values{"threshold": {"95"}}
+	req = httptest.NewRequest(http.MethodPost, "/matches/run", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	app.RunMatchHandler(w, req)
+	if w.Code != http.StatusSeeOther || !strings.HasPrefix(w.Header().Get("Location"), "/jobs/") {
+		t.Fatalf("RunMatchHandler() = %v %q, want a redirect to the job", w.Code, w.Header().Get("Location"))
+	}
+
+	job, _ := app.jobs.Get(strings.TrimPrefix(w.Header().Get("Location"), "/jobs/"))
+	var params matchJobParams
+	job.Params(&params)
+	if params.Threshold != 0.95 {
+		t.Errorf("Threshold = %v, want 0.95", params.Threshold)
+	}
+	waitForJob(t, job)
+}
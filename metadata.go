@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const (
+	// overridesFileName is the file holding locally edited metadata for a media item
+	overridesFileName = "overrides.json"
+
+	maxTitleLength       = 200
+	maxDescriptionLength = 5000
+	maxGenreLength       = 50
+	maxGenreCount        = 20
+)
+
+// MetadataOverrides holds metadata fields edited in the browser.
+// Overrides live in their own file so TMDB fetches (which write title.txt,
+// description.txt and genre.txt) can never overwrite a local edit.
+type MetadataOverrides struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Genres      []string `json:"genres,omitempty"`
+}
+
+// IsEmpty reports whether no field is overridden
+func (o MetadataOverrides) IsEmpty() bool {
+	return o.Title == "" && o.Description == "" && len(o.Genres) == 0
+}
+
+// loadOverrides reads overrides.json from the media directory
+// Returns empty overrides if the file doesn't exist or contains invalid JSON
+func loadOverrides(dirPath string) MetadataOverrides {
+	var overrides MetadataOverrides
+	data, err := os.ReadFile(filepath.Join(dirPath, overridesFileName))
+	if err != nil {
+		return overrides
+	}
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return MetadataOverrides{}
+	}
+	return overrides
+}
+
+// saveOverrides writes overrides.json to the media directory
+// The file is removed when no field is overridden
+func saveOverrides(dirPath string, overrides MetadataOverrides) error {
+	overridesPath := filepath.Join(dirPath, overridesFileName)
+
+	if overrides.IsEmpty() {
+		if err := os.Remove(overridesPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove overrides file: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(overridesPath, data, 0644)
+}
+
+// writeFileAtomic writes data to a temporary file in the destination directory
+// and renames it into place, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeReaderAtomic(path, bytes.NewReader(data), perm)
+}
+
+// writeReaderAtomic is like writeFileAtomic but streams the content from a reader
+func writeReaderAtomic(path string, r io.Reader, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Clean up the temporary file on any failure
+	success := false
+	defer func() {
+		if !success {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+
+	success = true
+	return nil
+}
+
+// ParseGenreList splits a comma-separated genre list, dropping empty entries
+func ParseGenreList(text string) []string {
+	var genres []string
+	for _, genre := range strings.Split(text, ",") {
+		genre = strings.TrimSpace(genre)
+		if genre != "" {
+			genres = append(genres, genre)
+		}
+	}
+	return genres
+}
+
+// ValidateMetadataEdit checks edited metadata fields before they are written
+func ValidateMetadataEdit(title, description string, genres []string) error {
+	if len(title) > maxTitleLength {
+		return fmt.Errorf("title must be at most %d characters", maxTitleLength)
+	}
+	if containsControlChars(title, false) {
+		return fmt.Errorf("title contains invalid characters")
+	}
+
+	if len(description) > maxDescriptionLength {
+		return fmt.Errorf("description must be at most %d characters", maxDescriptionLength)
+	}
+	if containsControlChars(description, true) {
+		return fmt.Errorf("description contains invalid characters")
+	}
+
+	if len(genres) > maxGenreCount {
+		return fmt.Errorf("at most %d genres are allowed", maxGenreCount)
+	}
+	for _, genre := range genres {
+		if len(genre) > maxGenreLength {
+			return fmt.Errorf("genre %q must be at most %d characters", genre, maxGenreLength)
+		}
+		if containsControlChars(genre, false) {
+			return fmt.Errorf("genre %q contains invalid characters", genre)
+		}
+	}
+
+	return nil
+}
+
+// containsControlChars reports whether text contains control characters
+// Newlines and tabs are permitted when allowNewlines is true
+func containsControlChars(text string, allowNewlines bool) bool {
+	for _, r := range text {
+		if allowNewlines && (r == '\n' || r == '\r' || r == '\t') {
+			continue
+		}
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// directoryTitle extracts the title from a media directory name
+// Returns an empty string if the directory name doesn't match a media pattern
+func directoryTitle(dirPath string) string {
+	dirName := filepath.Base(dirPath)
+	if matches := filmPattern.FindStringSubmatch(dirName); matches != nil {
+		return matches[1]
+	}
+	if matches := tvPattern.FindStringSubmatch(dirName); matches != nil {
+		return matches[1]
+	}
+	return ""
+}
+
+// equalGenres reports whether two genre lists are identical
+func equalGenres(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "title.txt")
+
+	if err := writeFileAtomic(path, []byte("First"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	if err := writeFileAtomic(path, []byte("Second"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic() overwrite error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != "Second" {
+		t.Errorf("File content = %q, want %q", string(data), "Second")
+	}
+
+	// No temporary files should be left behind
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 file in directory, found %d", len(entries))
+	}
+}
+
+func TestWriteFileAtomicMissingDir(t *testing.T) {
+	err := writeFileAtomic("/nonexistent/dir/title.txt", []byte("x"), 0644)
+	if err == nil {
+		t.Error("Expected error for nonexistent directory, got nil")
+	}
+}
+
+func TestOverridesRoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	overrides := MetadataOverrides{
+		Title:       "Local Title",
+		Description: "Local description",
+		Genres:      []string{"Drama", "Comedy"},
+	}
+	if err := saveOverrides(tmpDir, overrides); err != nil {
+		t.Fatalf("saveOverrides() error = %v", err)
+	}
+
+	loaded := loadOverrides(tmpDir)
+	if loaded.Title != "Local Title" || loaded.Description != "Local description" {
+		t.Errorf("loadOverrides() = %+v, want %+v", loaded, overrides)
+	}
+	if !equalGenres(loaded.Genres, overrides.Genres) {
+		t.Errorf("loadOverrides().Genres = %v, want %v", loaded.Genres, overrides.Genres)
+	}
+
+	// Saving empty overrides removes the file
+	if err := saveOverrides(tmpDir, MetadataOverrides{}); err != nil {
+		t.Fatalf("saveOverrides() with empty overrides error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, overridesFileName)); !os.IsNotExist(err) {
+		t.Error("Expected overrides file to be removed")
+	}
+}
+
+func TestLoadOverridesInvalidJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, overridesFileName), []byte("{invalid"), 0644)
+
+	if overrides := loadOverrides(tmpDir); !overrides.IsEmpty() {
+		t.Errorf("loadOverrides() with invalid JSON = %+v, want empty", overrides)
+	}
+}
+
+func TestMediaPrefersOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "title.txt"), []byte("TMDB Title"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "description.txt"), []byte("TMDB description"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "genre.txt"), []byte("Action, Thriller"), 0644)
+
+	media := &Media{Title: "Dir Title", Type: Film, Path: tmpDir}
+
+	if got := media.LoadDescription(); got != "TMDB description" {
+		t.Errorf("LoadDescription() without override = %q, want TMDB description", got)
+	}
+
+	saveOverrides(tmpDir, MetadataOverrides{
+		Title:       "Edited Title",
+		Description: "Edited description",
+		Genres:      []string{"Comedy"},
+	})
+
+	if got := media.LoadDescription(); got != "Edited description" {
+		t.Errorf("LoadDescription() = %q, want Edited description", got)
+	}
+	if got := media.LoadTMDBDescription(); got != "TMDB description" {
+		t.Errorf("LoadTMDBDescription() = %q, want TMDB description", got)
+	}
+	if got := media.LoadGenres(); !equalGenres(got, []string{"Comedy"}) {
+		t.Errorf("LoadGenres() = %v, want [Comedy]", got)
+	}
+	if got := media.LoadTMDBGenres(); !equalGenres(got, []string{"Action", "Thriller"}) {
+		t.Errorf("LoadTMDBGenres() = %v, want [Action Thriller]", got)
+	}
+	if got := media.ResolveTitle(); got != "Edited Title" {
+		t.Errorf("ResolveTitle() = %q, want Edited Title", got)
+	}
+}
+
+func TestResolveTitleFallback(t *testing.T) {
+	tmpDir := t.TempDir()
+	filmDir := filepath.Join(tmpDir, "Alien (1979) [Film]")
+	os.Mkdir(filmDir, 0755)
+
+	media := &Media{Title: "Edited", Type: Film, Year: 1979, Path: filmDir}
+	if got := media.ResolveTitle(); got != "Alien" {
+		t.Errorf("ResolveTitle() = %q, want title from directory name", got)
+	}
+
+	os.WriteFile(filepath.Join(filmDir, "title.txt"), []byte("Alien: Director's Cut"), 0644)
+	if got := media.ResolveTitle(); got != "Alien: Director's Cut" {
+		t.Errorf("ResolveTitle() = %q, want title from title.txt", got)
+	}
+}
+
+func TestValidateMetadataEdit(t *testing.T) {
+	tests := []struct {
+		name        string
+		title       string
+		description string
+		genres      []string
+		wantErr     bool
+	}{
+		{"Valid edit", "The Thing", "An Antarctic research team.\nGreat film.", []string{"Horror"}, false},
+		{"Empty fields", "", "", nil, false},
+		{"Title too long", strings.Repeat("a", maxTitleLength+1), "", nil, true},
+		{"Title with newline", "The\nThing", "", nil, true},
+		{"Description too long", "", strings.Repeat("a", maxDescriptionLength+1), nil, true},
+		{"Description with control char", "", "bad\x00text", nil, true},
+		{"Too many genres", "", "", make([]string, maxGenreCount+1), true},
+		{"Genre too long", "", "", []string{strings.Repeat("a", maxGenreLength+1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMetadataEdit(tt.title, tt.description, tt.genres)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMetadataEdit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseGenreList(t *testing.T) {
+	got := ParseGenreList(" Drama, ,Comedy ,, Science Fiction ")
+	want := []string{"Drama", "Comedy", "Science Fiction"}
+	if !equalGenres(got, want) {
+		t.Errorf("ParseGenreList() = %v, want %v", got, want)
+	}
+	if got := ParseGenreList(""); len(got) != 0 {
+		t.Errorf("ParseGenreList(\"\") = %v, want empty", got)
+	}
+}
@@ -34,6 +34,7 @@ type Disk struct {
 	Format string  // Disk format (e.g., "Blu-Ray", "DVD", "Blu-Ray UHD")
 	SizeGB float64 // Disk size in gigabytes
 	Path   string  // Absolute path to the disk directory
+	Series int     // Series number (TV only, 0 for films)
 }
 
 // Media represents a media item from the backup directory
@@ -45,14 +46,22 @@ type Media struct {
 	Disks     []Disk    // Individual disk information
 	TMDBID    string    // TMDB ID (optional, empty string if not present)
 	Path      string    // Absolute path to the media directory
+
+	// LocalTitle is the title in the language a page is shown in, when that
+	// isn't the language metadata is saved in. Slugs still use Title.
+	LocalTitle string
 }
 
 // DisplayTitle returns the title with year for films, just title for TV
 func (m *Media) DisplayTitle() string {
+	title := m.Title
+	if m.LocalTitle != "" {
+		title = m.LocalTitle
+	}
 	if m.Type == Film && m.Year > 0 {
-		return fmt.Sprintf("%s (%d)", m.Title, m.Year)
+		return fmt.Sprintf("%s (%d)", title, m.Year)
 	}
-	return m.Title
+	return title
 }
 
 // Slug generates a URL-friendly slug from the media title and year
@@ -71,13 +80,12 @@ func (m *Media) Slug() string {
 
 // FindPosterFile returns the path and extension of the poster file if it exists
 func (m *Media) FindPosterFile() (string, bool) {
-	for _, ext := range []string{".jpg", ".jpeg", ".png", ".webp"} {
-		posterPath := filepath.Join(m.Path, "poster"+ext)
-		if _, err := os.Stat(posterPath); err == nil {
-			return posterPath, true
-		}
-	}
-	return "", false
+	return m.FindArtworkFile(ArtworkPoster)
+}
+
+// FindArtworkFile returns the path of the named artwork file (e.g. "fanart") if it exists
+func (m *Media) FindArtworkFile(name string) (string, bool) {
+	return findArtworkFile(m.Path, name)
 }
 
 // PosterURL returns the relative URL for the poster image
@@ -85,8 +93,65 @@ func (m *Media) PosterURL() string {
 	return fmt.Sprintf("/posters/%s", m.Slug())
 }
 
-// LoadDescription reads and returns the description from description.txt
+// ArtworkURL returns the relative URL for the named artwork image
+func (m *Media) ArtworkURL(name string) string {
+	return fmt.Sprintf("/art/%s/%s", m.Slug(), name)
+}
+
+// BackdropURL returns the relative URL for the backdrop (fanart) image
+func (m *Media) BackdropURL() string {
+	return m.ArtworkURL(ArtworkBackdrop)
+}
+
+// LogoURL returns the relative URL for the clear-logo image
+func (m *Media) LogoURL() string {
+	return m.ArtworkURL(ArtworkLogo)
+}
+
+// SeasonArtURL returns the relative URL for a TV season's poster
+func (m *Media) SeasonArtURL(season int) string {
+	return m.ArtworkURL(SeasonArtworkName(season))
+}
+
+// ThumbnailURL returns the URL for the named artwork resized to the given width.
+// The URL carries a version derived from the file's modification time so browsers
+// can cache it indefinitely and still pick up replaced artwork.
+func (m *Media) ThumbnailURL(name string, width int) string {
+	thumbURL := fmt.Sprintf("%s?w=%d", m.ArtworkURL(name), width)
+	if artworkPath, found := m.FindArtworkFile(name); found {
+		if info, err := os.Stat(artworkPath); err == nil {
+			thumbURL += "&v=" + artworkVersion(info.ModTime())
+		}
+	}
+	return thumbURL
+}
+
+// PosterThumbnailURL returns the URL for the poster resized to the given width
+func (m *Media) PosterThumbnailURL(width int) string {
+	return m.ThumbnailURL(ArtworkPoster, width)
+}
+
+// SeasonThumbnailURL returns the URL for a TV season's poster resized to the given width
+func (m *Media) SeasonThumbnailURL(season, width int) string {
+	return m.ThumbnailURL(SeasonArtworkName(season), width)
+}
+
+// LoadOverrides returns the locally edited metadata fields for the media item
+func (m *Media) LoadOverrides() MetadataOverrides {
+	return loadOverrides(m.Path)
+}
+
+// LoadDescription returns the description, preferring a local override
+// over the TMDB description in description.txt
 func (m *Media) LoadDescription() string {
+	if override := m.LoadOverrides().Description; override != "" {
+		return override
+	}
+	return m.LoadTMDBDescription()
+}
+
+// LoadTMDBDescription reads and returns the description from description.txt
+func (m *Media) LoadTMDBDescription() string {
 	descPath := filepath.Join(m.Path, "description.txt")
 	data, err := os.ReadFile(descPath)
 	if err != nil {
@@ -95,8 +160,17 @@ func (m *Media) LoadDescription() string {
 	return strings.TrimSpace(string(data))
 }
 
-// LoadGenres reads and returns the genres from genre.txt as a slice
+// LoadGenres returns the genres, preferring a local override
+// over the TMDB genres in genre.txt
 func (m *Media) LoadGenres() []string {
+	if override := m.LoadOverrides().Genres; len(override) > 0 {
+		return override
+	}
+	return m.LoadTMDBGenres()
+}
+
+// LoadTMDBGenres reads and returns the genres from genre.txt as a slice
+func (m *Media) LoadTMDBGenres() []string {
 	genrePath := filepath.Join(m.Path, "genre.txt")
 	data, err := os.ReadFile(genrePath)
 	if err != nil {
@@ -114,45 +188,48 @@ func (m *Media) LoadGenres() []string {
 	return genres
 }
 
-// PlayCommand generates a VLC play command for the disk
-func (d *Disk) PlayCommand(prefix string) string {
-	// Determine protocol based on disk format
-	var protocol string
-	formatLower := strings.ToLower(d.Format)
-
-	if strings.Contains(formatLower, "blu-ray") || strings.Contains(formatLower, "bluray") {
-		protocol = "bluray://"
-	} else if strings.Contains(formatLower, "dvd") {
-		protocol = "dvd://"
-	} else {
-		protocol = "file://"
-	}
-
-	// Construct the full path
-	fullPath := d.Path
-	if prefix != "" {
-		fullPath = prefix + d.Path
+// LoadTMDBTitle reads and returns the official title from title.txt
+func (m *Media) LoadTMDBTitle() string {
+	data, err := os.ReadFile(filepath.Join(m.Path, "title.txt"))
+	if err != nil {
+		return ""
 	}
-
-	return fmt.Sprintf("vlc \"%s%s\"", protocol, fullPath)
+	return strings.TrimSpace(string(data))
 }
 
-// MPVPlayCommand generates an MPV play command for the disk
-func (d *Disk) MPVPlayCommand(prefix string) string {
-	// Construct the full path
-	fullPath := d.Path
-	if prefix != "" {
-		fullPath = prefix + d.Path
+// ResolveTitle returns the title to display: a local override, then the
+// TMDB title from title.txt, then the title parsed from the directory name
+func (m *Media) ResolveTitle() string {
+	if override := m.LoadOverrides().Title; override != "" {
+		return override
+	}
+	if title := m.LoadTMDBTitle(); title != "" {
+		return title
 	}
+	if title := directoryTitle(m.Path); title != "" {
+		return title
+	}
+	return m.Title
+}
 
-	// Determine command based on disk format
+// FormatKind returns whether the disk is a Blu-ray, a DVD or a plain file
+func (d *Disk) FormatKind() string {
 	formatLower := strings.ToLower(d.Format)
 
 	if strings.Contains(formatLower, "blu-ray") || strings.Contains(formatLower, "bluray") {
-		return fmt.Sprintf("mpv bd:// --bluray-device=\"%s\"", fullPath)
+		return FormatBluRay
 	} else if strings.Contains(formatLower, "dvd") {
-		return fmt.Sprintf("mpv dvd:// --dvd-device=\"%s\"", fullPath)
-	} else {
-		return fmt.Sprintf("mpv \"%s\"", fullPath)
+		return FormatDVD
 	}
+	return FormatFile
+}
+
+// PlayCommand generates a VLC play command for the disk
+func (d *Disk) PlayCommand(mapping PathMapping) string {
+	return vlcPlayerProfile.Command(d, mapping)
+}
+
+// MPVPlayCommand generates an MPV play command for the disk
+func (d *Disk) MPVPlayCommand(mapping PathMapping) string {
+	return mpvPlayerProfile.Command(d, mapping)
 }
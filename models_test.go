@@ -178,7 +178,7 @@ func TestDiskPlayCommand(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := tt.disk.PlayCommand(tt.prefix)
+			result := tt.disk.PlayCommand(PathMapping{To: tt.prefix})
 			if result != tt.expected {
 				t.Errorf("Disk.PlayCommand() = %v, want %v", result, tt.expected)
 			}
@@ -287,7 +287,7 @@ func TestDiskMPVPlayCommand(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := tt.disk.MPVPlayCommand(tt.prefix)
+			result := tt.disk.MPVPlayCommand(PathMapping{To: tt.prefix})
 			if result != tt.expected {
 				t.Errorf("Disk.MPVPlayCommand() = %v, want %v", result, tt.expected)
 			}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// MoveProgressFunc reports the number of bytes moved so far out of the total
+type MoveProgressFunc func(done, total int64)
+
+// moveProgressInterval limits how often copy progress is reported
+const moveProgressInterval = 8 << 20 // 8 MB
+
+// moveDir moves a directory tree from src to dst. A rename is tried first;
+// when src and dst are on different filesystems the tree is copied to a
+// temporary directory next to dst, renamed into place and then src is removed,
+// so dst never holds a partial copy. Cancelling ctx stops a copy and removes
+// the temporary directory, leaving src in place.
+func moveDir(ctx context.Context, src, dst string, progress MoveProgressFunc) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	return copyAndRemoveDir(ctx, src, dst, progress)
+}
+
+// copyAndRemoveDir moves a directory tree by copying it and removing the source
+func copyAndRemoveDir(ctx context.Context, src, dst string, progress MoveProgressFunc) error {
+	total, err := regularFilesSize(src)
+	if err != nil {
+		return fmt.Errorf("failed to measure source directory: %w", err)
+	}
+
+	tmpDst := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".importing")
+	if err := os.RemoveAll(tmpDst); err != nil {
+		return fmt.Errorf("failed to clear previous partial copy: %w", err)
+	}
+
+	copier := &treeCopier{ctx: ctx, total: total, progress: progress}
+	if err := copier.copyTree(src, tmpDst); err != nil {
+		os.RemoveAll(tmpDst)
+		return fmt.Errorf("failed to copy directory: %w", err)
+	}
+	if err := os.Rename(tmpDst, dst); err != nil {
+		os.RemoveAll(tmpDst)
+		return fmt.Errorf("failed to move copied directory into place: %w", err)
+	}
+	if progress != nil {
+		progress(total, total)
+	}
+
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("copied to destination but failed to remove source: %w", err)
+	}
+	return nil
+}
+
+// regularFilesSize returns the total size of the regular files under dirPath,
+// which is the number of bytes a copy will move
+func regularFilesSize(dirPath string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// treeCopier copies directory trees while counting bytes copied, until its
+// context is cancelled
+type treeCopier struct {
+	ctx          context.Context
+	done         int64
+	total        int64
+	lastReported int64
+	progress     MoveProgressFunc
+}
+
+// copyTree recursively copies src to dst, preserving permissions and symlinks
+func (c *treeCopier) copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return c.copyFile(path, target, info.Mode().Perm())
+		default:
+			return fmt.Errorf("unsupported file type: %s", path)
+		}
+	})
+}
+
+// copyFile copies a single regular file
+func (c *treeCopier) copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, io.TeeReader(in, c)); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Write counts bytes passing through the copy and reports progress
+// periodically. It fails once the context is cancelled, stopping the copy.
+func (c *treeCopier) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	c.done += int64(len(p))
+	if c.progress != nil && c.done-c.lastReported >= moveProgressInterval {
+		c.lastReported = c.done
+		c.progress(c.done, c.total)
+	}
+	return len(p), nil
+}
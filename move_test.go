@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMoveDirSameFilesystem(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	dst := filepath.Join(tmpDir, "dst")
+	os.MkdirAll(filepath.Join(src, "BDMV"), 0755)
+	os.WriteFile(filepath.Join(src, "BDMV", "index.bdmv"), []byte("data"), 0644)
+
+	if err := moveDir(context.Background(), src, dst, nil); err != nil {
+		t.Fatalf("moveDir() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "BDMV", "index.bdmv")); err != nil {
+		t.Errorf("Moved file missing: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("Source directory still exists")
+	}
+}
+
+func TestCopyAndRemoveDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	dst := filepath.Join(tmpDir, "media", "Disk [Blu-Ray]")
+	os.MkdirAll(filepath.Join(src, "BDMV", "STREAM"), 0755)
+	os.MkdirAll(filepath.Dir(dst), 0755)
+
+	// Large enough to trigger intermediate progress reports
+	large := strings.Repeat("x", moveProgressInterval+1024)
+	os.WriteFile(filepath.Join(src, "BDMV", "STREAM", "00000.m2ts"), []byte(large), 0644)
+	os.WriteFile(filepath.Join(src, "BDMV", "index.bdmv"), []byte("index"), 0600)
+	os.Symlink("index.bdmv", filepath.Join(src, "BDMV", "link.bdmv"))
+
+	var reports [][2]int64
+	err := copyAndRemoveDir(context.Background(), src, dst, func(done, total int64) {
+		reports = append(reports, [2]int64{done, total})
+	})
+	if err != nil {
+		t.Fatalf("copyAndRemoveDir() error = %v", err)
+	}
+
+	// Contents, permissions and symlinks are preserved
+	data, err := os.ReadFile(filepath.Join(dst, "BDMV", "STREAM", "00000.m2ts"))
+	if err != nil || len(data) != len(large) {
+		t.Errorf("Copied stream has %d bytes (err %v), want %d", len(data), err, len(large))
+	}
+	if info, err := os.Stat(filepath.Join(dst, "BDMV", "index.bdmv")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Copied index mode = %v (err %v), want 0600", info.Mode().Perm(), err)
+	}
+	if link, err := os.Readlink(filepath.Join(dst, "BDMV", "link.bdmv")); err != nil || link != "index.bdmv" {
+		t.Errorf("Copied symlink = %q (err %v), want index.bdmv", link, err)
+	}
+
+	// The source and temporary copy are gone
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("Source directory still exists")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dst), ".Disk [Blu-Ray].importing")); !os.IsNotExist(err) {
+		t.Error("Temporary copy still exists")
+	}
+
+	// Progress is reported during the copy and ends at the total
+	if len(reports) < 2 {
+		t.Fatalf("Got %d progress reports, want at least 2", len(reports))
+	}
+	last := reports[len(reports)-1]
+	total := int64(len(large) + len("index"))
+	if last[0] != total || last[1] != total {
+		t.Errorf("Final progress = %d/%d, want %d/%d", last[0], last[1], total, total)
+	}
+}
+
+func TestCopyAndRemoveDirCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	dst := filepath.Join(tmpDir, "media", "Disk [Blu-Ray]")
+	os.MkdirAll(filepath.Join(src, "BDMV", "STREAM"), 0755)
+	os.MkdirAll(filepath.Dir(dst), 0755)
+	large := strings.Repeat("x", 3*moveProgressInterval)
+	os.WriteFile(filepath.Join(src, "BDMV", "STREAM", "00000.m2ts"), []byte(large), 0644)
+
+	// Cancel as soon as the copy is under way
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := copyAndRemoveDir(ctx, src, dst, func(done, total int64) {
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("copyAndRemoveDir() error = %v, want context.Canceled", err)
+	}
+
+	// Nothing is left half-written and the source is untouched
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dst), ".Disk [Blu-Ray].importing")); !os.IsNotExist(err) {
+		t.Error("Temporary copy still exists")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Error("Destination exists after a cancelled copy")
+	}
+	if info, err := os.Stat(filepath.Join(src, "BDMV", "STREAM", "00000.m2ts")); err != nil || info.Size() != int64(len(large)) {
+		t.Errorf("Source stream changed: %v", err)
+	}
+}
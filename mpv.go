@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// mpvTimeout bounds connecting to MPV and waiting for each reply
+const mpvTimeout = 5 * time.Second
+
+// MPVClient sends commands to a running MPV over its JSON IPC protocol, as
+// enabled by mpv --input-ipc-server. Each call uses a fresh connection so MPV
+// can be restarted between calls.
+type MPVClient struct {
+	network   string // "unix" or "tcp"
+	address   string
+	timeout   time.Duration
+	requestID atomic.Int64
+}
+
+// NewMPVClient creates a client for an IPC endpoint: a Unix socket path, or
+// tcp://host:port for a socket forwarded over the network (e.g. with socat)
+func NewMPVClient(endpoint string) (*MPVClient, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("MPV IPC endpoint must not be empty")
+	}
+	if address, ok := strings.CutPrefix(endpoint, "tcp://"); ok {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return nil, fmt.Errorf("invalid MPV TCP endpoint %q: %w", endpoint, err)
+		}
+		return &MPVClient{network: "tcp", address: address, timeout: mpvTimeout}, nil
+	}
+	return &MPVClient{network: "unix", address: endpoint, timeout: mpvTimeout}, nil
+}
+
+// mpvRequest is a command sent to MPV
+type mpvRequest struct {
+	Command   []interface{} `json:"command"`
+	RequestID int64         `json:"request_id"`
+}
+
+// mpvResponse is MPV's reply to a command, or an unrelated event
+type mpvResponse struct {
+	Error     string `json:"error"`
+	RequestID int64  `json:"request_id"`
+	Event     string `json:"event"`
+}
+
+// run sends commands in order over one connection, stopping at the first
+// one MPV rejects
+func (c *MPVClient) run(commands ...[]interface{}) error {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MPV: %w", err)
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for _, command := range commands {
+		conn.SetDeadline(time.Now().Add(c.timeout))
+
+		request := mpvRequest{Command: command, RequestID: c.requestID.Add(1)}
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to send command to MPV: %w", err)
+		}
+
+		// Skip events until the reply to this request arrives
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return fmt.Errorf("failed to read reply from MPV: %w", err)
+			}
+			var response mpvResponse
+			if err := json.Unmarshal(line, &response); err != nil {
+				return fmt.Errorf("invalid reply from MPV: %w", err)
+			}
+			if response.Event != "" || response.RequestID != request.RequestID {
+				continue
+			}
+			if response.Error != "success" {
+				return fmt.Errorf("MPV rejected %v: %s", command[0], response.Error)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// mpvLoadCommands returns the commands that play a disk at path, using the
+// same Blu-ray and DVD device handling as MPVPlayCommand
+func mpvLoadCommands(d *Disk, path string) [][]interface{} {
+	switch d.FormatKind() {
+	case FormatBluRay:
+		return [][]interface{}{
+			{"set_property", "bluray-device", path},
+			{"loadfile", "bd://", "replace"},
+		}
+	case FormatDVD:
+		return [][]interface{}{
+			{"set_property", "dvd-device", path},
+			{"loadfile", "dvd://", "replace"},
+		}
+	default:
+		return [][]interface{}{
+			{"loadfile", path, "replace"},
+		}
+	}
+}
+
+// Play replaces whatever MPV is playing with the disk at path
+func (c *MPVClient) Play(d *Disk, path string) error {
+	return c.run(append(mpvLoadCommands(d, path), []interface{}{"set_property", "pause", false})...)
+}
+
+// TogglePause pauses or resumes playback
+func (c *MPVClient) TogglePause() error {
+	return c.run([]interface{}{"cycle", "pause"})
+}
+
+// Stop stops playback
+func (c *MPVClient) Stop() error {
+	return c.run([]interface{}{"stop"})
+}
+
+// SeekChapter skips forward or back by a number of chapters
+func (c *MPVClient) SeekChapter(delta int) error {
+	return c.run([]interface{}{"add", "chapter", delta})
+}
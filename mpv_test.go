@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeMPV is a fake MPV IPC server that records the commands it receives
+type fakeMPV struct {
+	listener net.Listener
+	mu       sync.Mutex
+	commands [][]interface{}
+	reject   string // Command name answered with an error
+}
+
+// newFakeMPV starts a fake MPV listening on a Unix socket or TCP port
+func newFakeMPV(t *testing.T, network string) *fakeMPV {
+	t.Helper()
+
+	address := "127.0.0.1:0"
+	if network == "unix" {
+		dir, err := os.MkdirTemp("", "mpv")
+		if err != nil {
+			t.Fatalf("Failed to create socket directory: %v", err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		address = filepath.Join(dir, "socket")
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	fake := &fakeMPV{listener: listener}
+	go fake.serve()
+	return fake
+}
+
+// endpoint returns the MPV_IPC value for the fake server
+func (f *fakeMPV) endpoint() string {
+	if f.listener.Addr().Network() == "tcp" {
+		return "tcp://" + f.listener.Addr().String()
+	}
+	return f.listener.Addr().String()
+}
+
+func (f *fakeMPV) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeMPV) handle(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var request mpvRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, request.Command)
+		reject := f.reject
+		f.mu.Unlock()
+
+		// Real MPV interleaves events with replies
+		conn.Write([]byte(`{"event":"playback-restart"}` + "\n"))
+
+		status := "success"
+		if request.Command[0] == reject {
+			status = "property unavailable"
+		}
+		reply, _ := json.Marshal(map[string]interface{}{"error": status, "request_id": request.RequestID})
+		conn.Write(append(reply, '\n'))
+	}
+}
+
+// rejectCommand makes the fake answer a command with an error
+func (f *fakeMPV) rejectCommand(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reject = name
+}
+
+// received returns the commands received so far, JSON-decoded
+func (f *fakeMPV) received() [][]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]interface{}(nil), f.commands...)
+}
+
+func TestMPVClientPlay(t *testing.T) {
+	tests := []struct {
+		name string
+		disk Disk
+		want [][]interface{}
+	}{
+		{
+			name: "Blu-ray",
+			disk: Disk{Format: "Blu-Ray UHD", Path: "/media/Film/Disk [Blu-Ray UHD]"},
+			want: [][]interface{}{
+				{"set_property", "bluray-device", "/media/Film/Disk [Blu-Ray UHD]"},
+				{"loadfile", "bd://", "replace"},
+				{"set_property", "pause", false},
+			},
+		},
+		{
+			name: "DVD",
+			disk: Disk{Format: "DVD", Path: "/media/Film/Disk [DVD]"},
+			want: [][]interface{}{
+				{"set_property", "dvd-device", "/media/Film/Disk [DVD]"},
+				{"loadfile", "dvd://", "replace"},
+				{"set_property", "pause", false},
+			},
+		},
+		{
+			name: "file",
+			disk: Disk{Format: "MKV", Path: "/media/Film/film.mkv"},
+			want: [][]interface{}{
+				{"loadfile", "/media/Film/film.mkv", "replace"},
+				{"set_property", "pause", false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeMPV(t, "unix")
+			client, err := NewMPVClient(fake.endpoint())
+			if err != nil {
+				t.Fatalf("NewMPVClient() error = %v", err)
+			}
+
+			if err := client.Play(&tt.disk, tt.disk.Path); err != nil {
+				t.Fatalf("Play() error = %v", err)
+			}
+			if got := fake.received(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MPV received %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMPVClientTransportControls(t *testing.T) {
+	fake := newFakeMPV(t, "tcp")
+	client, err := NewMPVClient(fake.endpoint())
+	if err != nil {
+		t.Fatalf("NewMPVClient() error = %v", err)
+	}
+
+	if err := client.TogglePause(); err != nil {
+		t.Errorf("TogglePause() error = %v", err)
+	}
+	if err := client.SeekChapter(1); err != nil {
+		t.Errorf("SeekChapter(1) error = %v", err)
+	}
+	if err := client.SeekChapter(-1); err != nil {
+		t.Errorf("SeekChapter(-1) error = %v", err)
+	}
+	if err := client.Stop(); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+
+	want := [][]interface{}{
+		{"cycle", "pause"},
+		{"add", "chapter", float64(1)},
+		{"add", "chapter", float64(-1)},
+		{"stop"},
+	}
+	if got := fake.received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MPV received %v, want %v", got, want)
+	}
+}
+
+func TestMPVClientErrors(t *testing.T) {
+	if _, err := NewMPVClient(""); err == nil {
+		t.Error("NewMPVClient(\"\") expected error, got nil")
+	}
+	if _, err := NewMPVClient("tcp://no-port"); err == nil {
+		t.Error("NewMPVClient() invalid TCP endpoint expected error, got nil")
+	}
+
+	// MPV not running
+	client, _ := NewMPVClient(filepath.Join(t.TempDir(), "missing-socket"))
+	if err := client.Stop(); err == nil || !strings.Contains(err.Error(), "connect") {
+		t.Errorf("Stop() without MPV error = %v, want connection error", err)
+	}
+
+	// MPV rejects a command, so later commands aren't sent
+	fake := newFakeMPV(t, "unix")
+	fake.rejectCommand("set_property")
+	client, _ = NewMPVClient(fake.endpoint())
+	err := client.Play(&Disk{Format: "DVD", Path: "/media/Film"}, "/media/Film")
+	if err == nil || !strings.Contains(err.Error(), "property unavailable") {
+		t.Errorf("Play() error = %v, want MPV's error", err)
+	}
+	if got := fake.received(); len(got) != 1 {
+		t.Errorf("MPV received %d commands after a rejection, want 1", len(got))
+	}
+}
+
+func TestMPVHandler(t *testing.T) {
+	tmpl, err := parseTemplates(templateFS(""))
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+	testDir := setupTestData(t)
+	mediaList, err := NewScanner(testDir).Scan()
+	if err != nil {
+		t.Fatalf("Failed to scan test data: %v", err)
+	}
+	app := NewApp(mediaList, tmpl, testDir, "")
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+
+	post := func(form url.Values, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/media/war-of-the-worlds-2025/mpv", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		app.MPVHandler(w, req)
+		return w
+	}
+
+	// Disabled until an endpoint is configured
+	if w := post(url.Values{"action": {"stop"}}, ""); w.Code != http.StatusNotFound {
+		t.Errorf("MPVHandler() without MPV status = %v, want %v", w.Code, http.StatusNotFound)
+	}
+
+	fake := newFakeMPV(t, "unix")
+	client, _ := NewMPVClient(fake.endpoint())
+	app.SetMPVClient(client, PathMapping{From: testDir, To: "/mnt/nas"})
+
+	// The detail page shows the MPV controls
+	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025", nil)
+	w := httptest.NewRecorder()
+	app.DetailHandler(w, req)
+	if !strings.Contains(w.Body.String(), "Play in MPV") || !strings.Contains(w.Body.String(), `value="next-chapter"`) {
+		t.Error("Detail page does not show the MPV controls")
+	}
+
+	// Playing a disk sends its mapped path
+	w = post(url.Values{"action": {"play"}, "disk": {"0"}}, "")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/media/war-of-the-worlds-2025" {
+		t.Fatalf("MPVHandler() play = %v %q, want redirect to detail page", w.Code, w.Header().Get("Location"))
+	}
+	mapped := PathMapping{From: testDir, To: "/mnt/nas"}.Apply(media.Disks[0].Path)
+	if got := fake.received(); len(got) == 0 || !reflect.DeepEqual(got[0][2], mapped) {
+		t.Errorf("MPV received %v, want device %q", got, mapped)
+	}
+
+	// Scripts get a message
+	w = post(url.Values{"action": {"pause"}}, "application/json")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "message") {
+		t.Errorf("MPVHandler() JSON = %v %q, want message", w.Code, w.Body.String())
+	}
+
+	// Bad requests
+	for _, form := range []url.Values{
+		{"action": {"play"}, "disk": {"99"}},
+		{"action": {"play"}},
+		{"action": {"eject"}},
+	} {
+		if w := post(form, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("MPVHandler(%v) status = %v, want %v", form, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	// MPV errors are reported to scripts
+	fake.rejectCommand("stop")
+	w = post(url.Values{"action": {"stop"}}, "application/json")
+	if w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "error") {
+		t.Errorf("MPVHandler() rejected = %v %q, want error", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mediaForRemote finds the media item for a /media/{slug}/... remote control URL
+func (app *App) mediaForRemote(w http.ResponseWriter, r *http.Request) *Media {
+	// Only accept POST requests
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil
+	}
+
+	// Extract slug from URL: /media/{slug}/{action}
+	path := strings.TrimPrefix(r.URL.Path, "/media/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return nil
+	}
+
+	media := app.findMediaBySlug(parts[0])
+	if media == nil {
+		http.NotFound(w, r)
+	}
+	return media
+}
+
+// diskFromForm returns the disk picked by the form's "disk" index
+func diskFromForm(media *Media, r *http.Request) (*Disk, bool) {
+	index, err := strconv.Atoi(r.FormValue("disk"))
+	if err != nil || index < 0 || index >= len(media.Disks) {
+		return nil, false
+	}
+	return &media.Disks[index], true
+}
+
+// respondToRemote reports the result of a remote control action. Scripts
+// asking for JSON get a message to show; plain form posts go back to the
+// detail page.
+func respondToRemote(w http.ResponseWriter, r *http.Request, media *Media, message string, err error) {
+	if err != nil {
+		log.Printf("Remote control failed for %s: %v", media.Slug(), err)
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"message": message})
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	http.Redirect(w, r, "/media/"+media.Slug(), http.StatusSeeOther)
+}
+
+// MPVHandler sends a disk or a transport control to MPV: /media/{slug}/mpv
+func (app *App) MPVHandler(w http.ResponseWriter, r *http.Request) {
+	media := app.mediaForRemote(w, r)
+	if media == nil {
+		return
+	}
+
+	if app.mpv == nil {
+		http.Error(w, "MPV is not configured", http.StatusNotFound)
+		return
+	}
+
+	var message string
+	var err error
+	switch action := r.FormValue("action"); action {
+	case "play":
+		disk, ok := diskFromForm(media, r)
+		if !ok {
+			http.Error(w, "Invalid disk", http.StatusBadRequest)
+			return
+		}
+		err = app.mpv.Play(disk, app.mpvPathMapping.Apply(disk.Path))
+		message = "Playing " + disk.Name + " in MPV"
+	case "pause":
+		err = app.mpv.TogglePause()
+		message = "Paused or resumed MPV"
+	case "stop":
+		err = app.mpv.Stop()
+		message = "Stopped MPV"
+	case "next-chapter":
+		err = app.mpv.SeekChapter(1)
+		message = "Next chapter"
+	case "previous-chapter":
+		err = app.mpv.SeekChapter(-1)
+		message = "Previous chapter"
+	default:
+		http.Error(w, "Unknown MPV action", http.StatusBadRequest)
+		return
+	}
+
+	respondToRemote(w, r, media, message, err)
+}
+
+// KodiHandler plays a disk on a Kodi instance: /media/{slug}/kodi
+func (app *App) KodiHandler(w http.ResponseWriter, r *http.Request) {
+	media := app.mediaForRemote(w, r)
+	if media == nil {
+		return
+	}
+
+	client := app.findKodi(r.FormValue("instance"))
+	if client == nil {
+		http.Error(w, "Unknown Kodi instance", http.StatusBadRequest)
+		return
+	}
+	disk, ok := diskFromForm(media, r)
+	if !ok {
+		http.Error(w, "Invalid disk", http.StatusBadRequest)
+		return
+	}
+
+	err := client.Play(r.Context(), disk, app.playURLPrefix)
+	respondToRemote(w, r, media, "Playing "+disk.Name+" on "+client.Name(), err)
+}
+
+// kodiStatusTimeout bounds the status check so offline boxes don't hold up the page
+const kodiStatusTimeout = 3 * time.Second
+
+// KodiStatus is whether a Kodi instance is answering
+type KodiStatus struct {
+	Name   string `json:"name"`
+	Online bool   `json:"online"`
+	Error  string `json:"error,omitempty"`
+}
+
+// KodiStatusHandler reports which Kodi instances are online as JSON
+func (app *App) KodiStatusHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), kodiStatusTimeout)
+	defer cancel()
+
+	// Check every instance at once
+	statuses := make([]KodiStatus, len(app.kodi))
+	var wg sync.WaitGroup
+	for i, client := range app.kodi {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = KodiStatus{Name: client.Name(), Online: true}
+			if err := client.Ping(ctx); err != nil {
+				statuses[i].Online = false
+				statuses[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(statuses)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Disc format kinds that player profiles give command templates for
+const (
+	FormatBluRay = "bluray"
+	FormatDVD    = "dvd"
+	FormatFile   = "file" // Anything else, played as a plain file or folder
+)
+
+// Path styles control the separators and quoting used for a client's OS
+const (
+	PathStyleDefault = ""        // Paths and quoting left as they are
+	PathStylePOSIX   = "posix"   // Linux and macOS shells
+	PathStyleWindows = "windows" // Windows, with backslash separators
+)
+
+// PathMapping rewrites a disk's path on the server into the path a player sees
+type PathMapping struct {
+	From  string `json:"from"`            // Server path prefix to replace; empty to only prepend To
+	To    string `json:"to"`              // Replacement, such as /mnt/nas or \\nas\media
+	Style string `json:"style,omitempty"` // One of the PathStyle constants
+}
+
+// Apply maps a server path. Paths outside From are returned unchanged apart
+// from their separators. From only matches whole path elements, so "/media"
+// maps "/media/Film" but not "/media2/Film".
+func (m PathMapping) Apply(path string) string {
+	rest := path
+	mapped := false
+	if m.From == "" {
+		mapped = true
+	} else if r, ok := strings.CutPrefix(path, m.From); ok && (r == "" || strings.HasPrefix(r, "/") || strings.HasSuffix(m.From, "/")) {
+		rest, mapped = r, true
+	}
+
+	if m.Style == PathStyleWindows {
+		rest = strings.ReplaceAll(rest, "/", `\`)
+	}
+	if !mapped {
+		return rest
+	}
+	if m.Style == PathStyleWindows && strings.HasSuffix(m.To, `\`) {
+		rest = strings.TrimPrefix(rest, `\`)
+	}
+	return m.To + rest
+}
+
+// Quote escapes a mapped path, or a disk name, for use inside the double
+// quotes of a command template, following the quoting rules of the
+// mapping's style
+func (m PathMapping) Quote(path string) string {
+	switch m.Style {
+	case PathStylePOSIX:
+		// Inside double quotes the shell still expands these
+		return posixQuoteReplacer.Replace(path)
+	case PathStyleWindows:
+		// Backslashes are literal unless they come before a quote, so
+		// they're doubled there and the quote escaped. Paths can't contain
+		// quotes, but names can, and a trailing backslash comes before the
+		// closing quote.
+		var b strings.Builder
+		backslashes := 0
+		for _, r := range path {
+			switch r {
+			case '\\':
+				backslashes++
+				continue
+			case '"':
+				b.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+			default:
+				b.WriteString(strings.Repeat(`\`, backslashes))
+			}
+			backslashes = 0
+			b.WriteRune(r)
+		}
+		b.WriteString(strings.Repeat(`\`, 2*backslashes))
+		return b.String()
+	default:
+		return path
+	}
+}
+
+// posixQuoteReplacer escapes the characters that are special inside double quotes
+var posixQuoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+
+const (
+	// pathProfileCookieName is the cookie holding the browser's chosen path profile
+	pathProfileCookieName = "shelf_path_profile"
+
+	// pathProfileCookieMaxAge keeps the choice for a year, in seconds
+	pathProfileCookieMaxAge = 365 * 24 * 60 * 60
+)
+
+// PathProfile is a named path mapping that a browser can choose, so each
+// client gets play commands with paths its OS understands
+type PathProfile struct {
+	Name string `json:"name"`
+	PathMapping
+}
+
+// pathProfilesFile is the JSON layout of the path profiles file
+type pathProfilesFile struct {
+	Profiles []PathProfile `json:"path_profiles"`
+}
+
+// LoadPathProfiles reads path mapping profiles from a JSON file
+func LoadPathProfiles(path string) ([]PathProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read path profiles file: %w", err)
+	}
+
+	var file pathProfilesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse path profiles file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, profile := range file.Profiles {
+		if profile.Name == "" {
+			return nil, fmt.Errorf("path profiles file contains a profile without a name")
+		}
+		if seen[profile.Name] {
+			return nil, fmt.Errorf("path profiles file contains duplicate profile %q", profile.Name)
+		}
+		seen[profile.Name] = true
+
+		switch profile.Style {
+		case PathStyleDefault, PathStylePOSIX, PathStyleWindows:
+		default:
+			return nil, fmt.Errorf("path profile %q has invalid style %q (expected %q or %q)", profile.Name, profile.Style, PathStylePOSIX, PathStyleWindows)
+		}
+	}
+	return file.Profiles, nil
+}
+
+// FindPathProfile returns the profile with the given name
+func FindPathProfile(profiles []PathProfile, name string) *PathProfile {
+	for i := range profiles {
+		if profiles[i].Name == name {
+			return &profiles[i]
+		}
+	}
+	return nil
+}
+
+// PlayerProfile describes how to launch a media player for a disk
+type PlayerProfile struct {
+	Name string `json:"name"`
+
+	// Commands are command templates keyed by format kind. {path} is replaced
+	// with the disk's path and {name} with its name. Formats without a template
+	// use the "file" template.
+	Commands map[string]string `json:"commands"`
+
+	// PathMapping replaces PLAY_URL_PREFIX for this player when set
+	PathMapping *PathMapping `json:"path_mapping,omitempty"`
+}
+
+// The built-in profiles, used when no players file is configured
+var (
+	vlcPlayerProfile = PlayerProfile{
+		Name: "VLC",
+		Commands: map[string]string{
+			FormatBluRay: `vlc "bluray://{path}"`,
+			FormatDVD:    `vlc "dvd://{path}"`,
+			FormatFile:   `vlc "file://{path}"`,
+		},
+	}
+	mpvPlayerProfile = PlayerProfile{
+		Name: "MPV",
+		Commands: map[string]string{
+			FormatBluRay: `mpv bd:// --bluray-device="{path}"`,
+			FormatDVD:    `mpv dvd:// --dvd-device="{path}"`,
+			FormatFile:   `mpv "{path}"`,
+		},
+	}
+)
+
+// DefaultPlayerProfiles returns the built-in VLC and MPV profiles
+func DefaultPlayerProfiles() []PlayerProfile {
+	return []PlayerProfile{vlcPlayerProfile, mpvPlayerProfile}
+}
+
+// Command returns the command that plays d, with its path rewritten by mapping.
+// Returns "" if the profile has no template for the disk's format.
+func (p PlayerProfile) Command(d *Disk, mapping PathMapping) string {
+	tmpl, ok := p.Commands[d.FormatKind()]
+	if !ok {
+		tmpl, ok = p.Commands[FormatFile]
+	}
+	if !ok {
+		return ""
+	}
+	path := mapping.Quote(mapping.Apply(d.Path))
+	return strings.NewReplacer("{path}", path, "{name}", mapping.Quote(d.Name)).Replace(tmpl)
+}
+
+// CommandFor returns the command that plays d on a client. The client's path
+// profile wins, then the player's own mapping, then the PLAY_URL_PREFIX prefix.
+func (p PlayerProfile) CommandFor(d *Disk, client *PathProfile, prefix string) string {
+	mapping := PathMapping{To: prefix}
+	if client != nil {
+		mapping = client.PathMapping
+	} else if p.PathMapping != nil {
+		mapping = *p.PathMapping
+	}
+	return p.Command(d, mapping)
+}
+
+// playersFile is the JSON layout of the players file
+type playersFile struct {
+	Players []PlayerProfile `json:"players"`
+}
+
+// LoadPlayerProfiles reads player profiles from a JSON players file
+func LoadPlayerProfiles(path string) ([]PlayerProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read players file: %w", err)
+	}
+
+	var file playersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse players file: %w", err)
+	}
+	if len(file.Players) == 0 {
+		return nil, fmt.Errorf("players file contains no players")
+	}
+
+	seen := make(map[string]bool)
+	for _, player := range file.Players {
+		if player.Name == "" {
+			return nil, fmt.Errorf("players file contains a player without a name")
+		}
+		if seen[player.Name] {
+			return nil, fmt.Errorf("players file contains duplicate player %q", player.Name)
+		}
+		seen[player.Name] = true
+
+		if len(player.Commands) == 0 {
+			return nil, fmt.Errorf("player %q has no commands", player.Name)
+		}
+		for kind, command := range player.Commands {
+			if kind != FormatBluRay && kind != FormatDVD && kind != FormatFile {
+				return nil, fmt.Errorf("player %q has a command for unknown format %q (expected %q, %q or %q)", player.Name, kind, FormatBluRay, FormatDVD, FormatFile)
+			}
+			if !strings.Contains(command, "{path}") {
+				return nil, fmt.Errorf("player %q %s command does not contain {path}", player.Name, kind)
+			}
+		}
+	}
+	return file.Players, nil
+}
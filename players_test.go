@@ -0,0 +1,296 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathMappingApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping PathMapping
+		path    string
+		want    string
+	}{
+		{"prefix only", PathMapping{To: "/mnt"}, "/media/Film", "/mnt/media/Film"},
+		{"replace root", PathMapping{From: "/media", To: "Z:/films"}, "/media/Film", "Z:/films/Film"},
+		{"outside root", PathMapping{From: "/srv", To: "/mnt"}, "/media/Film", "/media/Film"},
+		{"sibling of root", PathMapping{From: "/media", To: "/mnt"}, "/media2/Film", "/media2/Film"},
+		{"root itself", PathMapping{From: "/media", To: "/mnt"}, "/media", "/mnt"},
+		{"root with separator", PathMapping{From: "/media/", To: "/mnt/"}, "/media/Film", "/mnt/Film"},
+		{"windows UNC", PathMapping{From: "/srv/media", To: `\\nas\media`, Style: PathStyleWindows}, "/srv/media/Film (2020) [Film]/Disk [DVD]", `\\nas\media\Film (2020) [Film]\Disk [DVD]`},
+		{"windows root with separator", PathMapping{From: "/srv/media", To: `Z:\`, Style: PathStyleWindows}, "/srv/media/Film", `Z:\Film`},
+		{"posix mount", PathMapping{From: "/srv/media", To: "/mnt/nas", Style: PathStylePOSIX}, "/srv/media/Film", "/mnt/nas/Film"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mapping.Apply(tt.path); got != tt.want {
+				t.Errorf("Apply(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlayerProfileCommand(t *testing.T) {
+	iina := PlayerProfile{
+		Name: "IINA",
+		Commands: map[string]string{
+			FormatBluRay: `iina "bluray://{path}"`,
+			FormatFile:   `iina "{path}"`,
+		},
+		PathMapping: &PathMapping{From: "/media", To: "/Volumes/nas"},
+	}
+
+	tests := []struct {
+		name   string
+		player PlayerProfile
+		disk   Disk
+		prefix string
+		want   string
+	}{
+		{"Blu-ray template", iina, Disk{Format: "Blu-Ray UHD", Path: "/media/Film/Disk"}, "", `iina "bluray:///Volumes/nas/Film/Disk"`},
+		{"falls back to file template", iina, Disk{Format: "DVD", Path: "/media/Film/Disk"}, "", `iina "/Volumes/nas/Film/Disk"`},
+		{"mapping overrides prefix", iina, Disk{Format: "Blu-Ray", Path: "/media/Film/Disk"}, "/ignored", `iina "bluray:///Volumes/nas/Film/Disk"`},
+		{"default VLC uses prefix", vlcPlayerProfile, Disk{Format: "DVD", Path: "/Film/Disk"}, "/mnt", `vlc "dvd:///mnt/Film/Disk"`},
+		{"name placeholder", PlayerProfile{Commands: map[string]string{FormatFile: `play "{path}" --title "{name}"`}}, Disk{Name: "Disk 1", Format: "MKV", Path: "/f"}, "", `play "/f" --title "Disk 1"`},
+		{"name quoted", PlayerProfile{Commands: map[string]string{FormatFile: `play "{path}" --title "{name}"`}, PathMapping: &PathMapping{Style: PathStylePOSIX}}, Disk{Name: `Disk "$1"`, Format: "MKV", Path: "/f"}, "", `play "/f" --title "Disk \"\$1\""`},
+		{"no template", PlayerProfile{Commands: map[string]string{FormatDVD: `dvdplayer "{path}"`}}, Disk{Format: "Blu-Ray", Path: "/f"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.player.CommandFor(&tt.disk, nil, tt.prefix); got != tt.want {
+				t.Errorf("CommandFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPlayerProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "players.json")
+	os.WriteFile(path, []byte(`{"players": [
+		{"name": "mpc-hc", "commands": {"file": "mpc-hc64.exe \"{path}\""}, "path_mapping": {"from": "/media", "to": "Z:"}},
+		{"name": "Kodi", "commands": {"bluray": "kodi-send --action=\"PlayMedia({path})\"", "file": "kodi-send --action=\"PlayMedia({path})\""}}
+	]}`), 0644)
+
+	players, err := LoadPlayerProfiles(path)
+	if err != nil {
+		t.Fatalf("LoadPlayerProfiles() error = %v", err)
+	}
+	if len(players) != 2 || players[0].Name != "mpc-hc" || players[1].Name != "Kodi" {
+		t.Fatalf("LoadPlayerProfiles() = %+v, want mpc-hc and Kodi in order", players)
+	}
+	if got := players[0].CommandFor(&Disk{Format: "Blu-Ray", Path: "/media/Film"}, nil, ""); got != `mpc-hc64.exe "Z:/Film"` {
+		t.Errorf("mpc-hc command = %q", got)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid JSON", "{"},
+		{"no players", `{"players": []}`},
+		{"missing name", `{"players": [{"commands": {"file": "x {path}"}}]}`},
+		{"duplicate name", `{"players": [{"name": "a", "commands": {"file": "x {path}"}}, {"name": "a", "commands": {"file": "y {path}"}}]}`},
+		{"no commands", `{"players": [{"name": "a"}]}`},
+		{"unknown format", `{"players": [{"name": "a", "commands": {"hddvd": "x {path}"}}]}`},
+		{"missing path", `{"players": [{"name": "a", "commands": {"file": "x"}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "players.json")
+			os.WriteFile(path, []byte(tt.content), 0644)
+			if _, err := LoadPlayerProfiles(path); err == nil {
+				t.Error("LoadPlayerProfiles() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestDetailHandlerPlayerButtons(t *testing.T) {
+	tmpl, err := parseTemplates(templateFS(""))
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+	testDir := setupTestData(t)
+	mediaList, err := NewScanner(testDir).Scan()
+	if err != nil {
+		t.Fatalf("Failed to scan test data: %v", err)
+	}
+	app := NewApp(mediaList, tmpl, testDir, "")
+
+	render := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025", nil)
+		w := httptest.NewRecorder()
+		app.DetailHandler(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("DetailHandler() status = %v, want %v", w.Code, http.StatusOK)
+		}
+		return w.Body.String()
+	}
+
+	// The built-in profiles keep the VLC and MPV buttons
+	body := render()
+	for _, expected := range []string{"Copy VLC Command", "Copy MPV Command", "bluray:"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Detail page does not contain %q", expected)
+		}
+	}
+
+	// Configured profiles replace them
+	app.SetPlayerProfiles([]PlayerProfile{
+		{Name: "IINA", Commands: map[string]string{FormatFile: `iina "{path}"`}},
+		{Name: "Kodi", Commands: map[string]string{FormatBluRay: `kodi "{path}"`, FormatFile: `kodi "{path}"`}},
+	})
+	body = render()
+	if strings.Contains(body, "Copy VLC Command") {
+		t.Error("Detail page still shows the built-in VLC button")
+	}
+	for _, expected := range []string{"Copy IINA Command", "Copy Kodi Command"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Detail page does not contain %q", expected)
+		}
+	}
+}
+
+func TestPathMappingQuote(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		path  string
+		want  string
+	}{
+		{"default unchanged", PathStyleDefault, `/media/Film "$HOME"`, `/media/Film "$HOME"`},
+		{"posix specials", PathStylePOSIX, "/media/Film \"Cut\" $5 `x` \\", "/media/Film \\\"Cut\\\" \\$5 \\`x\\` \\\\"},
+		{"windows plain", PathStyleWindows, `\\nas\media\Film`, `\\nas\media\Film`},
+		{"windows trailing backslash", PathStyleWindows, `Z:\`, `Z:\\`},
+		{"windows quotes", PathStyleWindows, `Disk "1"\ \"x`, `Disk \"1\"\ \\\"x`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (PathMapping{Style: tt.style}).Quote(tt.path); got != tt.want {
+				t.Errorf("Quote(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiskPlayCommandWithPathProfile(t *testing.T) {
+	disk := Disk{Format: "Blu-Ray", Path: "/srv/media/Heat (1995) [Film]/Disk [Blu-Ray]"}
+	windows := PathMapping{From: "/srv/media", To: `\\nas\media`, Style: PathStyleWindows}
+	linux := PathMapping{From: "/srv/media", To: "/mnt/nas", Style: PathStylePOSIX}
+
+	if got, want := disk.PlayCommand(windows), `vlc "bluray://\\nas\media\Heat (1995) [Film]\Disk [Blu-Ray]"`; got != want {
+		t.Errorf("PlayCommand(windows) = %q, want %q", got, want)
+	}
+	if got, want := disk.MPVPlayCommand(linux), `mpv bd:// --bluray-device="/mnt/nas/Heat (1995) [Film]/Disk [Blu-Ray]"`; got != want {
+		t.Errorf("MPVPlayCommand(linux) = %q, want %q", got, want)
+	}
+
+	// A client's profile wins over the player's own mapping and the prefix
+	player := PlayerProfile{Commands: map[string]string{FormatFile: `play "{path}"`}, PathMapping: &PathMapping{To: "/player"}}
+	client := &PathProfile{Name: "Linux", PathMapping: linux}
+	if got, want := player.CommandFor(&disk, client, "/prefix"), `play "/mnt/nas/Heat (1995) [Film]/Disk [Blu-Ray]"`; got != want {
+		t.Errorf("CommandFor(client) = %q, want %q", got, want)
+	}
+}
+
+func TestLoadPathProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "paths.json")
+	os.WriteFile(path, []byte(`{"path_profiles": [
+		{"name": "Windows laptop", "from": "/srv/media", "to": "\\\\nas\\media", "style": "windows"},
+		{"name": "Linux desktop", "from": "/srv/media", "to": "/mnt/nas", "style": "posix"}
+	]}`), 0644)
+
+	profiles, err := LoadPathProfiles(path)
+	if err != nil {
+		t.Fatalf("LoadPathProfiles() error = %v", err)
+	}
+	windows := FindPathProfile(profiles, "Windows laptop")
+	if windows == nil || windows.To != `\\nas\media` || windows.Style != PathStyleWindows {
+		t.Errorf("Windows laptop profile = %+v", windows)
+	}
+	if FindPathProfile(profiles, "Phone") != nil {
+		t.Error("FindPathProfile() found an unknown profile")
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid JSON", "{"},
+		{"missing name", `{"path_profiles": [{"to": "/mnt"}]}`},
+		{"duplicate name", `{"path_profiles": [{"name": "a"}, {"name": "a"}]}`},
+		{"invalid style", `{"path_profiles": [{"name": "a", "style": "dos"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "paths.json")
+			os.WriteFile(path, []byte(tt.content), 0644)
+			if _, err := LoadPathProfiles(path); err == nil {
+				t.Error("LoadPathProfiles() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestPathProfileHandler(t *testing.T) {
+	tmpl, err := parseTemplates(templateFS(""))
+	if err != nil {
+		t.Fatalf("Failed to parse templates: %v", err)
+	}
+	testDir := setupTestData(t)
+	mediaList, err := NewScanner(testDir).Scan()
+	if err != nil {
+		t.Fatalf("Failed to scan test data: %v", err)
+	}
+	app := NewApp(mediaList, tmpl, testDir, "")
+	app.SetPathProfiles([]PathProfile{
+		{Name: "Windows", PathMapping: PathMapping{From: testDir, To: `\\nas\media`, Style: PathStyleWindows}},
+	})
+
+	choose := func(profile string) *httptest.ResponseRecorder {
+		form := url.Values{"profile": {profile}, "next": {"/media/war-of-the-worlds-2025"}}
+		req := httptest.NewRequest(http.MethodPost, "/path-profile", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		app.PathProfileHandler(w, req)
+		return w
+	}
+
+	w := choose("Windows")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/media/war-of-the-worlds-2025" {
+		t.Fatalf("PathProfileHandler() = %v %q, want redirect back", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != pathProfileCookieName || cookies[0].Value != "Windows" {
+		t.Fatalf("PathProfileHandler() cookies = %+v, want path profile cookie", cookies)
+	}
+
+	// The detail page uses the chosen profile
+	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025", nil)
+	req.AddCookie(cookies[0])
+	rec := httptest.NewRecorder()
+	app.DetailHandler(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, `nas\\media\\War of the Worlds`) {
+		t.Errorf("Detail page does not use the Windows paths")
+	}
+	if !strings.Contains(body, `<option value="Windows" selected>`) {
+		t.Errorf("Detail page does not show the chosen profile")
+	}
+
+	// Choosing the default clears the cookie
+	w = choose("")
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("Choosing default cookies = %+v, want cleared cookie", cookies)
+	}
+
+	if w := choose("Phone"); w.Code != http.StatusBadRequest {
+		t.Errorf("Unknown profile status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
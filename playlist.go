@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// playURLSchemes are the URL schemes players open each kind of disk with,
+// matching the VLC play commands
+var playURLSchemes = map[string]string{
+	FormatBluRay: "bluray",
+	FormatDVD:    "dvd",
+	FormatFile:   "file",
+}
+
+// diskNumberPattern matches the disk number at the end of a disk's name
+var diskNumberPattern = regexp.MustCompile(`Disk (\d+)$`)
+
+// PlayURL returns the URL a player opens to play the disk, with its path
+// rewritten by mapping and percent-encoded. Windows paths are written with
+// slashes, and a UNC path's server becomes the host, as in
+// file://nas/media/Film for \\nas\media\Film.
+func (d *Disk) PlayURL(mapping PathMapping) string {
+	path := mapping.Apply(d.Path)
+	host := ""
+	if mapping.Style == PathStyleWindows {
+		path = strings.ReplaceAll(path, `\`, "/")
+		if unc, ok := strings.CutPrefix(path, "//"); ok {
+			host, path, _ = strings.Cut(unc, "/")
+			path = "/" + path
+		} else if !strings.HasPrefix(path, "/") {
+			path = "/" + path // Drive letters, as in file:///Z:/Film
+		}
+	}
+	u := url.URL{Scheme: playURLSchemes[d.FormatKind()], Host: host, Path: path}
+	return u.String()
+}
+
+// number returns the disk's number within its series, or 0 if it has none
+func (d *Disk) number() int {
+	matches := diskNumberPattern.FindStringSubmatch(d.Name)
+	if matches == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(matches[1])
+	return n
+}
+
+// PlaylistEntry is a disk in a playlist
+type PlaylistEntry struct {
+	Title    string
+	Location string
+}
+
+// playlistEntries returns entries for disks in series and disk order. Disk
+// directories are read in name order, so "Disk 10" would otherwise come
+// before "Disk 2".
+func playlistEntries(media *Media, disks []Disk, mapping PathMapping) []PlaylistEntry {
+	ordered := append([]Disk(nil), disks...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Series != ordered[j].Series {
+			return ordered[i].Series < ordered[j].Series
+		}
+		return ordered[i].number() < ordered[j].number()
+	})
+
+	title := media.ResolveTitle()
+	entries := make([]PlaylistEntry, len(ordered))
+	for i := range ordered {
+		entries[i] = PlaylistEntry{
+			Title:    title + " - " + ordered[i].Name,
+			Location: ordered[i].PlayURL(mapping),
+		}
+	}
+	return entries
+}
+
+// writeM3U writes an extended M3U playlist. Durations are unknown, which
+// M3U writes as -1.
+func writeM3U(buf *bytes.Buffer, entries []PlaylistEntry) {
+	buf.WriteString("#EXTM3U\n")
+	for _, entry := range entries {
+		fmt.Fprintf(buf, "#EXTINF:-1,%s\n%s\n", oneLine(entry.Title), oneLine(entry.Location))
+	}
+}
+
+// oneLine replaces line breaks, which would split an M3U entry
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+// xspfPlaylist is the XML layout of an XSPF playlist
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version string      `xml:"version,attr"`
+	Title   string      `xml:"title"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title"`
+}
+
+// writeXSPF writes an XSPF playlist. XSPF locations must be URIs, which
+// play URLs already are.
+func writeXSPF(buf *bytes.Buffer, title string, entries []PlaylistEntry) error {
+	playlist := xspfPlaylist{Version: "1", Title: title}
+	for _, entry := range entries {
+		playlist.Tracks = append(playlist.Tracks, xspfTrack{Location: entry.Location, Title: entry.Title})
+	}
+
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(playlist); err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+	return nil
+}
+
+// PlaylistHandler serves a media item's disks as a playlist:
+// /media/{slug}/playlist.{m3u,xspf} for every disk, or
+// /media/{slug}/disks/{disk}/playlist.{m3u,xspf} for one
+func (app *App) PlaylistHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract slug, optional disk index and format from URL
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/media/"), "/")
+	if len(parts) != 2 && (len(parts) != 4 || parts[1] != "disks") {
+		http.NotFound(w, r)
+		return
+	}
+	media := app.findMediaBySlug(parts[0])
+	if media == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	disks := media.Disks
+	filename := media.Slug()
+	if len(parts) == 4 {
+		index, err := strconv.Atoi(parts[2])
+		if err != nil || index < 0 || index >= len(media.Disks) {
+			http.NotFound(w, r)
+			return
+		}
+		disks = media.Disks[index : index+1]
+		filename += "-disk-" + parts[2]
+	}
+
+	// Use the same paths as the play commands on the detail page
+	mapping := PathMapping{To: app.playURLPrefix}
+	if profile := app.pathProfileFor(r); profile != nil {
+		mapping = profile.PathMapping
+	}
+	entries := playlistEntries(media, disks, mapping)
+
+	var buf bytes.Buffer
+	switch parts[len(parts)-1] {
+	case "playlist.m3u":
+		writeM3U(&buf, entries)
+		w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+		filename += ".m3u"
+	case "playlist.xspf":
+		if err := writeXSPF(&buf, media.ResolveTitle(), entries); err != nil {
+			http.Error(w, "Failed to write playlist", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xspf+xml")
+		filename += ".xspf"
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newPlaylistTestApp returns an app whose TV show has disks that sort out of
+// order by name, and the media directory
+func newPlaylistTestApp(t *testing.T) (*App, string) {
+	t.Helper()
+
+	app, _, testDir := setupAppWithMockTMDB(t, func(testDir string) {
+		tvDir := filepath.Join(testDir, "Better Call Saul [TV]")
+		for _, name := range []string{"Series 1 Disk 10 [DVD]", "Series 2 Disk 1 [Blu-Ray]", "Series 10 Disk 1 [Blu-Ray]"} {
+			if err := os.Mkdir(filepath.Join(tvDir, name), 0755); err != nil {
+				t.Fatalf("Failed to create TV disk: %v", err)
+			}
+		}
+	})
+	return app, testDir
+}
+
+func TestDiskPlayURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		disk    Disk
+		mapping PathMapping
+		want    string
+	}{
+		{"Blu-ray", Disk{Format: "Blu-Ray", Path: "/Film/Disk"}, PathMapping{To: "/mnt/nas"}, "bluray:///mnt/nas/Film/Disk"},
+		{"DVD", Disk{Format: "DVD", Path: "/srv/media/Film/Disk"}, PathMapping{From: "/srv/media", To: "/media"}, "dvd:///media/Film/Disk"},
+		{"file", Disk{Format: "MKV", Path: "/Film/Disk"}, PathMapping{}, "file:///Film/Disk"},
+		{"escaped", Disk{Format: "MKV", Path: "/Film (2025)/Disk #1 [MKV]"}, PathMapping{}, "file:///Film%20%282025%29/Disk%20%231%20%5BMKV%5D"},
+		{"UNC", Disk{Format: "MKV", Path: "/srv/media/My Film/Disk"}, PathMapping{From: "/srv/media", To: `\\nas\media`, Style: PathStyleWindows}, "file://nas/media/My%20Film/Disk"},
+		{"drive", Disk{Format: "DVD", Path: "/srv/media/Film/Disk"}, PathMapping{From: "/srv/media", To: `Z:\`, Style: PathStyleWindows}, "dvd:///Z:/Film/Disk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.disk.PlayURL(tt.mapping); got != tt.want {
+				t.Errorf("PlayURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaylistHandlerM3U(t *testing.T) {
+	app, testDir := newPlaylistTestApp(t)
+	app.SetPlayURLPrefix("/mnt/nas")
+
+	req := httptest.NewRequest(http.MethodGet, "/media/better-call-saul/playlist.m3u", nil)
+	w := httptest.NewRecorder()
+	app.PlaylistHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PlaylistHandler() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "audio/x-mpegurl") {
+		t.Errorf("Content-Type = %q, want audio/x-mpegurl", got)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, `filename="better-call-saul.m3u"`) {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	tvDir := (&url.URL{Path: "/mnt/nas" + filepath.Join(testDir, "Better Call Saul [TV]")}).EscapedPath()
+	want := "#EXTM3U\n" +
+		"#EXTINF:-1,Better Call Saul - Series 1 Disk 1\nbluray://" + tvDir + "/Series%201%20Disk%201%20%5BBlu-Ray%5D\n" +
+		"#EXTINF:-1,Better Call Saul - Series 1 Disk 2\nbluray://" + tvDir + "/Series%201%20Disk%202%20%5BBlu-Ray%20UHD%5D\n" +
+		"#EXTINF:-1,Better Call Saul - Series 1 Disk 10\ndvd://" + tvDir + "/Series%201%20Disk%2010%20%5BDVD%5D\n" +
+		"#EXTINF:-1,Better Call Saul - Series 2 Disk 1\nbluray://" + tvDir + "/Series%202%20Disk%201%20%5BBlu-Ray%5D\n" +
+		"#EXTINF:-1,Better Call Saul - Series 10 Disk 1\nbluray://" + tvDir + "/Series%2010%20Disk%201%20%5BBlu-Ray%5D\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("PlaylistHandler() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPlaylistHandlerXSPF(t *testing.T) {
+	app, _ := newPlaylistTestApp(t)
+	media := app.findMediaBySlug("better-call-saul")
+
+	req := httptest.NewRequest(http.MethodGet, "/media/better-call-saul/playlist.xspf", nil)
+	w := httptest.NewRecorder()
+	app.PlaylistHandler(w, req)
+	if got := w.Header().Get("Content-Type"); got != "application/xspf+xml" {
+		t.Errorf("Content-Type = %q, want application/xspf+xml", got)
+	}
+
+	var playlist xspfPlaylist
+	if err := xml.Unmarshal(w.Body.Bytes(), &playlist); err != nil {
+		t.Fatalf("Invalid XSPF: %v\n%s", err, w.Body.String())
+	}
+	if playlist.Title != "Better Call Saul" || len(playlist.Tracks) != len(media.Disks) {
+		t.Fatalf("XSPF = %+v, want %d tracks", playlist, len(media.Disks))
+	}
+	first := playlist.Tracks[0]
+	if first.Title != "Better Call Saul - Series 1 Disk 1" || !strings.HasPrefix(first.Location, "bluray:///") || !strings.HasSuffix(first.Location, "/Series%201%20Disk%201%20%5BBlu-Ray%5D") {
+		t.Errorf("First track = %+v, want percent-encoded Series 1 Disk 1", first)
+	}
+
+	// UNC paths become the URL's host rather than being encoded whole
+	app.SetPathProfiles([]PathProfile{{Name: "Windows", PathMapping: PathMapping{From: app.mediaDir, To: `\\nas\media`, Style: PathStyleWindows}}})
+	req = httptest.NewRequest(http.MethodGet, "/media/better-call-saul/playlist.xspf", nil)
+	req.AddCookie(&http.Cookie{Name: pathProfileCookieName, Value: "Windows"})
+	w = httptest.NewRecorder()
+	app.PlaylistHandler(w, req)
+	var unc xspfPlaylist
+	if err := xml.Unmarshal(w.Body.Bytes(), &unc); err != nil {
+		t.Fatalf("Invalid XSPF: %v\n%s", err, w.Body.String())
+	}
+	if got, want := unc.Tracks[0].Location, "bluray://nas/media/Better%20Call%20Saul%20%5BTV%5D/Series%201%20Disk%201%20%5BBlu-Ray%5D"; got != want {
+		t.Errorf("UNC track location = %q, want %q", got, want)
+	}
+}
+
+func TestPlaylistHandlerPerDisk(t *testing.T) {
+	app, _ := newPlaylistTestApp(t)
+	app.SetPathProfiles([]PathProfile{{Name: "Laptop", PathMapping: PathMapping{To: "/Volumes/nas"}}})
+	media := app.findMediaBySlug("better-call-saul")
+
+	req := httptest.NewRequest(http.MethodGet, "/media/better-call-saul/disks/1/playlist.m3u", nil)
+	req.AddCookie(&http.Cookie{Name: pathProfileCookieName, Value: "Laptop"})
+	w := httptest.NewRecorder()
+	app.PlaylistHandler(w, req)
+
+	// The browser's path profile applies, as it does to the play commands
+	want := "#EXTM3U\n#EXTINF:-1,Better Call Saul - " + media.Disks[1].Name + "\n" +
+		media.Disks[1].PlayURL(PathMapping{To: "/Volumes/nas"}) + "\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("PlaylistHandler() = %q, want %q", got, want)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, `filename="better-call-saul-disk-1.m3u"`) {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	for _, path := range []string{
+		"/media/better-call-saul/disks/99/playlist.m3u",
+		"/media/better-call-saul/disks/x/playlist.m3u",
+		"/media/better-call-saul/playlist.pls",
+		"/media/missing/playlist.m3u",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		app.PlaylistHandler(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("PlaylistHandler(%q) status = %v, want %v", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestDetailLinksPlaylists(t *testing.T) {
+	app, _ := newPlaylistTestApp(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/media/better-call-saul", nil)
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, link := range []string{
+		`href="/media/better-call-saul/playlist.m3u"`,
+		`href="/media/better-call-saul/playlist.xspf"`,
+		`href="/media/better-call-saul/disks/0/playlist.xspf"`,
+	} {
+		if !strings.Contains(body, link) {
+			t.Errorf("Detail page missing %s", link)
+		}
+	}
+}
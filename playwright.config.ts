@@ -31,11 +31,19 @@ export default defineConfig({
     },
   ],
 
-  /* Run the Go server before starting tests */
-  webServer: {
-    command: 'npx tsx e2e/setup-fixtures.ts && MEDIA_DIR=./e2e/fixtures/media IMPORT_DIR=./e2e/fixtures/import PORT=8080 TMDB_API_KEY=test_key_for_e2e ./shelf',
-    url: 'http://localhost:8080',
-    reuseExistingServer: !process.env.CI,
-    timeout: 120 * 1000,
-  },
+  /* Run a fake TMDB server and the Go server before starting tests */
+  webServer: [
+    {
+      command: './shelf fake-tmdb -addr :8091',
+      url: 'http://localhost:8091/3/movie/603',
+      reuseExistingServer: !process.env.CI,
+      timeout: 120 * 1000,
+    },
+    {
+      command: 'npx tsx e2e/setup-fixtures.ts && MEDIA_DIR=./e2e/fixtures/media IMPORT_DIR=./e2e/fixtures/import PORT=8080 TMDB_API_URL=http://localhost:8091/3 TMDB_IMAGE_URL=http://localhost:8091/t/p ./shelf',
+      url: 'http://localhost:8080',
+      reuseExistingServer: !process.env.CI,
+      timeout: 120 * 1000,
+    },
+  ],
 });
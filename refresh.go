@@ -0,0 +1,490 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Metadata fields a refresh can update
+const (
+	fieldTitle       = "title"
+	fieldDescription = "description"
+	fieldGenres      = "genres"
+	fieldPoster      = "poster"
+)
+
+// refreshFields are the fields a refresh can overwrite, in display order
+var refreshFields = []string{fieldPoster, fieldTitle, fieldDescription, fieldGenres}
+
+// metadataFiles are the files each field is saved in
+var metadataFiles = map[string]string{
+	fieldTitle:       "title.txt",
+	fieldDescription: "description.txt",
+	fieldGenres:      "genre.txt",
+}
+
+// RemoteMetadata is a media item's metadata as TMDB currently has it
+type RemoteMetadata struct {
+	Title       string
+	Description string
+	Genres      []string
+	PosterPath  string
+	Credits     Credits
+	Collection  *Collection // Films only
+}
+
+// MetadataChange is a field whose saved value differs from TMDB's
+type MetadataChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// FetchRemoteMetadata fetches a media item's current title, overview,
+// genres and poster from TMDB
+func (c *TMDBClient) FetchRemoteMetadata(ctx context.Context, media *Media) (*RemoteMetadata, error) {
+	if media.TMDBID == "" {
+		return nil, fmt.Errorf("no TMDB ID for media: %s", media.Title)
+	}
+
+	var remote RemoteMetadata
+	var genres []Genre
+	if media.Type == Film {
+		movie, err := c.FetchMovieMetadata(ctx, media.TMDBID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch movie metadata: %w", err)
+		}
+		remote = RemoteMetadata{
+			Title:       movie.Title,
+			Description: movie.Overview,
+			PosterPath:  movie.PosterPath,
+			Credits:     movie.Credits.Credits(),
+			Collection:  movie.BelongsToCollection,
+		}
+		genres = movie.Genres
+	} else {
+		tv, err := c.FetchTVMetadata(ctx, media.TMDBID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch TV metadata: %w", err)
+		}
+		remote = RemoteMetadata{Title: tv.Name, Description: tv.Overview, PosterPath: tv.PosterPath, Credits: tv.TVCredits()}
+		genres = tv.Genres
+	}
+
+	for _, genre := range genres {
+		remote.Genres = append(remote.Genres, genre.Name)
+	}
+	remote.Title = strings.TrimSpace(remote.Title)
+	remote.Description = strings.TrimSpace(remote.Description)
+	return &remote, nil
+}
+
+// diffMetadata compares a media item's saved TMDB title, description and
+// genres with TMDB's. Fields TMDB has no value for are never reported, so a
+// gap on TMDB doesn't erase what was saved earlier.
+func diffMetadata(media *Media, remote *RemoteMetadata) []MetadataChange {
+	var changes []MetadataChange
+	if remote.Title != "" && remote.Title != media.LoadTMDBTitle() {
+		changes = append(changes, MetadataChange{Field: fieldTitle, Old: media.LoadTMDBTitle(), New: remote.Title})
+	}
+	if remote.Description != "" && remote.Description != media.LoadTMDBDescription() {
+		changes = append(changes, MetadataChange{Field: fieldDescription, Old: media.LoadTMDBDescription(), New: remote.Description})
+	}
+	if len(remote.Genres) > 0 && !equalGenres(remote.Genres, media.LoadTMDBGenres()) {
+		changes = append(changes, MetadataChange{
+			Field: fieldGenres,
+			Old:   strings.Join(media.LoadTMDBGenres(), ", "),
+			New:   strings.Join(remote.Genres, ", "),
+		})
+	}
+	return changes
+}
+
+// isOverridden reports whether field has been edited in the browser
+func (o MetadataOverrides) isOverridden(field string) bool {
+	switch field {
+	case fieldTitle:
+		return o.Title != ""
+	case fieldDescription:
+		return o.Description != ""
+	case fieldGenres:
+		return len(o.Genres) > 0
+	}
+	return false
+}
+
+// clear removes the local edit of field
+func (o *MetadataOverrides) clear(field string) {
+	switch field {
+	case fieldTitle:
+		o.Title = ""
+	case fieldDescription:
+		o.Description = ""
+	case fieldGenres:
+		o.Genres = nil
+	}
+}
+
+// RefreshPreview compares one field's saved value with TMDB's before a
+// refresh overwrites it. Posters are compared by URL: the saved poster's
+// origin isn't recorded, so a poster only counts as changed when missing.
+type RefreshPreview struct {
+	Field   string `json:"field"`
+	Current string `json:"current"`
+	TMDB    string `json:"tmdb"`
+	Changed bool   `json:"changed"`
+	// Edited is set when a local edit is shown instead of the saved value
+	Edited bool `json:"edited"`
+}
+
+// previewRefresh lists every field a refresh can overwrite with its saved
+// and TMDB values
+func (c *TMDBClient) previewRefresh(media *Media, remote *RemoteMetadata) []RefreshPreview {
+	changed := make(map[string]bool)
+	for _, change := range diffMetadata(media, remote) {
+		changed[change.Field] = true
+	}
+	overrides := media.LoadOverrides()
+
+	var previews []RefreshPreview
+	for _, field := range refreshFields {
+		preview := RefreshPreview{Field: field, Changed: changed[field], Edited: overrides.isOverridden(field)}
+		switch field {
+		case fieldPoster:
+			if _, exists := media.FindPosterFile(); exists {
+				preview.Current = media.PosterURL()
+			}
+			if remote.PosterPath != "" {
+				preview.TMDB = c.ThumbnailURL(remote.PosterPath)
+			}
+			preview.Changed = preview.Current == "" && preview.TMDB != ""
+		case fieldTitle:
+			preview.Current, preview.TMDB = media.LoadTMDBTitle(), remote.Title
+		case fieldDescription:
+			preview.Current, preview.TMDB = media.LoadTMDBDescription(), remote.Description
+		case fieldGenres:
+			preview.Current = strings.Join(media.LoadTMDBGenres(), ", ")
+			preview.TMDB = strings.Join(remote.Genres, ", ")
+		}
+		previews = append(previews, preview)
+	}
+	return previews
+}
+
+// applyRefresh overwrites the chosen fields with TMDB's values, whether or
+// not they changed, and returns the fields it wrote. Fields TMDB has no
+// value for are skipped. Local edits to the fields written are discarded,
+// as they would otherwise keep hiding TMDB's value. The cast and crew and a
+// film's collection have no local edits, so they're brought up to date
+// whatever was chosen.
+func (c *TMDBClient) applyRefresh(ctx context.Context, media *Media, remote *RemoteMetadata, fields []string) ([]string, error) {
+	values := map[string]string{
+		fieldTitle:       remote.Title,
+		fieldDescription: remote.Description,
+		fieldGenres:      strings.Join(remote.Genres, ", "),
+	}
+	overrides := media.LoadOverrides()
+	editsCleared := false
+
+	var written []string
+	for _, field := range fields {
+		if field == fieldPoster {
+			if remote.PosterPath == "" {
+				continue
+			}
+			if err := c.DownloadPoster(ctx, remote.PosterPath, media.Path); err != nil {
+				return written, err
+			}
+		} else {
+			if values[field] == "" {
+				continue
+			}
+			if err := saveMetadataChange(media.Path, MetadataChange{Field: field, New: values[field]}); err != nil {
+				return written, err
+			}
+			if overrides.isOverridden(field) {
+				overrides.clear(field)
+				editsCleared = true
+			}
+		}
+		written = append(written, field)
+	}
+
+	if editsCleared {
+		if err := saveOverrides(media.Path, overrides); err != nil {
+			return written, fmt.Errorf("failed to clear local edits: %w", err)
+		}
+	}
+
+	if !remote.Credits.IsEmpty() {
+		if _, err := saveCredits(media.Path, remote.Credits); err != nil {
+			log.Printf("Warning: Failed to save credits for %s: %v", media.Title, err)
+		}
+	}
+	if media.Type == Film {
+		if _, err := saveCollection(media.Path, remote.Collection); err != nil {
+			log.Printf("Warning: Failed to save collection for %s: %v", media.Title, err)
+		}
+	}
+	return written, nil
+}
+
+// saveMetadataChange writes a changed field's new value to its file
+func saveMetadataChange(mediaPath string, change MetadataChange) error {
+	name, ok := metadataFiles[change.Field]
+	if !ok {
+		return fmt.Errorf("unknown metadata field %q", change.Field)
+	}
+	if err := writeFileAtomic(filepath.Join(mediaPath, name), []byte(change.New), 0644); err != nil {
+		return fmt.Errorf("failed to save %s: %w", change.Field, err)
+	}
+	return nil
+}
+
+// queueMetadataRefresh queues a refresh job for every media item with a
+// TMDB ID and returns how many were queued
+func (app *App) queueMetadataRefresh() int {
+	queued := 0
+	for _, media := range app.allMedia() {
+		if media.TMDBID == "" {
+			continue
+		}
+		if _, err := app.queueMediaJob(jobTypeRefresh, "Refresh metadata for "+media.Title, &media); err != nil {
+			log.Printf("Warning: Failed to queue job: %v", err)
+			continue
+		}
+		queued++
+	}
+	return queued
+}
+
+// StartMetadataRefresh queues a metadata refresh of the library each time
+// schedule fires, until ctx is canceled
+func (app *App) StartMetadataRefresh(ctx context.Context, schedule *CronSchedule) {
+	go func() {
+		for {
+			next := schedule.Next(time.Now())
+			if next.IsZero() {
+				log.Printf("Warning: Metadata refresh schedule %q never fires", schedule)
+				return
+			}
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			log.Printf("Scheduled metadata refresh: queued %d items", app.queueMetadataRefresh())
+		}
+	}()
+}
+
+// runRefreshJob checks TMDB for changes to a media item's title, overview
+// and genres, and saves any it finds. Fields edited in the browser are left
+// alone. The cast and crew, collection and translations are brought up to
+// date too.
+func (app *App) runRefreshJob(job *Job) (string, error) {
+	media, err := app.mediaForJob(job)
+	if err != nil {
+		return "", err
+	}
+	if app.tmdbClient == nil {
+		return "", errors.New("TMDB is not configured")
+	}
+	if media.TMDBID == "" {
+		return "", fmt.Errorf("%s has no TMDB ID", media.Title)
+	}
+
+	// A refresh is pointless against cached responses
+	job.SetStep("Checking TMDB for changes")
+	remote, err := app.tmdbClient.FetchRemoteMetadata(withoutTMDBCache(job.Context()), media)
+	if err != nil {
+		return "", err
+	}
+
+	changes := diffMetadata(media, remote)
+	overrides := media.LoadOverrides()
+	updated := 0
+	for _, change := range changes {
+		if overrides.isOverridden(change.Field) {
+			job.Logf("%s: kept local edit (TMDB now has %q)", change.Field, change.New)
+			continue
+		}
+		if err := saveMetadataChange(media.Path, change); err != nil {
+			return "", err
+		}
+		job.Logf("%s: %q → %q", change.Field, change.Old, change.New)
+		log.Printf("Refreshed %s of %s: %q → %q", change.Field, media.Title, change.Old, change.New)
+		updated++
+	}
+
+	if len(changes) == 0 {
+		job.Logf("No changes on TMDB")
+	}
+
+	// Credits, collections and translations have no local edits to keep, so
+	// they're simply replaced
+	if !remote.Credits.IsEmpty() {
+		if changed, err := saveCredits(media.Path, remote.Credits); err != nil {
+			job.Logf("Cast and crew not updated: %v", err)
+		} else if changed {
+			job.Logf("Updated cast and crew")
+		}
+	}
+	if media.Type == Film {
+		if changed, err := saveCollection(media.Path, remote.Collection); err != nil {
+			job.Logf("Collection not updated: %v", err)
+		} else if changed && remote.Collection != nil {
+			job.Logf("Part of %s", remote.Collection.Name)
+		} else if changed {
+			job.Logf("No longer part of a collection")
+		}
+	}
+	if written, err := app.tmdbClient.saveLanguageVariants(withoutTMDBCache(job.Context()), media, true); err != nil {
+		job.Logf("Translations not updated: %v", err)
+	} else if written > 0 {
+		job.Logf("Updated %d translated titles and descriptions", written)
+	}
+
+	if updated == 0 {
+		return "/media/" + url.PathEscape(media.Slug()), nil
+	}
+
+	// A new title changes the slug
+	app.refreshMediaTitle(media.Path)
+	if refreshed := app.findMediaByPath(media.Path); refreshed != nil {
+		media = refreshed
+	}
+	return "/media/" + url.PathEscape(media.Slug()), nil
+}
+
+// RefreshMetadataHandler previews and applies a forced re-fetch of a media
+// item's TMDB metadata: GET /media/{slug}/refresh compares each field with
+// TMDB, and POST overwrites the fields named in "fields" (poster, title,
+// description, genres). Both answer JSON when the request asks for it.
+func (app *App) RefreshMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract slug from URL: /media/{slug}/refresh
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/media/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	media := app.findMediaBySlug(parts[0])
+	if media == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if app.tmdbClient == nil {
+		http.Error(w, "TMDB API is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if media.TMDBID == "" {
+		http.Error(w, "Set a TMDB ID before refreshing metadata", http.StatusBadRequest)
+		return
+	}
+
+	// Validate the choice before asking TMDB
+	var fields []string
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			return
+		}
+		for _, field := range r.Form["fields"] {
+			if !slices.Contains(refreshFields, field) {
+				http.Error(w, fmt.Sprintf("Unknown field %q", field), http.StatusBadRequest)
+				return
+			}
+			if !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			http.Error(w, "Choose at least one field to overwrite", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Compare against TMDB as it is now, not a cached copy
+	remote, err := app.tmdbClient.FetchRemoteMetadata(withoutTMDBCache(r.Context()), media)
+	if err != nil {
+		log.Printf("Failed to fetch TMDB metadata for %s: %v", media.Title, err)
+		http.Error(w, tmdbErrorMessage(err), tmdbErrorStatus(err))
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		previews := app.tmdbClient.previewRefresh(media, remote)
+		if wantsJSON(r) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"fields": previews})
+			return
+		}
+
+		data := struct {
+			Media    *Media
+			Previews []RefreshPreview
+		}{
+			Media:    media,
+			Previews: previews,
+		}
+		if err := app.templatesFor(r).ExecuteTemplate(w, "refresh.html", data); err != nil {
+			log.Printf("Error rendering refresh template: %v", err)
+			http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	written, err := app.tmdbClient.applyRefresh(r.Context(), media, remote, fields)
+	if err != nil {
+		log.Printf("Failed to refresh metadata for %s: %v", media.Title, err)
+		http.Error(w, "Failed to save metadata", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Refreshed %s of %s from TMDB", strings.Join(written, ", "), media.Title)
+
+	// A new title changes the slug
+	app.refreshMediaTitle(media.Path)
+	if refreshed := app.findMediaByPath(media.Path); refreshed != nil {
+		media = refreshed
+	}
+	mediaURL := "/media/" + url.PathEscape(media.Slug())
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"updated": written, "url": mediaURL})
+		return
+	}
+	http.Redirect(w, r, mediaURL, http.StatusSeeOther)
+}
+
+// refreshMediaTitle re-resolves the title of the media item in mediaPath
+// after its metadata files change
+func (app *App) refreshMediaTitle(mediaPath string) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+
+	for i := range app.mediaList {
+		if app.mediaList[i].Path == mediaPath {
+			app.mediaList[i].Title = app.mediaList[i].ResolveTitle()
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffMetadata(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "title.txt"), []byte("Same Title\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "description.txt"), []byte("Old overview"), 0644)
+	os.WriteFile(filepath.Join(dir, "genre.txt"), []byte("Drama, Crime"), 0644)
+	media := &Media{Path: dir}
+
+	changes := diffMetadata(media, &RemoteMetadata{
+		Title:       "Same Title",
+		Description: "New overview",
+		Genres:      []string{"Drama", "Thriller"},
+	})
+	want := []MetadataChange{
+		{Field: fieldDescription, Old: "Old overview", New: "New overview"},
+		{Field: fieldGenres, Old: "Drama, Crime", New: "Drama, Thriller"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("diffMetadata() = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("diffMetadata()[%d] = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+
+	// Fields missing on TMDB never erase saved values
+	if changes := diffMetadata(media, &RemoteMetadata{}); len(changes) != 0 {
+		t.Errorf("diffMetadata() with empty TMDB data = %+v, want none", changes)
+	}
+}
+
+func TestRefreshJobUpdatesChangedFields(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+	filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	os.WriteFile(filepath.Join(filmDir, "description.txt"), []byte("An outdated overview"), 0644)
+	os.WriteFile(filepath.Join(filmDir, "genre.txt"), []byte("Horror"), 0644)
+
+	// Genres were edited in the browser, so TMDB's must not replace them
+	if err := saveOverrides(filmDir, MetadataOverrides{Genres: []string{"Favourites"}}); err != nil {
+		t.Fatalf("saveOverrides() error = %v", err)
+	}
+
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+	job, err := app.queueMediaJob(jobTypeRefresh, "Refresh metadata for "+media.Title, media)
+	if err != nil {
+		t.Fatalf("queueMediaJob() error = %v", err)
+	}
+	progress := waitForJob(t, job)
+	if progress.Status != JobSucceeded {
+		t.Fatalf("Refresh job status = %s (%s), want succeeded", progress.Status, progress.Error)
+	}
+
+	description, _ := os.ReadFile(filepath.Join(filmDir, "description.txt"))
+	if string(description) != "A contemporary retelling of H.G. Wells' seminal classic." {
+		t.Errorf("description.txt = %q, want TMDB's overview", description)
+	}
+	genres, _ := os.ReadFile(filepath.Join(filmDir, "genre.txt"))
+	if string(genres) != "Horror" {
+		t.Errorf("genre.txt = %q, want the edited field left alone", genres)
+	}
+	if got := media.LoadGenres(); !equalGenres(got, []string{"Favourites"}) {
+		t.Errorf("LoadGenres() = %v, want the local edit", got)
+	}
+
+	log := strings.Join(progress.Log, "\n")
+	for _, expected := range []string{
+		`description: "An outdated overview" → "A contemporary retelling`,
+		`genres: kept local edit (TMDB now has "Science Fiction, Thriller")`,
+	} {
+		if !strings.Contains(log, expected) {
+			t.Errorf("Job log missing %q:\n%s", expected, log)
+		}
+	}
+	if strings.Contains(log, "title:") {
+		t.Errorf("Job log reports an unchanged title:\n%s", log)
+	}
+}
+
+func TestRefreshJobNoChanges(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+	tvDir := filepath.Join(testDir, "Better Call Saul [TV]")
+	os.WriteFile(filepath.Join(tvDir, "description.txt"), []byte("Six years before Saul Goodman meets Walter White.\n"), 0644)
+	os.WriteFile(filepath.Join(tvDir, "genre.txt"), []byte("Drama, Crime"), 0644)
+	info, _ := os.Stat(filepath.Join(tvDir, "genre.txt"))
+
+	media := app.findMediaBySlug("better-call-saul")
+	job, err := app.queueMediaJob(jobTypeRefresh, "Refresh metadata for "+media.Title, media)
+	if err != nil {
+		t.Fatalf("queueMediaJob() error = %v", err)
+	}
+	progress := waitForJob(t, job)
+	if progress.Status != JobSucceeded {
+		t.Fatalf("Refresh job status = %s (%s), want succeeded", progress.Status, progress.Error)
+	}
+	if len(progress.Log) != 1 || !strings.HasSuffix(progress.Log[0], "No changes on TMDB") {
+		t.Errorf("Job log = %v, want no changes", progress.Log)
+	}
+	if after, _ := os.Stat(filepath.Join(tvDir, "genre.txt")); !after.ModTime().Equal(info.ModTime()) {
+		t.Error("Unchanged genre.txt was rewritten")
+	}
+}
+
+func TestRefreshJobNewTitle(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+	filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	os.WriteFile(filepath.Join(filmDir, "title.txt"), []byte("War of the Worlds: Working Title"), 0644)
+	mediaList, _ := NewScanner(testDir).Scan()
+	app.setMediaList(mediaList)
+
+	media := app.findMediaBySlug("war-of-the-worlds-working-title-2025")
+	if media == nil {
+		t.Fatal("Media with working title not found")
+	}
+	job, err := app.queueMediaJob(jobTypeRefresh, "Refresh metadata for "+media.Title, media)
+	if err != nil {
+		t.Fatalf("queueMediaJob() error = %v", err)
+	}
+	progress := waitForJob(t, job)
+
+	// The library shows the new title, and the job links to its new slug
+	if progress.ResultURL != "/media/war-of-the-worlds-2025" {
+		t.Errorf("ResultURL = %q, want /media/war-of-the-worlds-2025", progress.ResultURL)
+	}
+	if app.findMediaBySlug("war-of-the-worlds-2025") == nil {
+		t.Error("Library title was not updated")
+	}
+}
+
+func TestQueueMetadataRefresh(t *testing.T) {
+	app, _, _ := setupAppWithMockTMDB(t)
+
+	// Only items with a TMDB ID are refreshed
+	if got := app.queueMetadataRefresh(); got != 2 {
+		t.Errorf("queueMetadataRefresh() = %d, want 2", got)
+	}
+	for _, progress := range app.jobs.List() {
+		if progress.Kind != jobTypeRefresh {
+			t.Errorf("Queued job kind = %q, want %q", progress.Kind, jobTypeRefresh)
+		}
+		job, _ := app.jobs.Get(progress.ID)
+		waitForJob(t, job)
+	}
+}
+
+func TestRefreshMetadataPreview(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+	filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	os.WriteFile(filepath.Join(filmDir, "description.txt"), []byte("A wrong description"), 0644)
+	os.WriteFile(filepath.Join(filmDir, "genre.txt"), []byte("Science Fiction, Thriller"), 0644)
+
+	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025/refresh", nil)
+	w := httptest.NewRecorder()
+	app.RefreshMetadataHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("RefreshMetadataHandler() status = %v, want %v: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, expected := range []string{
+		"A wrong description",
+		"A contemporary retelling of H.G. Wells&#39; seminal classic.",
+		`value="description" checked`,
+		`value="poster" checked`, // No poster yet
+		`value="genres">`,
+		`value="title">`,
+		"Unchanged",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Preview missing %q", expected)
+		}
+	}
+
+	// Nothing is written by the preview
+	description, _ := os.ReadFile(filepath.Join(filmDir, "description.txt"))
+	if string(description) != "A wrong description" {
+		t.Errorf("Preview changed description.txt to %q", description)
+	}
+
+	// The API returns the same comparison
+	req = httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025/refresh", nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	app.RefreshMetadataHandler(w, req)
+
+	var response struct {
+		Fields []RefreshPreview `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(response.Fields) != len(refreshFields) {
+		t.Fatalf("Preview fields = %+v, want %d", response.Fields, len(refreshFields))
+	}
+	for _, preview := range response.Fields {
+		wantChanged := preview.Field == fieldDescription || preview.Field == fieldPoster
+		if preview.Changed != wantChanged {
+			t.Errorf("%s changed = %v, want %v", preview.Field, preview.Changed, wantChanged)
+		}
+	}
+}
+
+func TestRefreshMetadataOverwritesChosenFields(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+	filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	os.WriteFile(filepath.Join(filmDir, "description.txt"), []byte("A wrong description"), 0644)
+	os.WriteFile(filepath.Join(filmDir, "genre.txt"), []byte("Horror"), 0644)
+	os.WriteFile(filepath.Join(filmDir, "poster.png"), []byte("bad-poster"), 0644)
+
+	form := url.Values{"fields": {"poster", "description"}}
+	req := httptest.NewRequest(http.MethodPost, "/media/war-of-the-worlds-2025/refresh", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	addCSRFToken(t, req)
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/media/war-of-the-worlds-2025" {
+		t.Fatalf("Refresh status = %v, location %q: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+
+	description, _ := os.ReadFile(filepath.Join(filmDir, "description.txt"))
+	if string(description) != "A contemporary retelling of H.G. Wells' seminal classic." {
+		t.Errorf("description.txt = %q, want TMDB's overview", description)
+	}
+	genres, _ := os.ReadFile(filepath.Join(filmDir, "genre.txt"))
+	if string(genres) != "Horror" {
+		t.Errorf("genre.txt = %q, want it untouched", genres)
+	}
+	if _, err := os.Stat(filepath.Join(filmDir, "poster.png")); !os.IsNotExist(err) {
+		t.Error("Old poster was not replaced")
+	}
+	poster, _ := os.ReadFile(filepath.Join(filmDir, "poster.jpg"))
+	if string(poster) != "fake-image-data" {
+		t.Errorf("poster.jpg = %q, want the TMDB poster", poster)
+	}
+}
+
+func TestRefreshMetadataClearsLocalEdits(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+	filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	if err := saveOverrides(filmDir, MetadataOverrides{Title: "My Title", Genres: []string{"Favourites"}}); err != nil {
+		t.Fatalf("saveOverrides() error = %v", err)
+	}
+	mediaList, _ := NewScanner(testDir).Scan()
+	app.setMediaList(mediaList)
+
+	form := url.Values{"fields": {"title"}}
+	req := httptest.NewRequest(http.MethodPost, "/media/my-title-2025/refresh", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	addCSRFToken(t, req)
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, req)
+
+	// TMDB's title shows, rather than the edit it was hidden behind
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/media/war-of-the-worlds-2025" {
+		t.Fatalf("Refresh status = %v, location %q: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+	overrides := loadOverrides(filmDir)
+	if overrides.Title != "" {
+		t.Errorf("Title edit %q kept after overwriting the title", overrides.Title)
+	}
+	if !equalGenres(overrides.Genres, []string{"Favourites"}) {
+		t.Errorf("Genre edit = %v, want it kept as genres weren't chosen", overrides.Genres)
+	}
+}
+
+func TestRefreshMetadataSavesCredits(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+	filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	handler := app.routes()
+
+	form := url.Values{"fields": {"description"}}
+	req := httptest.NewRequest(http.MethodPost, "/media/war-of-the-worlds-2025/refresh", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	addCSRFToken(t, req)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Refresh status = %v: %s", w.Code, w.Body.String())
+	}
+
+	// The cast and crew are saved whichever fields were chosen, so the
+	// film shows on its people's pages
+	if _, err := os.Stat(filepath.Join(filmDir, creditsFileName)); err != nil {
+		t.Fatalf("credits.json not saved: %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/people/2", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "War of the Worlds") {
+		t.Errorf("/people/2 status = %v, want it to list the film: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRefreshMetadataAPI(t *testing.T) {
+	app, _, testDir := setupAppWithMockTMDB(t)
+	filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	os.WriteFile(filepath.Join(filmDir, "title.txt"), []byte("Wrong Title"), 0644)
+	mediaList, _ := NewScanner(testDir).Scan()
+	app.setMediaList(mediaList)
+
+	form := url.Values{"fields": {"title", "genres", "title"}}
+	req := httptest.NewRequest(http.MethodPost, "/media/wrong-title-2025/refresh", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	app.RefreshMetadataHandler(w, req)
+
+	var response struct {
+		Updated []string `json:"updated"`
+		URL     string   `json:"url"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, w.Body.String())
+	}
+	if !equalGenres(response.Updated, []string{"title", "genres"}) {
+		t.Errorf("Updated = %v, want [title genres]", response.Updated)
+	}
+	if response.URL != "/media/war-of-the-worlds-2025" {
+		t.Errorf("URL = %q, want the slug of the refreshed title", response.URL)
+	}
+}
+
+func TestRefreshMetadataErrors(t *testing.T) {
+	app, _, _ := setupAppWithMockTMDB(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		fields url.Values
+		want   int
+	}{
+		{"no fields", http.MethodPost, "/media/war-of-the-worlds-2025/refresh", url.Values{}, http.StatusBadRequest},
+		{"unknown field", http.MethodPost, "/media/war-of-the-worlds-2025/refresh", url.Values{"fields": {"tmdb"}}, http.StatusBadRequest},
+		{"no TMDB ID", http.MethodGet, "/media/no-tmdb-2021/refresh", nil, http.StatusBadRequest},
+		{"missing media", http.MethodGet, "/media/missing/refresh", nil, http.StatusNotFound},
+		{"wrong method", http.MethodDelete, "/media/war-of-the-worlds-2025/refresh", nil, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.fields.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			app.RefreshMetadataHandler(w, req)
+			if w.Code != tt.want {
+				t.Errorf("RefreshMetadataHandler() status = %v, want %v", w.Code, tt.want)
+			}
+		})
+	}
+
+	app.SetTMDBClient(nil)
+	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025/refresh", nil)
+	w := httptest.NewRecorder()
+	app.RefreshMetadataHandler(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Without TMDB status = %v, want %v", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestDetailLinksRefresh(t *testing.T) {
+	app, _, _ := setupAppWithMockTMDB(t)
+
+	for slug, want := range map[string]bool{"war-of-the-worlds-2025": true, "no-tmdb-2021": false} {
+		req := httptest.NewRequest(http.MethodGet, "/media/"+slug, nil)
+		w := httptest.NewRecorder()
+		app.routes().ServeHTTP(w, req)
+		if got := strings.Contains(w.Body.String(), `href="/media/`+slug+`/refresh"`); got != want {
+			t.Errorf("Detail page of %s links to refresh = %v, want %v", slug, got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// routes registers every handler behind the role it requires and wraps them
+// in the CSRF middleware, and the authentication middleware when
+// authentication is enabled
+func (app *App) routes() http.Handler {
+	viewer := func(h http.HandlerFunc) http.Handler { return app.requireRole(RoleViewer, h) }
+	curator := func(h http.HandlerFunc) http.Handler { return app.requireRole(RoleCurator, h) }
+	admin := func(h http.HandlerFunc) http.Handler { return app.requireRole(RoleAdmin, h) }
+
+	mux := http.NewServeMux()
+	mux.Handle("/", viewer(app.IndexHandler))
+	mux.Handle("/posters/", viewer(app.PosterHandler))
+	mux.Handle("/art/", viewer(app.ArtworkHandler))
+	mux.Handle("/people/", viewer(app.PersonHandler))
+	mux.Handle("/collections", viewer(app.CollectionsHandler))
+
+	// Import routes
+	mux.Handle("/import", admin(app.ImportListHandler))
+	mux.Handle("/import/start", admin(app.ImportStartHandler))
+	mux.Handle("/import/step1", admin(app.ImportStep1Handler))
+	mux.Handle("/import/step2", admin(app.ImportStep2Handler))
+	mux.Handle("/import/step2/confirm", admin(app.ImportStep2ConfirmHandler))
+	mux.Handle("/import/step3", admin(app.ImportStep3Handler))
+	mux.Handle("/import/step4", admin(app.ImportStep4Handler))
+	mux.Handle("/import/step5", admin(app.ImportStep5Handler))
+	mux.Handle("/import/confirm", admin(app.ImportConfirmHandler))
+	mux.Handle("/import/execute", admin(app.ImportExecuteHandler))
+	mux.Handle("/import/success", admin(app.ImportSuccessHandler))
+
+	// Background job routes
+	mux.Handle("/rescan", admin(app.RescanHandler))
+	mux.Handle("/jobs", viewer(app.JobsHandler))
+	jobEvents := viewer(app.JobEventsHandler)
+	cancelJob := curator(app.CancelJobHandler)
+	job := viewer(app.JobHandler)
+	mux.HandleFunc("/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/events") {
+			jobEvents.ServeHTTP(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/cancel") {
+			cancelJob.ServeHTTP(w, r)
+		} else {
+			job.ServeHTTP(w, r)
+		}
+	})
+
+	// Bulk TMDB matching, its review queue and the TMDB response cache
+	mux.Handle("/matches", curator(app.MatchesHandler))
+	mux.Handle("/matches/run", curator(app.RunMatchHandler))
+	mux.Handle("/matches/confirm", curator(app.ConfirmMatchHandler))
+	mux.Handle("/matches/dismiss", curator(app.DismissMatchHandler))
+	mux.Handle("/tmdb/cache/clear", curator(app.ClearTMDBCacheHandler))
+
+	// Streaming, open to signed URLs so players without a session can fetch them
+	stream := viewer(app.StreamHandler)
+	mux.HandleFunc("/stream/", func(w http.ResponseWriter, r *http.Request) {
+		if app.streams == nil {
+			http.NotFound(w, r)
+		} else if app.streams.Verify(r, time.Now()) {
+			app.StreamHandler(w, r)
+		} else {
+			stream.ServeHTTP(w, r)
+		}
+	})
+
+	// Per-browser settings
+	mux.Handle("/path-profile", viewer(app.PathProfileHandler))
+	mux.Handle("/metadata-language", viewer(app.MetadataLanguageHandler))
+
+	// Remote players
+	mux.Handle("/kodi/status", curator(app.KodiStatusHandler))
+
+	// TMDB routes (must come before the general /media/ route)
+	searchTMDB := curator(app.SearchTMDBHandler)
+	confirmTMDB := curator(app.ConfirmTMDBHandler)
+	saveTMDB := curator(app.SaveTMDBHandler)
+	editMetadata := curator(app.EditMetadataHandler)
+	posterPicker := curator(app.PosterPickerHandler)
+	setPoster := curator(app.SetPosterHandler)
+	mpv := curator(app.MPVHandler)
+	kodi := curator(app.KodiHandler)
+	playlist := viewer(app.PlaylistHandler)
+	mediaJob := curator(app.MediaJobHandler)
+	refresh := curator(app.RefreshMetadataHandler)
+	detail := viewer(app.DetailHandler)
+	mux.HandleFunc("/media/", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		// Route to specific handlers based on path suffix
+		if strings.HasSuffix(path, "/search-tmdb") {
+			searchTMDB.ServeHTTP(w, r)
+		} else if strings.HasSuffix(path, "/confirm-tmdb") {
+			confirmTMDB.ServeHTTP(w, r)
+		} else if strings.HasSuffix(path, "/set-tmdb") {
+			saveTMDB.ServeHTTP(w, r)
+		} else if strings.HasSuffix(path, "/edit") {
+			editMetadata.ServeHTTP(w, r)
+		} else if strings.HasSuffix(path, "/posters") {
+			posterPicker.ServeHTTP(w, r)
+		} else if strings.HasSuffix(path, "/poster") {
+			setPoster.ServeHTTP(w, r)
+		} else if strings.HasSuffix(path, "/mpv") {
+			mpv.ServeHTTP(w, r)
+		} else if strings.HasSuffix(path, "/kodi") {
+			kodi.ServeHTTP(w, r)
+		} else if strings.HasSuffix(path, "/refresh") {
+			refresh.ServeHTTP(w, r)
+		} else if strings.HasSuffix(path, "/verify") || strings.HasSuffix(path, "/sizes") {
+			mediaJob.ServeHTTP(w, r)
+		} else if strings.HasSuffix(path, "/playlist.m3u") || strings.HasSuffix(path, "/playlist.xspf") {
+			playlist.ServeHTTP(w, r)
+		} else {
+			// Default to detail handler
+			detail.ServeHTTP(w, r)
+		}
+	})
+
+	// Public routes: static files (CSS, etc.) and signing in and out
+	mux.Handle("/static/", staticAssets)
+	mux.HandleFunc("/login", app.LoginHandler)
+	mux.HandleFunc("/logout", app.LogoutHandler)
+
+	var handler http.Handler = mux
+	if app.auth != nil {
+		handler = app.auth.Middleware(handler)
+	}
+	return csrfMiddleware(handler)
+}
+
+// requireRole wraps h so it's only reachable by users with the given role.
+// Every user has every role when authentication is disabled.
+func (app *App) requireRole(role Role, h http.HandlerFunc) http.Handler {
+	if app.auth == nil {
+		return h
+	}
+	return app.auth.Require(role, h)
+}
+
+// userCan reports whether the request's user has the permissions of role
+func (app *App) userCan(r *http.Request, role Role) bool {
+	if app.auth == nil {
+		return true
+	}
+	return app.auth.Allows(r, role)
+}
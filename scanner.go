@@ -23,14 +23,23 @@ var (
 type Scanner struct {
 	mediaDir   string
 	tmdbClient *TMDBClient
+	progress   ScanProgressFunc
+	deferSizes bool
+	unsized    []string // Media directories with disks of unknown size
 }
 
+// ScanProgressFunc reports how many directories have been scanned out of the total
+type ScanProgressFunc func(done, total int, current string)
+
 // NewScanner creates a new Scanner for the given directory
 func NewScanner(mediaDir string) *Scanner {
 	return &Scanner{mediaDir: mediaDir}
 }
 
 // NewScannerWithTMDB creates a new Scanner with TMDB client for poster fetching
+//
+// Deprecated: the scanner no longer fetches metadata itself; queue metadata
+// jobs for the media it finds instead.
 func NewScannerWithTMDB(mediaDir string, tmdbClient *TMDBClient) *Scanner {
 	return &Scanner{
 		mediaDir:   mediaDir,
@@ -38,6 +47,30 @@ func NewScannerWithTMDB(mediaDir string, tmdbClient *TMDBClient) *Scanner {
 	}
 }
 
+// SetProgressFunc sets a function called after each directory is scanned
+func (s *Scanner) SetProgressFunc(progress ScanProgressFunc) {
+	s.progress = progress
+}
+
+// SetDeferSizes makes Scan skip calculating the size of disks missing from
+// the size cache, so a large new disk doesn't hold up the scan. Their media
+// directories are listed by Unsized.
+func (s *Scanner) SetDeferSizes(deferSizes bool) {
+	s.deferSizes = deferSizes
+}
+
+// Unsized returns the media directories whose disk sizes were skipped by the last scan
+func (s *Scanner) Unsized() []string {
+	return s.unsized
+}
+
+// markUnsized records that a media directory has disks of unknown size
+func (s *Scanner) markUnsized(dirPath string) {
+	if n := len(s.unsized); n == 0 || s.unsized[n-1] != dirPath {
+		s.unsized = append(s.unsized, dirPath)
+	}
+}
+
 // Scan scans the configured directory and returns a slice of Media items
 func (s *Scanner) Scan() ([]Media, error) {
 	// Verify directory exists and is readable
@@ -59,6 +92,16 @@ func (s *Scanner) Scan() ([]Media, error) {
 	}
 
 	var mediaList []Media
+	s.unsized = nil
+
+	// Count directories up front so progress has a total
+	total := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			total++
+		}
+	}
+	done := 0
 
 	for _, entry := range entries {
 		if !entry.IsDir() {
@@ -68,6 +111,11 @@ func (s *Scanner) Scan() ([]Media, error) {
 		dirName := entry.Name()
 		dirPath := filepath.Join(s.mediaDir, dirName)
 
+		done++
+		if s.progress != nil {
+			s.progress(done, total, dirName)
+		}
+
 		// Try to parse as film
 		if media, ok := s.parseFilm(dirName, dirPath); ok {
 			mediaList = append(mediaList, media)
@@ -115,11 +163,9 @@ func (s *Scanner) parseFilm(dirName, dirPath string) (Media, bool) {
 		media.Title = officialTitle
 	}
 
-	// Fetch metadata if TMDB client is configured
-	if s.tmdbClient != nil && media.TMDBID != "" {
-		if err := s.tmdbClient.FetchAndSaveMetadata(&media); err != nil {
-			log.Printf("Warning: Failed to fetch metadata for %s: %v", media.Title, err)
-		}
+	// A title edited in the browser takes precedence over everything else
+	if override := loadOverrides(dirPath).Title; override != "" {
+		media.Title = override
 	}
 
 	return media, true
@@ -153,11 +199,9 @@ func (s *Scanner) parseTV(dirName, dirPath string) (Media, bool) {
 		media.Title = officialTitle
 	}
 
-	// Fetch metadata if TMDB client is configured
-	if s.tmdbClient != nil && media.TMDBID != "" {
-		if err := s.tmdbClient.FetchAndSaveMetadata(&media); err != nil {
-			log.Printf("Warning: Failed to fetch metadata for %s: %v", media.Title, err)
-		}
+	// A title edited in the browser takes precedence over everything else
+	if override := loadOverrides(dirPath).Title; override != "" {
+		media.Title = override
 	}
 
 	return media, true
@@ -268,6 +312,9 @@ func (s *Scanner) collectFilmDisks(dirPath string) []Disk {
 			diskDirName := entry.Name()
 			if cachedSize, exists := cache[diskDirName]; exists {
 				size = cachedSize
+			} else if s.deferSizes {
+				// Leave the size unknown for a size job to fill in
+				s.markUnsized(dirPath)
 			} else {
 				// Calculate size and update cache
 				var err error
@@ -348,6 +395,9 @@ func (s *Scanner) collectTVDisks(dirPath string) []Disk {
 			diskDirName := entry.Name()
 			if cachedSize, exists := cache[diskDirName]; exists {
 				size = cachedSize
+			} else if s.deferSizes {
+				// Leave the size unknown for a size job to fill in
+				s.markUnsized(dirPath)
 			} else {
 				// Calculate size and update cache
 				var err error
@@ -360,11 +410,14 @@ func (s *Scanner) collectTVDisks(dirPath string) []Disk {
 
 			sizeGB := float64(size) / (1024 * 1024 * 1024) // Convert bytes to GB
 
+			series, _ := strconv.Atoi(seriesNum) // We know it's numeric from regex
+
 			disks = append(disks, Disk{
 				Name:   fmt.Sprintf("Series %s Disk %s", seriesNum, diskNum),
 				Format: format,
 				SizeGB: sizeGB,
 				Path:   diskPath,
+				Series: series,
 			})
 		}
 	}
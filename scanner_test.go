@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -517,6 +518,9 @@ func TestCollectTVDisks(t *testing.T) {
 	if disks[0].SizeGB < 0 {
 		t.Errorf("Disk[0] SizeGB = %v, should be >= 0", disks[0].SizeGB)
 	}
+	if disks[0].Series != 1 {
+		t.Errorf("Disk[0] Series = %d, want 1", disks[0].Series)
+	}
 
 	// Second disk
 	if disks[1].Name != "Series 1 Disk 2" {
@@ -600,6 +604,31 @@ func TestSizeCacheMiss(t *testing.T) {
 	}
 }
 
+// Test deferred sizes - uncached disks are left for a size job
+func TestSizeCacheDeferred(t *testing.T) {
+	testDir := setupTestData(t)
+	filmPath := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	saveSizeCache(filepath.Join(testDir, "Better Call Saul [TV]"), map[string]int64{
+		"Series 1 Disk 1 [Blu-Ray]":     1024,
+		"Series 1 Disk 2 [Blu-Ray UHD]": 2048,
+	})
+
+	scanner := NewScanner(testDir)
+	scanner.SetDeferSizes(true)
+	if _, err := scanner.Scan(); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	// Fully cached media isn't listed
+	want := []string{filepath.Join(testDir, "No TMDB (2021) [Film]"), filmPath}
+	if got := scanner.Unsized(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unsized() = %v, want %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(filmPath, "sizes.json")); !os.IsNotExist(err) {
+		t.Error("Cache file was written for a deferred size")
+	}
+}
+
 // Test cache hit scenario - sizes loaded from existing valid cache
 func TestSizeCacheHit(t *testing.T) {
 	testDir := setupTestData(t)
@@ -0,0 +1,125 @@
+// Live progress for background jobs.
+//
+// Forms marked with data-job="#selector" are submitted in the background and
+// their progress is shown in the element matching the selector. Elements with
+// data-job-events="/jobs/{id}/events" show progress for an existing job.
+// Without JavaScript the forms post normally and land on the job's progress page.
+(function () {
+    'use strict';
+
+    function formatBytes(bytes) {
+        var units = ['B', 'KB', 'MB', 'GB', 'TB'];
+        var i = 0;
+        while (bytes >= 1024 && i < units.length - 1) {
+            bytes /= 1024;
+            i++;
+        }
+        return bytes.toFixed(i === 0 ? 0 : 1) + ' ' + units[i];
+    }
+
+    function render(container, job) {
+        container.hidden = false;
+        container.querySelector('.job-step').textContent = job.step || '';
+
+        var bar = container.querySelector('.job-bar');
+        var detail = '';
+        if (job.bytes_total > 0) {
+            bar.max = job.bytes_total;
+            bar.value = job.bytes_done || 0;
+            detail = formatBytes(job.bytes_done || 0) + ' of ' + formatBytes(job.bytes_total) + ' moved';
+        } else if (job.items_total > 0) {
+            bar.max = job.items_total;
+            bar.value = job.items_done || 0;
+            detail = (job.items_done || 0) + ' of ' + job.items_total + ' items';
+        } else if (job.status === 'running' || job.status === 'queued') {
+            bar.removeAttribute('value'); // Indeterminate
+        } else {
+            bar.max = 1;
+            bar.value = 1;
+        }
+        if (job.max_attempts > 1) {
+            detail = 'Attempt ' + job.attempt + ' of ' + job.max_attempts + '. ' + detail;
+        }
+        container.querySelector('.job-detail').textContent = detail;
+
+        var error = container.querySelector('.job-error');
+        error.textContent = job.error || '';
+        error.hidden = !job.error;
+    }
+
+    function ensureMarkup(container) {
+        if (container.querySelector('.job-step')) {
+            return;
+        }
+        container.innerHTML =
+            '<div class="job-step"></div>' +
+            '<progress class="job-bar"></progress>' +
+            '<div class="job-detail"></div>' +
+            '<div class="job-error" hidden></div>';
+    }
+
+    function watch(eventsURL, container, onFailed) {
+        ensureMarkup(container);
+        var source = new EventSource(eventsURL);
+
+        source.addEventListener('progress', function (e) {
+            render(container, JSON.parse(e.data));
+        });
+
+        source.addEventListener('done', function (e) {
+            source.close();
+            var job = JSON.parse(e.data);
+            render(container, job);
+            if (job.status === 'succeeded' && job.result_url) {
+                window.location.href = job.result_url;
+            } else if ((job.status === 'failed' || job.status === 'canceled') && onFailed) {
+                onFailed(job);
+            }
+        });
+    }
+
+    document.querySelectorAll('form[data-job]').forEach(function (form) {
+        form.addEventListener('submit', function (e) {
+            var container = document.querySelector(form.dataset.job);
+            if (!container || !window.EventSource) {
+                return; // Fall back to a normal form post
+            }
+            e.preventDefault();
+
+            var buttons = form.querySelectorAll('button');
+            buttons.forEach(function (b) { b.disabled = true; });
+
+            fetch(form.action, {
+                method: 'POST',
+                body: new URLSearchParams(new FormData(form)),
+                headers: { 'Accept': 'application/json' },
+                credentials: 'same-origin'
+            }).then(function (response) {
+                if (response.status === 202) {
+                    return response.json();
+                }
+                if (response.ok && response.redirected) {
+                    // Finished without starting a job
+                    window.location.href = response.url;
+                    return null;
+                }
+                return response.text().then(function (text) { throw new Error(text.trim()); });
+            }).then(function (job) {
+                if (!job) {
+                    return;
+                }
+                watch(job.events_url, container, function () {
+                    buttons.forEach(function (b) { b.disabled = false; });
+                });
+            }).catch(function (err) {
+                ensureMarkup(container);
+                render(container, { status: 'failed', step: 'Could not start', error: err.message });
+                buttons.forEach(function (b) { b.disabled = false; });
+            });
+        });
+    });
+
+    document.querySelectorAll('[data-job-events]').forEach(function (container) {
+        watch(container.dataset.jobEvents, container);
+    });
+})();
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultStreamURLLifetime is how long a signed stream URL works for
+const defaultStreamURLLifetime = 6 * time.Hour
+
+// videoContentTypes maps the video file extensions that can be streamed to
+// their content types. Go's built-in MIME table doesn't cover most of them.
+var videoContentTypes = map[string]string{
+	".mkv":  "video/x-matroska",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".avi":  "video/x-msvideo",
+	".ts":   "video/mp2t",
+	".m2ts": "video/mp2t",
+	".mpg":  "video/mpeg",
+	".mpeg": "video/mpeg",
+}
+
+// isVideoFile reports whether a file name has a streamable video extension
+func isVideoFile(name string) bool {
+	_, ok := videoContentTypes[strings.ToLower(filepath.Ext(name))]
+	return ok
+}
+
+// VideoFiles returns the video files of a file-based disk as slash-separated
+// paths relative to the disk directory, sorted by name. Blu-ray and DVD
+// backups have none, since they're played as a whole disc.
+func (d *Disk) VideoFiles() []string {
+	if d.FormatKind() != FormatFile {
+		return nil
+	}
+
+	var files []string
+	filepath.WalkDir(d.Path, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() || !isVideoFile(entry.Name()) {
+			return nil
+		}
+		if rel, err := filepath.Rel(d.Path, p); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+// StreamSigner signs stream URLs so players without a session can fetch them
+// until they expire
+type StreamSigner struct {
+	secret   []byte
+	lifetime time.Duration
+}
+
+// NewStreamSigner creates a signer. A random secret is generated when secret
+// is empty, so signed URLs stop working when the server restarts.
+func NewStreamSigner(secret []byte, lifetime time.Duration) (*StreamSigner, error) {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate stream secret: %w", err)
+		}
+	}
+	if lifetime <= 0 {
+		lifetime = defaultStreamURLLifetime
+	}
+	return &StreamSigner{secret: secret, lifetime: lifetime}, nil
+}
+
+// signature returns the signature of a URL path expiring at expires
+func (s *StreamSigner) signature(urlPath string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%d", urlPath, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Sign returns urlPath with an expiry time and signature added
+func (s *StreamSigner) Sign(urlPath string, now time.Time) string {
+	expires := now.Add(s.lifetime).Unix()
+	query := url.Values{
+		"expires": {strconv.FormatInt(expires, 10)},
+		"sig":     {s.signature(urlPath, expires)},
+	}
+	return urlPath + "?" + query.Encode()
+}
+
+// Verify reports whether a request's URL carries a valid, unexpired signature
+func (s *StreamSigner) Verify(r *http.Request, now time.Time) bool {
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || now.Unix() > expires {
+		return false
+	}
+	expected := s.signature(r.URL.EscapedPath(), expires)
+	return hmac.Equal([]byte(expected), []byte(query.Get("sig")))
+}
+
+// streamPath returns the URL path that streams a disk's file. The path is the
+// file's place in the media directory rather than the slug and disk number,
+// so editing a title or adding a disk doesn't break URLs already handed to
+// players. Returns "" for disks outside the media directory.
+func (app *App) streamPath(disk *Disk, file string) string {
+	rel, err := filepath.Rel(app.mediaDir, filepath.Join(disk.Path, filepath.FromSlash(file)))
+	if err != nil || !filepath.IsLocal(rel) {
+		return ""
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/stream/" + strings.Join(segments, "/")
+}
+
+// streamDisk returns the file-based disk that filePath is in, or nil
+func (app *App) streamDisk(filePath string) *Disk {
+	for _, media := range app.allMedia() {
+		for i := range media.Disks {
+			disk := &media.Disks[i]
+			if disk.FormatKind() == FormatFile && pathWithin(disk.Path, filePath) {
+				return disk
+			}
+		}
+	}
+	return nil
+}
+
+// pathWithin reports whether target is inside the directory root
+func pathWithin(root, target string) bool {
+	rel, err := filepath.Rel(root, target)
+	return err == nil && rel != "." && filepath.IsLocal(rel)
+}
+
+// StreamFile is a file of a disk that can be streamed
+type StreamFile struct {
+	Name string // Path relative to the disk directory
+	URL  string // Absolute signed URL
+}
+
+// streamFiles returns signed stream URLs for every file-based disk of media,
+// indexed like media.Disks
+func (app *App) streamFiles(r *http.Request, media *Media) [][]StreamFile {
+	if app.streams == nil {
+		return nil
+	}
+
+	now := time.Now()
+	files := make([][]StreamFile, len(media.Disks))
+	for i := range media.Disks {
+		for _, name := range media.Disks[i].VideoFiles() {
+			urlPath := app.streamPath(&media.Disks[i], name)
+			if urlPath == "" {
+				continue
+			}
+			signed := app.streams.Sign(urlPath, now)
+			files[i] = append(files[i], StreamFile{Name: name, URL: absoluteURL(r, signed)})
+		}
+	}
+	return files
+}
+
+// absoluteURL turns a path into a URL on the host the request was made to,
+// so it can be pasted into another device
+func absoluteURL(r *http.Request, urlPath string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + urlPath
+}
+
+// StreamHandler streams a file of a file-based disk, with Range support so
+// players can seek: /stream/{path in the media directory}
+func (app *App) StreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only video files inside a file-based disk can be streamed
+	file := path.Clean("/" + strings.TrimPrefix(r.URL.Path, "/stream/"))[1:]
+	contentType, ok := videoContentTypes[strings.ToLower(path.Ext(file))]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	filePath := filepath.Join(app.mediaDir, filepath.FromSlash(file))
+	if app.streamDisk(filePath) == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Symlinks may not lead out of the media directory
+	resolved, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	root, err := filepath.EvalSymlinks(app.mediaDir)
+	if err != nil || !pathWithin(root, resolved) {
+		log.Printf("Security warning: refused to stream %s, which resolves outside the media dir", filePath)
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(resolved)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, no-transform")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newStreamTestApp returns an app whose film has an MKV disk alongside its
+// Blu-ray, and that disk's index
+func newStreamTestApp(t *testing.T) (*App, int) {
+	t.Helper()
+
+	app, _, _ := setupAppWithMockTMDB(t, func(testDir string) {
+		diskDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]", "Disk [MKV]")
+		if err := os.MkdirAll(filepath.Join(diskDir, "Extras"), 0755); err != nil {
+			t.Fatalf("Failed to create MKV disk: %v", err)
+		}
+		os.WriteFile(filepath.Join(diskDir, "Feature.mkv"), []byte("0123456789"), 0644)
+		os.WriteFile(filepath.Join(diskDir, "Extras", "Trailer 1.mp4"), []byte("trailer"), 0644)
+		os.WriteFile(filepath.Join(diskDir, "notes.txt"), []byte("not a video"), 0644)
+	})
+	signer, err := NewStreamSigner([]byte("test secret"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewStreamSigner() error = %v", err)
+	}
+	app.SetStreamSigner(signer)
+
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+	for i := range media.Disks {
+		if media.Disks[i].FormatKind() == FormatFile {
+			return app, i
+		}
+	}
+	t.Fatal("MKV disk not found")
+	return nil, 0
+}
+
+func TestDiskVideoFiles(t *testing.T) {
+	app, index := newStreamTestApp(t)
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+
+	want := []string{"Extras/Trailer 1.mp4", "Feature.mkv"}
+	if got := media.Disks[index].VideoFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("VideoFiles() = %v, want %v", got, want)
+	}
+	if got := media.Disks[1-index].VideoFiles(); got != nil {
+		t.Errorf("VideoFiles() of a Blu-ray = %v, want none", got)
+	}
+}
+
+func TestStreamSigner(t *testing.T) {
+	signer, _ := NewStreamSigner([]byte("secret"), time.Hour)
+	now := time.Now()
+	signed := signer.Sign("/stream/film/0/Feature.mkv", now)
+
+	tests := []struct {
+		name string
+		url  string
+		now  time.Time
+		want bool
+	}{
+		{"valid", signed, now, true},
+		{"expired", signed, now.Add(2 * time.Hour), false},
+		{"other file", strings.Replace(signed, "Feature", "Other", 1), now, false},
+		{"tampered expiry", strings.Replace(signed, "expires=", "expires=9", 1), now, false},
+		{"unsigned", "/stream/film/0/Feature.mkv", now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if got := signer.Verify(req, tt.now); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Another key doesn't accept the URL
+	other, _ := NewStreamSigner(nil, time.Hour)
+	if other.Verify(httptest.NewRequest(http.MethodGet, signed, nil), now) {
+		t.Error("Verify() accepted a URL signed with another key")
+	}
+}
+
+func TestStreamHandler(t *testing.T) {
+	app, index := newStreamTestApp(t)
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+
+	get := func(path, rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		app.StreamHandler(w, req)
+		return w
+	}
+
+	// Whole file
+	w := get(app.streamPath(&media.Disks[index], "Feature.mkv"), "")
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Fatalf("StreamHandler() = %v %q, want whole file", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "video/x-matroska" {
+		t.Errorf("Content-Type = %q, want video/x-matroska", got)
+	}
+	if got := w.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("Accept-Ranges = %q, want bytes", got)
+	}
+
+	// Range requests let players seek
+	w = get(app.streamPath(&media.Disks[index], "Feature.mkv"), "bytes=2-5")
+	if w.Code != http.StatusPartialContent || w.Body.String() != "2345" {
+		t.Errorf("StreamHandler() range = %v %q, want 206 \"2345\"", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 2-5/10" {
+		t.Errorf("Content-Range = %q, want bytes 2-5/10", got)
+	}
+
+	// Files in subdirectories with escaped names
+	w = get(app.streamPath(&media.Disks[index], "Extras/Trailer 1.mp4"), "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "video/mp4" {
+		t.Errorf("StreamHandler() subdirectory = %v %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// Only video files inside file-based disks
+	for _, path := range []string{
+		app.streamPath(&media.Disks[index], "notes.txt"),
+		app.streamPath(&media.Disks[index], "Missing.mkv"),
+		"/stream/../../etc/Secret.mkv",
+		"/stream/%2e%2e/%2e%2e/Secret.mkv",
+		app.streamPath(&media.Disks[1-index], "Feature.mkv"),
+		"/stream/Missing%20%282020%29%20%5BFilm%5D/Disk%20%5BMKV%5D/Feature.mkv",
+		"/stream/War%20of%20the%20Worlds%20%282025%29%20%5BFilm%5D/Feature.mkv",
+	} {
+		if w := get(path, ""); w.Code != http.StatusNotFound {
+			t.Errorf("StreamHandler(%q) status = %v, want %v", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestStreamPath(t *testing.T) {
+	app, index := newStreamTestApp(t)
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+
+	want := "/stream/War%20of%20the%20Worlds%20%282025%29%20%5BFilm%5D/Disk%20%5BMKV%5D/Extras/Trailer%201.mp4"
+	if got := app.streamPath(&media.Disks[index], "Extras/Trailer 1.mp4"); got != want {
+		t.Errorf("streamPath() = %q, want %q", got, want)
+	}
+	if got := app.streamPath(&media.Disks[index], "../../../Secret.mkv"); got != "" {
+		t.Errorf("streamPath() outside the media dir = %q, want none", got)
+	}
+}
+
+func TestStreamHandlerRefusesSymlinksOutOfMediaDir(t *testing.T) {
+	app, index := newStreamTestApp(t)
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+	diskDir := media.Disks[index].Path
+
+	outside := filepath.Join(t.TempDir(), "Secret.mkv")
+	os.WriteFile(outside, []byte("secret"), 0644)
+	if err := os.Symlink(outside, filepath.Join(diskDir, "Escape.mkv")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	os.Symlink(filepath.Dir(outside), filepath.Join(diskDir, "Elsewhere"))
+	os.Symlink("Feature.mkv", filepath.Join(diskDir, "Alias.mkv"))
+
+	for file, want := range map[string]int{
+		"Escape.mkv":           http.StatusNotFound,
+		"Elsewhere/Secret.mkv": http.StatusNotFound,
+		"Alias.mkv":            http.StatusOK, // Links within the library are fine
+	} {
+		req := httptest.NewRequest(http.MethodGet, app.streamPath(&media.Disks[index], file), nil)
+		w := httptest.NewRecorder()
+		app.StreamHandler(w, req)
+		if w.Code != want {
+			t.Errorf("StreamHandler(%s) status = %v, want %v", file, w.Code, want)
+		}
+	}
+}
+
+func TestStreamURLSurvivesTitleEdit(t *testing.T) {
+	app, index := newStreamTestApp(t)
+	handler := app.routes()
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+	signed := app.streams.Sign(app.streamPath(&media.Disks[index], "Feature.mkv"), time.Now())
+
+	if err := saveOverrides(media.Path, MetadataOverrides{Title: "Another Title"}); err != nil {
+		t.Fatalf("saveOverrides() error = %v", err)
+	}
+	app.refreshMediaTitle(media.Path)
+	if app.findMediaBySlug("another-title-2025") == nil {
+		t.Fatal("Title edit didn't change the slug")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, signed, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Errorf("Stream URL after a title edit = %v %q, want the file", w.Code, w.Body.String())
+	}
+}
+
+func TestStreamRouteAuth(t *testing.T) {
+	app, index := newStreamTestApp(t)
+	users, _ := LoadUserStore(writeUsersFile(t, map[string]string{"sam": "secret"}))
+	auth, err := NewAuthenticator(AuthConfig{Users: users})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+	app.SetAuthenticator(auth)
+	handler := app.routes()
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+	path := app.streamPath(&media.Disks[index], "Feature.mkv")
+
+	// Without a session, only signed URLs work
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther || !strings.HasPrefix(w.Header().Get("Location"), "/login") {
+		t.Errorf("Unsigned stream = %v %q, want redirect to login", w.Code, w.Header().Get("Location"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, app.streams.Sign(path, time.Now()), nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Errorf("Signed stream = %v %q, want file", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, app.streams.Sign(path, time.Now().Add(-2*time.Hour)), nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Expired stream status = %v, want %v", w.Code, http.StatusSeeOther)
+	}
+}
+
+func TestDetailShowsStreamURLs(t *testing.T) {
+	app, _ := newStreamTestApp(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025", nil)
+	req.Host = "shelf.example"
+	w := httptest.NewRecorder()
+	app.DetailHandler(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Copy Stream URL") || !strings.Contains(body, "http:\\/\\/shelf.example\\/stream\\/War%20of%20the%20Worlds%20%282025%29%20%5BFilm%5D\\/Disk%20%5BMKV%5D\\/") {
+		t.Error("Detail page does not offer signed stream URLs")
+	}
+	if !strings.Contains(body, "Feature.mkv") {
+		t.Error("Detail page does not name the streamable files")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // Register PNG decoder
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register WebP decoder
+)
+
+// thumbnailWidths lists the thumbnail widths that can be requested.
+// Requests are snapped to one of these so the cache stays bounded.
+var thumbnailWidths = []int{150, 300, 600, 1280}
+
+// thumbnailQuality is the JPEG quality used for generated thumbnails
+const thumbnailQuality = 85
+
+// maxThumbnailSourcePixels refuses to decode images larger than this, so a
+// small file claiming huge dimensions can't exhaust memory. It allows an
+// 8K image with room to spare.
+const maxThumbnailSourcePixels = 50_000_000
+
+// ThumbnailCache generates resized copies of artwork images and caches them on disk.
+// Cached files are keyed by source path, width and source modification time, so a
+// replaced poster produces a new thumbnail rather than serving a stale one.
+type ThumbnailCache struct {
+	dir string
+}
+
+// NewThumbnailCache creates a thumbnail cache in the given directory
+func NewThumbnailCache(dir string) (*ThumbnailCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail cache directory: %w", err)
+	}
+	return &ThumbnailCache{dir: dir}, nil
+}
+
+// defaultThumbnailCacheDir returns the default thumbnail cache location
+func defaultThumbnailCacheDir() string {
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cacheDir, "shelf", "thumbnails")
+	}
+	return filepath.Join(os.TempDir(), "shelf-thumbnails")
+}
+
+// snapThumbnailWidth returns the smallest supported width that is at least the
+// requested width, or the largest supported width
+func snapThumbnailWidth(width int) int {
+	for _, candidate := range thumbnailWidths {
+		if candidate >= width {
+			return candidate
+		}
+	}
+	return thumbnailWidths[len(thumbnailWidths)-1]
+}
+
+// Thumbnail returns the path of a thumbnail of sourcePath at the given width,
+// generating and caching it if needed. Images that are already narrower than
+// the requested width are returned unchanged.
+func (c *ThumbnailCache) Thumbnail(sourcePath string, width int) (string, error) {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat source image: %w", err)
+	}
+
+	prefix := c.keyPrefix(sourcePath, width)
+	cachePath := filepath.Join(c.dir, fmt.Sprintf("%s-%d.jpg", prefix, info.ModTime().UnixNano()))
+
+	// Serve from cache if this version of the source was already resized
+	if _, err := os.Stat(cachePath); err == nil {
+		return cachePath, nil
+	}
+
+	src, err := os.Open(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open source image: %w", err)
+	}
+	defer src.Close()
+
+	// Check dimensions first so small images are never fully decoded, and
+	// oversized ones are never decoded at all
+	config, _, err := image.DecodeConfig(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to read source image: %w", err)
+	}
+	if int64(config.Width)*int64(config.Height) > maxThumbnailSourcePixels {
+		return "", fmt.Errorf("source image is too large to resize (%d×%d)", config.Width, config.Height)
+	}
+	if config.Width <= width {
+		return sourcePath, nil
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind source image: %w", err)
+	}
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode source image: %w", err)
+	}
+
+	bounds := img.Bounds()
+
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	if err := writeFileAtomic(cachePath, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+
+	c.removeStale(prefix, cachePath)
+	return cachePath, nil
+}
+
+// keyPrefix identifies the thumbnails of one source image at one width
+func (c *ThumbnailCache) keyPrefix(sourcePath string, width int) string {
+	sum := sha256.Sum256([]byte(sourcePath))
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:12]), width)
+}
+
+// removeStale deletes thumbnails generated from older versions of a source image
+func (c *ThumbnailCache) removeStale(prefix, current string) {
+	matches, err := filepath.Glob(filepath.Join(c.dir, prefix+"-*.jpg"))
+	if err != nil {
+		return
+	}
+	for _, match := range matches {
+		if match == current {
+			continue
+		}
+		if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to remove stale thumbnail %s: %v", match, err)
+		}
+	}
+}
+
+// artworkETag builds an entity tag from a file's identity and modification time
+func artworkETag(path string, modTime time.Time, size int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d", path, modTime.UnixNano(), size)))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// artworkVersion returns a short version token for cache-busting artwork URLs
+func artworkVersion(modTime time.Time) string {
+	return strconv.FormatInt(modTime.UnixNano(), 36)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestImage writes a PNG of the given dimensions to path
+func writeTestImage(t *testing.T, path string, width, height int) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write test image: %v", err)
+	}
+}
+
+func TestSnapThumbnailWidth(t *testing.T) {
+	tests := []struct {
+		width int
+		want  int
+	}{
+		{1, 150},
+		{150, 150},
+		{151, 300},
+		{600, 600},
+		{5000, 1280},
+	}
+
+	for _, tt := range tests {
+		if got := snapThumbnailWidth(tt.width); got != tt.want {
+			t.Errorf("snapThumbnailWidth(%d) = %d, want %d", tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestThumbnailResizesAndCaches(t *testing.T) {
+	srcDir := t.TempDir()
+	cache, err := NewThumbnailCache(filepath.Join(t.TempDir(), "thumbs"))
+	if err != nil {
+		t.Fatalf("NewThumbnailCache() error = %v", err)
+	}
+
+	source := filepath.Join(srcDir, "poster.png")
+	writeTestImage(t, source, 1000, 1500)
+
+	thumbPath, err := cache.Thumbnail(source, 300)
+	if err != nil {
+		t.Fatalf("Thumbnail() error = %v", err)
+	}
+
+	f, err := os.Open(thumbPath)
+	if err != nil {
+		t.Fatalf("Failed to open thumbnail: %v", err)
+	}
+	config, err := jpeg.DecodeConfig(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("Thumbnail is not a JPEG: %v", err)
+	}
+	if config.Width != 300 || config.Height != 450 {
+		t.Errorf("Thumbnail size = %dx%d, want 300x450", config.Width, config.Height)
+	}
+
+	// A second request is served from the cache
+	cachedPath, err := cache.Thumbnail(source, 300)
+	if err != nil || cachedPath != thumbPath {
+		t.Errorf("Thumbnail() second call = %q, %v, want cached %q", cachedPath, err, thumbPath)
+	}
+
+	// Replacing the source produces a new thumbnail and removes the stale one
+	writeTestImage(t, source, 600, 600)
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(source, future, future)
+
+	newPath, err := cache.Thumbnail(source, 300)
+	if err != nil {
+		t.Fatalf("Thumbnail() after change error = %v", err)
+	}
+	if newPath == thumbPath {
+		t.Error("Thumbnail() returned the stale cached path after the source changed")
+	}
+	if _, err := os.Stat(thumbPath); !os.IsNotExist(err) {
+		t.Error("Stale thumbnail was not removed")
+	}
+}
+
+func TestThumbnailSmallSourceServedUnchanged(t *testing.T) {
+	srcDir := t.TempDir()
+	cache, err := NewThumbnailCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewThumbnailCache() error = %v", err)
+	}
+
+	source := filepath.Join(srcDir, "poster.png")
+	writeTestImage(t, source, 100, 150)
+
+	got, err := cache.Thumbnail(source, 300)
+	if err != nil {
+		t.Fatalf("Thumbnail() error = %v", err)
+	}
+	if got != source {
+		t.Errorf("Thumbnail() = %q, want original %q", got, source)
+	}
+}
+
+func TestThumbnailInvalidImage(t *testing.T) {
+	srcDir := t.TempDir()
+	cache, _ := NewThumbnailCache(t.TempDir())
+
+	source := filepath.Join(srcDir, "poster.jpg")
+	os.WriteFile(source, []byte("not an image"), 0644)
+
+	if _, err := cache.Thumbnail(source, 300); err == nil {
+		t.Error("Thumbnail() expected error for invalid image")
+	}
+}
+
+func TestThumbnailRefusesHugeImage(t *testing.T) {
+	cache, _ := NewThumbnailCache(t.TempDir())
+
+	// A PNG header claiming 20000×20000 pixels, which would take 1.6 GB to decode
+	ihdr := []byte("IHDR")
+	ihdr = binary.BigEndian.AppendUint32(ihdr, 20000)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, 20000)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0) // 8-bit RGBA
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = binary.BigEndian.AppendUint32(data, uint32(len(ihdr)-4))
+	data = append(data, ihdr...)
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(ihdr))
+
+	source := filepath.Join(t.TempDir(), "poster.png")
+	os.WriteFile(source, data, 0644)
+
+	if _, err := cache.Thumbnail(source, 300); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("Thumbnail() error = %v, want the image refused as too large", err)
+	}
+}
+
+func TestArtworkHandlerThumbnails(t *testing.T) {
+	testDir := setupTestData(t)
+	filmDir := filepath.Join(testDir, "War of the Worlds (2025) [Film]")
+	writeTestImage(t, filepath.Join(filmDir, "poster.png"), 1000, 1500)
+
+	mediaList, _ := NewScanner(testDir).Scan()
+	app := NewApp(mediaList, nil, testDir, "")
+	cache, err := NewThumbnailCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewThumbnailCache() error = %v", err)
+	}
+	app.SetThumbnailCache(cache)
+
+	media := app.findMediaBySlug("war-of-the-worlds-2025")
+	thumbURL := media.PosterThumbnailURL(300)
+	if !strings.Contains(thumbURL, "?w=300&v=") {
+		t.Fatalf("PosterThumbnailURL() = %q, want width and version", thumbURL)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, thumbURL, nil)
+	w := httptest.NewRecorder()
+	app.ArtworkHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ArtworkHandler() status = %v, want %v", w.Code, http.StatusOK)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("Content-Type = %q, want image/jpeg", ct)
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("Cache-Control = %q, want immutable for versioned URL", cc)
+	}
+	config, err := jpeg.DecodeConfig(w.Body)
+	if err != nil || config.Width != 300 {
+		t.Errorf("Thumbnail width = %d (err %v), want 300", config.Width, err)
+	}
+
+	// Conditional requests are answered without a body
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ArtworkHandler() did not set an ETag")
+	}
+	req = httptest.NewRequest(http.MethodGet, "/art/war-of-the-worlds-2025/poster?w=300", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	app.ArtworkHandler(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("ArtworkHandler() conditional status = %v, want %v", w.Code, http.StatusNotModified)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache for unversioned URL", cc)
+	}
+
+	// Behind authentication shared caches mustn't keep a copy
+	app.SetAuthenticator(&Authenticator{sessions: NewSessionStore()})
+	req = httptest.NewRequest(http.MethodGet, thumbURL, nil)
+	w = httptest.NewRecorder()
+	app.ArtworkHandler(w, req)
+	if cc := w.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "private,") {
+		t.Errorf("Cache-Control = %q, want private with authentication", cc)
+	}
+
+	// Invalid widths are rejected
+	req = httptest.NewRequest(http.MethodGet, "/art/war-of-the-worlds-2025/poster?w=abc", nil)
+	w = httptest.NewRecorder()
+	app.ArtworkHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("ArtworkHandler() invalid width status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+}
@@ -1,34 +1,115 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
 	tmdbAPIBaseURL = "https://api.themoviedb.org/3"
-	tmdbImageBaseURL = "https://image.tmdb.org/t/p/original"
+
+	// tmdbImageBaseURL is followed by a size and the image's file path
+	tmdbImageBaseURL = "https://image.tmdb.org/t/p"
+
+	tmdbOriginalSize  = "original" // Size downloaded as artwork
+	tmdbThumbnailSize = "w342"     // Size shown in previews
 )
 
 // TMDBClient handles interactions with the TMDB API
 type TMDBClient struct {
-	apiKey     string
-	httpClient *http.Client
+	apiKey       string
+	apiBaseURL   string
+	imageBaseURL string
+	httpClient   *http.Client
+	limiter      *rateLimiter
+	cache        *TMDBCache // nil when responses aren't cached
+
+	// Metadata language, e.g. "de-DE", and the region release dates are
+	// taken from, e.g. "DE". Empty for TMDB's defaults.
+	language         string
+	fallbackLanguage string   // Fills in overviews missing in language
+	variants         []string // Further languages saved beside the metadata
+	region           string
+
+	// Retry policy, see get
+	maxAttempts   int
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
 }
 
 // NewTMDBClient creates a new TMDB API client
 func NewTMDBClient(apiKey string) *TMDBClient {
 	return &TMDBClient{
-		apiKey:     apiKey,
-		httpClient: &http.Client{},
+		apiKey:        apiKey,
+		apiBaseURL:    tmdbAPIBaseURL,
+		imageBaseURL:  tmdbImageBaseURL,
+		httpClient:    &http.Client{Timeout: tmdbTimeout},
+		limiter:       newRateLimiter(tmdbRequestsPerSecond, tmdbBurst),
+		maxAttempts:   tmdbMaxAttempts,
+		retryDelay:    tmdbRetryDelay,
+		maxRetryDelay: tmdbMaxRetryDelay,
+	}
+}
+
+// SetBaseURLs points the client at another TMDB API and image server, such
+// as shelf fake-tmdb. Empty URLs keep the current ones.
+func (c *TMDBClient) SetBaseURLs(apiURL, imageURL string) {
+	if apiURL != "" {
+		c.apiBaseURL = strings.TrimRight(apiURL, "/")
 	}
+	if imageURL != "" {
+		c.imageBaseURL = strings.TrimRight(imageURL, "/")
+	}
+}
+
+// SetCache makes the client reuse responses from cache
+func (c *TMDBClient) SetCache(cache *TMDBCache) {
+	c.cache = cache
+}
+
+// SetLanguage sets the language metadata is fetched in, the language used for
+// overviews TMDB hasn't translated, and the region whose release dates are used
+func (c *TMDBClient) SetLanguage(language, fallback, region string) {
+	c.language = language
+	c.fallbackLanguage = fallback
+	c.region = region
+}
+
+// SetLanguageVariants sets further languages whose titles and descriptions are
+// saved beside the metadata, for viewers to choose between
+func (c *TMDBClient) SetLanguageVariants(languages []string) {
+	c.variants = languages
+}
+
+// withLanguage adds the language parameter to a TMDB API URL
+func withLanguage(rawURL, language string) string {
+	if language == "" {
+		return rawURL
+	}
+	return rawURL + "&language=" + url.QueryEscape(language)
+}
+
+// needsFallback reports whether text missing in the metadata language should
+// be fetched again in the fallback language
+func (c *TMDBClient) needsFallback() bool {
+	return c.language != "" && c.fallbackLanguage != "" && c.fallbackLanguage != c.language
+}
+
+// tmdbImageURL returns the URL of an image file path at the given size
+func tmdbImageURL(baseURL, size, filePath string) string {
+	return baseURL + "/" + size + "/" + strings.TrimPrefix(filePath, "/")
+}
+
+// ThumbnailURL returns a small rendition of a TMDB image for previews
+func (c *TMDBClient) ThumbnailURL(filePath string) string {
+	return tmdbImageURL(c.imageBaseURL, tmdbThumbnailSize, filePath)
 }
 
 // Genre represents a genre from TMDB
@@ -39,22 +120,52 @@ type Genre struct {
 
 // MovieResponse represents the TMDB API response for a movie
 type MovieResponse struct {
-	ID          int     `json:"id"`
-	Title       string  `json:"title"`
-	PosterPath  string  `json:"poster_path"`
-	ReleaseDate string  `json:"release_date"`
-	Overview    string  `json:"overview"`
-	Genres      []Genre `json:"genres"`
-}
-
-// TVResponse represents the TMDB API response for a TV show
-type TVResponse struct {
 	ID           int     `json:"id"`
-	Name         string  `json:"name"`
+	Title        string  `json:"title"`
 	PosterPath   string  `json:"poster_path"`
-	FirstAirDate string  `json:"first_air_date"`
+	BackdropPath string  `json:"backdrop_path"`
+	ReleaseDate  string  `json:"release_date"`
 	Overview     string  `json:"overview"`
 	Genres       []Genre `json:"genres"`
+
+	// The collection the movie is part of, if any
+	BelongsToCollection *Collection `json:"belongs_to_collection"`
+
+	// Cast and crew, and release dates by country when a region is set
+	Credits      *CreditsResponse      `json:"credits,omitempty"`
+	ReleaseDates *ReleaseDatesResponse `json:"release_dates,omitempty"`
+}
+
+// TVResponse represents the TMDB API response for a TV show
+type TVResponse struct {
+	ID           int      `json:"id"`
+	Name         string   `json:"name"`
+	PosterPath   string   `json:"poster_path"`
+	BackdropPath string   `json:"backdrop_path"`
+	FirstAirDate string   `json:"first_air_date"`
+	Overview     string   `json:"overview"`
+	Genres       []Genre  `json:"genres"`
+	Seasons      []Season `json:"seasons"`
+
+	// Cast and crew
+	CreatedBy []Person         `json:"created_by"`
+	Credits   *CreditsResponse `json:"credits,omitempty"`
+}
+
+// TVCredits returns the show's creators, writers and top-billed cast. Shows
+// credit directors per episode, so none are listed.
+func (tv *TVResponse) TVCredits() Credits {
+	credits := tv.Credits.Credits()
+	credits.Directors = nil
+	credits.Creators = tv.CreatedBy
+	return credits
+}
+
+// Season represents a season entry in the TMDB TV show response
+type Season struct {
+	SeasonNumber int    `json:"season_number"`
+	Name         string `json:"name"`
+	PosterPath   string `json:"poster_path"`
 }
 
 // MovieSearchResult represents a movie search result from TMDB
@@ -107,78 +218,122 @@ type TVSearchResponse struct {
 	Results []TVSearchResult `json:"results"`
 }
 
+// Image represents an image entry from the TMDB images endpoint
+type Image struct {
+	FilePath    string  `json:"file_path"`
+	Language    string  `json:"iso_639_1"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	VoteAverage float64 `json:"vote_average"`
+	VoteCount   int     `json:"vote_count"`
+}
+
+// ImagesResponse represents the TMDB API response for a movie or TV show's images
+type ImagesResponse struct {
+	ID        int     `json:"id"`
+	Posters   []Image `json:"posters"`
+	Backdrops []Image `json:"backdrops"`
+	Logos     []Image `json:"logos"`
+}
+
 // FetchMovieMetadata fetches metadata for a movie from TMDB
-func (c *TMDBClient) FetchMovieMetadata(movieID string) (*MovieResponse, error) {
-	url := fmt.Sprintf("%s/movie/%s?api_key=%s", tmdbAPIBaseURL, movieID, c.apiKey)
+func (c *TMDBClient) FetchMovieMetadata(ctx context.Context, movieID string) (*MovieResponse, error) {
+	movieURL := fmt.Sprintf("%s/movie/%s?api_key=%s", c.apiBaseURL, url.PathEscape(movieID), c.apiKey)
 
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch movie metadata: %w", err)
+	// Fetch the credits alongside, and the release dates when they're needed
+	// for a region
+	detailsURL := movieURL + "&append_to_response=credits"
+	if c.region != "" {
+		detailsURL += ",release_dates"
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("TMDB API returned status %d for movie %s", resp.StatusCode, movieID)
+	var movie MovieResponse
+	if err := c.getJSON(ctx, withLanguage(detailsURL, c.language), &movie); err != nil {
+		return nil, fmt.Errorf("failed to fetch movie %s: %w", movieID, err)
+	}
+	if date := movie.ReleaseDates.RegionalDate(c.region); date != "" {
+		movie.ReleaseDate = date
 	}
 
-	var movie MovieResponse
-	if err := json.NewDecoder(resp.Body).Decode(&movie); err != nil {
-		return nil, fmt.Errorf("failed to decode movie response: %w", err)
+	// TMDB leaves the overview empty when it hasn't been translated
+	if movie.Overview == "" && c.needsFallback() {
+		var fallback MovieResponse
+		if err := c.getJSON(ctx, withLanguage(movieURL, c.fallbackLanguage), &fallback); err == nil {
+			movie.Overview = fallback.Overview
+		}
 	}
 
 	return &movie, nil
 }
 
 // FetchTVMetadata fetches metadata for a TV show from TMDB
-func (c *TMDBClient) FetchTVMetadata(tvID string) (*TVResponse, error) {
-	url := fmt.Sprintf("%s/tv/%s?api_key=%s", tmdbAPIBaseURL, tvID, c.apiKey)
+func (c *TMDBClient) FetchTVMetadata(ctx context.Context, tvID string) (*TVResponse, error) {
+	tvURL := fmt.Sprintf("%s/tv/%s?api_key=%s", c.apiBaseURL, url.PathEscape(tvID), c.apiKey)
 
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch TV metadata: %w", err)
+	var tv TVResponse
+	if err := c.getJSON(ctx, withLanguage(tvURL+"&append_to_response=credits", c.language), &tv); err != nil {
+		return nil, fmt.Errorf("failed to fetch TV show %s: %w", tvID, err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("TMDB API returned status %d for TV show %s", resp.StatusCode, tvID)
+	// TMDB leaves the overview empty when it hasn't been translated
+	if tv.Overview == "" && c.needsFallback() {
+		var fallback TVResponse
+		if err := c.getJSON(ctx, withLanguage(tvURL, c.fallbackLanguage), &fallback); err == nil {
+			tv.Overview = fallback.Overview
+		}
 	}
 
-	var tv TVResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tv); err != nil {
-		return nil, fmt.Errorf("failed to decode TV response: %w", err)
+	return &tv, nil
+}
+
+// FetchImages fetches the alternative images for a movie or TV show from TMDB
+// If language is set, only images in that language and images without text are returned
+func (c *TMDBClient) FetchImages(ctx context.Context, tmdbID string, mediaType MediaType, language string) (*ImagesResponse, error) {
+	var kind string
+	switch mediaType {
+	case Film:
+		kind = "movie"
+	case TV:
+		kind = "tv"
+	default:
+		return nil, fmt.Errorf("unknown media type: %v", mediaType)
 	}
 
-	return &tv, nil
+	imagesURL := fmt.Sprintf("%s/%s/%s/images?api_key=%s", c.apiBaseURL, kind, url.PathEscape(tmdbID), c.apiKey)
+	if language != "" {
+		imagesURL = fmt.Sprintf("%s&include_image_language=%s,null", imagesURL, url.QueryEscape(language))
+	}
+
+	var images ImagesResponse
+	if err := c.getJSON(ctx, imagesURL, &images); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s %s images: %w", kind, tmdbID, err)
+	}
+
+	return &images, nil
 }
 
 // SearchMovies searches for movies on TMDB by title and optional year
 // Returns up to 20 results sorted by popularity
-func (c *TMDBClient) SearchMovies(query string, year int) ([]MovieSearchResult, error) {
+func (c *TMDBClient) SearchMovies(ctx context.Context, query string, year int) ([]MovieSearchResult, error) {
 	if query == "" {
 		return nil, fmt.Errorf("search query cannot be empty")
 	}
 
 	// Build URL with query parameter (URL-encoded)
-	searchURL := fmt.Sprintf("%s/search/movie?api_key=%s&query=%s", tmdbAPIBaseURL, c.apiKey, url.QueryEscape(query))
+	searchURL := fmt.Sprintf("%s/search/movie?api_key=%s&query=%s", c.apiBaseURL, c.apiKey, url.QueryEscape(query))
 
 	// Add year parameter if provided
 	if year > 0 {
 		searchURL = fmt.Sprintf("%s&year=%d", searchURL, year)
 	}
-
-	resp, err := c.httpClient.Get(searchURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search movies: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("TMDB API returned status %d for movie search", resp.StatusCode)
+	if c.region != "" {
+		searchURL += "&region=" + url.QueryEscape(c.region)
 	}
+	searchURL = withLanguage(searchURL, c.language)
 
 	var searchResp MovieSearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
-		return nil, fmt.Errorf("failed to decode movie search response: %w", err)
+	if err := c.getJSON(ctx, searchURL, &searchResp); err != nil {
+		return nil, fmt.Errorf("failed to search movies: %w", err)
 	}
 
 	// Limit to 20 results
@@ -192,26 +347,17 @@ func (c *TMDBClient) SearchMovies(query string, year int) ([]MovieSearchResult,
 
 // SearchTV searches for TV shows on TMDB by name
 // Returns up to 20 results sorted by popularity
-func (c *TMDBClient) SearchTV(query string) ([]TVSearchResult, error) {
+func (c *TMDBClient) SearchTV(ctx context.Context, query string) ([]TVSearchResult, error) {
 	if query == "" {
 		return nil, fmt.Errorf("search query cannot be empty")
 	}
 
-	searchURL := fmt.Sprintf("%s/search/tv?api_key=%s&query=%s", tmdbAPIBaseURL, c.apiKey, url.QueryEscape(query))
-
-	resp, err := c.httpClient.Get(searchURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search TV shows: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("TMDB API returned status %d for TV search", resp.StatusCode)
-	}
+	searchURL := fmt.Sprintf("%s/search/tv?api_key=%s&query=%s", c.apiBaseURL, c.apiKey, url.QueryEscape(query))
+	searchURL = withLanguage(searchURL, c.language)
 
 	var searchResp TVSearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
-		return nil, fmt.Errorf("failed to decode TV search response: %w", err)
+	if err := c.getJSON(ctx, searchURL, &searchResp); err != nil {
+		return nil, fmt.Errorf("failed to search TV shows: %w", err)
 	}
 
 	// Limit to 20 results
@@ -256,20 +402,20 @@ func WriteTMDBID(tmdbID, mediaPath string) error {
 
 // ValidateTMDBID verifies that a TMDB ID exists and matches the expected media type
 // Returns an error if the ID doesn't exist or the type mismatches
-func (c *TMDBClient) ValidateTMDBID(tmdbID string, mediaType MediaType) error {
+func (c *TMDBClient) ValidateTMDBID(ctx context.Context, tmdbID string, mediaType MediaType) error {
 	if tmdbID == "" {
 		return fmt.Errorf("TMDB ID cannot be empty")
 	}
 
 	// Attempt to fetch metadata based on media type
 	if mediaType == Film {
-		_, err := c.FetchMovieMetadata(tmdbID)
+		_, err := c.FetchMovieMetadata(ctx, tmdbID)
 		if err != nil {
 			return fmt.Errorf("invalid movie ID or API error: %w", err)
 		}
 		return nil
 	} else if mediaType == TV {
-		_, err := c.FetchTVMetadata(tmdbID)
+		_, err := c.FetchTVMetadata(ctx, tmdbID)
 		if err != nil {
 			return fmt.Errorf("invalid TV show ID or API error: %w", err)
 		}
@@ -280,52 +426,122 @@ func (c *TMDBClient) ValidateTMDBID(tmdbID string, mediaType MediaType) error {
 }
 
 // DownloadPoster downloads a poster image from TMDB and saves it to the specified directory
-func (c *TMDBClient) DownloadPoster(posterPath, destDir string) error {
-	if posterPath == "" {
-		return fmt.Errorf("poster path is empty")
+func (c *TMDBClient) DownloadPoster(ctx context.Context, posterPath, destDir string) error {
+	return c.DownloadArtwork(ctx, posterPath, destDir, ArtworkPoster)
+}
+
+// DownloadArtwork downloads an image from TMDB and saves it as the named artwork
+// (e.g. "poster", "fanart", "season01-poster") in the specified directory
+func (c *TMDBClient) DownloadArtwork(ctx context.Context, imagePath, destDir, name string) error {
+	if imagePath == "" {
+		return fmt.Errorf("%s path is empty", name)
 	}
 
 	// Remove leading slash if present
-	posterPath = strings.TrimPrefix(posterPath, "/")
+	imagePath = strings.TrimPrefix(imagePath, "/")
 
 	// Construct the full image URL
-	imageURL := fmt.Sprintf("%s/%s", tmdbImageBaseURL, posterPath)
+	imageURL := tmdbImageURL(c.imageBaseURL, tmdbOriginalSize, imagePath)
 
 	// Download the image
-	resp, err := c.httpClient.Get(imageURL)
+	resp, err := c.get(ctx, imageURL)
 	if err != nil {
-		return fmt.Errorf("failed to download poster: %w", err)
+		return fmt.Errorf("failed to download %s: %w", name, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download poster: status %d", resp.StatusCode)
-	}
-
-	// Determine file extension from the poster path
-	ext := filepath.Ext(posterPath)
+	// Determine file extension from the image path
+	ext := filepath.Ext(imagePath)
 	if ext == "" {
 		ext = ".jpg" // Default to jpg if no extension
 	}
 
-	// Create the destination file path
-	destPath := filepath.Join(destDir, "poster"+ext)
+	// Replace the artwork atomically, removing copies with other extensions
+	if err := ReplaceArtwork(destDir, name, ext, resp.Body); err != nil {
+		return err
+	}
+
+	log.Printf("Downloaded %s to %s", name, filepath.Join(destDir, name+ext))
+	return nil
+}
 
-	// Create the file
-	file, err := os.Create(destPath)
-	if err != nil {
-		return fmt.Errorf("failed to create poster file: %w", err)
+// saveArtwork downloads the backdrop, clear logo and (for TV) season posters
+// that aren't already present in the media directory
+func (c *TMDBClient) saveArtwork(ctx context.Context, media *Media, backdropPath string, seasons []Season) {
+	// Backdrop (fanart)
+	if _, exists := media.FindArtworkFile(ArtworkBackdrop); !exists && backdropPath != "" {
+		if err := c.DownloadArtwork(ctx, backdropPath, media.Path, ArtworkBackdrop); err != nil {
+			log.Printf("Warning: Failed to download backdrop for %s: %v", media.Title, err)
+		}
 	}
-	defer file.Close()
 
-	// Write the image data to the file
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to write poster file: %w", err)
+	// Clear logo (only available from the images endpoint)
+	if _, exists := media.FindArtworkFile(ArtworkLogo); !exists {
+		logo, err := c.fetchLogo(ctx, media)
+		if err != nil {
+			log.Printf("Warning: Failed to fetch logos for %s: %v", media.Title, err)
+		} else if logo != "" {
+			if err := c.DownloadArtwork(ctx, logo, media.Path, ArtworkLogo); err != nil {
+				log.Printf("Warning: Failed to download logo for %s: %v", media.Title, err)
+			}
+		}
 	}
 
-	log.Printf("Downloaded poster to %s", destPath)
-	return nil
+	// Season posters
+	for _, season := range seasons {
+		if season.PosterPath == "" {
+			continue
+		}
+		name := SeasonArtworkName(season.SeasonNumber)
+		if _, exists := media.FindArtworkFile(name); exists {
+			continue
+		}
+		if err := c.DownloadArtwork(ctx, season.PosterPath, media.Path, name); err != nil {
+			log.Printf("Warning: Failed to download season %d poster for %s: %v", season.SeasonNumber, media.Title, err)
+		}
+	}
+}
+
+// fetchLogo returns the file path of a clear logo in the metadata language,
+// or in the fallback language when TMDB has none, like the overview. Returns
+// "" when neither has a logo.
+func (c *TMDBClient) fetchLogo(ctx context.Context, media *Media) (string, error) {
+	languages := []string{imageLanguage(c.language)}
+	if c.needsFallback() && imageLanguage(c.fallbackLanguage) != languages[0] {
+		languages = append(languages, imageLanguage(c.fallbackLanguage))
+	}
+
+	for _, language := range languages {
+		images, err := c.FetchImages(ctx, media.TMDBID, media.Type, language)
+		if err != nil {
+			return "", err
+		}
+		if logo := selectLogo(images.Logos); logo != "" {
+			return logo, nil
+		}
+	}
+	return "", nil
+}
+
+// imageLanguage returns the code TMDB tags images with for a language tag,
+// e.g. "de" for "de-DE". TMDB's default language is English.
+func imageLanguage(tag string) string {
+	if tag == "" {
+		return "en"
+	}
+	code, _, _ := strings.Cut(tag, "-")
+	return code
+}
+
+// selectLogo returns the file path of the first raster logo
+// TMDB also serves SVG logos, which are skipped because they can carry scripts
+func selectLogo(logos []Image) string {
+	for _, logo := range logos {
+		if isArtworkExtension(strings.ToLower(filepath.Ext(logo.FilePath))) {
+			return logo.FilePath
+		}
+	}
+	return ""
 }
 
 // saveDescription saves the overview text to description.txt
@@ -391,8 +607,30 @@ func (c *TMDBClient) saveTitle(title, destDir string) error {
 	return nil
 }
 
+// hasAllMetadata reports whether a media directory already has a poster,
+// description, genres and title, and has had its artwork, cast and crew and
+// collection fetched. Every fetch writes credits.json, even when TMDB credits
+// no one, so items fetched before those were saved are fetched again.
+func hasAllMetadata(mediaPath string) bool {
+	posterExists := false
+	for _, ext := range []string{".jpg", ".jpeg", ".png", ".webp"} {
+		posterPath := filepath.Join(mediaPath, "poster"+ext)
+		if _, err := os.Stat(posterPath); err == nil {
+			posterExists = true
+			break
+		}
+	}
+
+	for _, name := range []string{"description.txt", "genre.txt", "title.txt", creditsFileName} {
+		if _, err := os.Stat(filepath.Join(mediaPath, name)); err != nil {
+			return false
+		}
+	}
+	return posterExists
+}
+
 // FetchAndSaveMetadata fetches metadata and downloads poster, description, genres, and title for a media item
-func (c *TMDBClient) FetchAndSaveMetadata(media *Media) error {
+func (c *TMDBClient) FetchAndSaveMetadata(ctx context.Context, media *Media) error {
 	if media.TMDBID == "" {
 		return fmt.Errorf("no TMDB ID for media: %s", media.Title)
 	}
@@ -419,37 +657,52 @@ func (c *TMDBClient) FetchAndSaveMetadata(media *Media) error {
 	_, titleErr := os.Stat(titlePath)
 	titleExists := titleErr == nil
 
+	// Without credits.json, the artwork, cast and crew and collection were
+	// never fetched
+	_, creditsErr := os.Stat(filepath.Join(media.Path, creditsFileName))
+	creditsExist := creditsErr == nil
+
 	// If all files exist, skip fetching
-	if posterExists && descriptionExists && genreExists && titleExists {
+	if posterExists && descriptionExists && genreExists && titleExists && creditsExist && c.hasLanguageVariants(media) {
 		log.Printf("All metadata files already exist for %s, skipping download", media.Title)
 		return nil
 	}
 
 	var posterPath string
+	var backdropPath string
+	var seasons []Season
 	var overview string
 	var genres []Genre
 	var title string
+	var credits Credits
+	var collection *Collection
 	var err error
 
 	// Fetch metadata based on media type
 	if media.Type == Film {
-		movie, err := c.FetchMovieMetadata(media.TMDBID)
+		movie, err := c.FetchMovieMetadata(ctx, media.TMDBID)
 		if err != nil {
 			return fmt.Errorf("failed to fetch movie metadata: %w", err)
 		}
 		posterPath = movie.PosterPath
+		backdropPath = movie.BackdropPath
 		overview = movie.Overview
 		genres = movie.Genres
 		title = movie.Title
+		credits = movie.Credits.Credits()
+		collection = movie.BelongsToCollection
 	} else if media.Type == TV {
-		tv, err := c.FetchTVMetadata(media.TMDBID)
+		tv, err := c.FetchTVMetadata(ctx, media.TMDBID)
 		if err != nil {
 			return fmt.Errorf("failed to fetch TV metadata: %w", err)
 		}
 		posterPath = tv.PosterPath
+		backdropPath = tv.BackdropPath
+		seasons = tv.Seasons
 		overview = tv.Overview
 		genres = tv.Genres
 		title = tv.Name
+		credits = tv.TVCredits()
 	}
 
 	// Download the poster if it doesn't exist
@@ -457,12 +710,15 @@ func (c *TMDBClient) FetchAndSaveMetadata(media *Media) error {
 		if posterPath == "" {
 			log.Printf("Warning: No poster available for %s", media.Title)
 		} else {
-			if err = c.DownloadPoster(posterPath, media.Path); err != nil {
+			if err = c.DownloadPoster(ctx, posterPath, media.Path); err != nil {
 				log.Printf("Warning: Failed to download poster for %s: %v", media.Title, err)
 			}
 		}
 	}
 
+	// Download backdrop, logo and season art if they don't exist
+	c.saveArtwork(ctx, media, backdropPath, seasons)
+
 	// Save description if it doesn't exist
 	if !descriptionExists {
 		if overview == "" {
@@ -496,11 +752,31 @@ func (c *TMDBClient) FetchAndSaveMetadata(media *Media) error {
 		}
 	}
 
+	// Save the cast and crew if they haven't been saved, even when there are
+	// none, as the file marks the item as fetched
+	if !creditsExist {
+		if _, err = saveCredits(media.Path, credits); err != nil {
+			log.Printf("Warning: Failed to save credits for %s: %v", media.Title, err)
+		}
+	}
+
+	// Save the collection the film is part of if it hasn't been saved
+	if _, err = os.Stat(filepath.Join(media.Path, collectionFileName)); os.IsNotExist(err) && collection != nil {
+		if _, err = saveCollection(media.Path, collection); err != nil {
+			log.Printf("Warning: Failed to save collection for %s: %v", media.Title, err)
+		}
+	}
+
+	// Save titles and descriptions in the other languages viewers can choose
+	if _, err = c.saveLanguageVariants(ctx, media, false); err != nil {
+		log.Printf("Warning: Failed to save translations for %s: %v", media.Title, err)
+	}
+
 	return nil
 }
 
 // FetchAndSavePoster is deprecated, use FetchAndSaveMetadata instead
 // Kept for backward compatibility
 func (c *TMDBClient) FetchAndSavePoster(media *Media) error {
-	return c.FetchAndSaveMetadata(media)
+	return c.FetchAndSaveMetadata(context.Background(), media)
 }
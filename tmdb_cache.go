@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultTMDBCacheTTL is how long TMDB responses are reused for
+	defaultTMDBCacheTTL = 24 * time.Hour
+
+	// defaultTMDBCacheMaxSize bounds the cache, in bytes
+	defaultTMDBCacheMaxSize = 100 << 20
+)
+
+// TMDBCache keeps TMDB API responses on disk so the same movie, show or search
+// isn't fetched again until it expires. Entries are keyed by request URL, less
+// the API key. When the cache grows past its size limit the oldest entries
+// are removed.
+type TMDBCache struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+
+	mu   sync.Mutex
+	size int64 // Total size of the entries on disk
+}
+
+// NewTMDBCache creates a cache in dir keeping responses for ttl, in at most
+// maxSize bytes
+func NewTMDBCache(dir string, ttl time.Duration, maxSize int64) (*TMDBCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create TMDB cache directory: %w", err)
+	}
+	c := &TMDBCache{dir: dir, ttl: ttl, maxSize: maxSize}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range c.entriesLocked() {
+		c.size += entry.size
+	}
+	return c, nil
+}
+
+// defaultTMDBCacheDir returns the default TMDB cache location
+func defaultTMDBCacheDir() string {
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cacheDir, "shelf", "tmdb")
+	}
+	return filepath.Join(os.TempDir(), "shelf-tmdb")
+}
+
+// path returns the file caching the response to rawURL
+func (c *TMDBCache) path(rawURL string) string {
+	key := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		query := u.Query()
+		query.Del("api_key")
+		u.RawQuery = query.Encode()
+		key = u.String()
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get returns the cached response to rawURL, if there is one that hasn't expired
+func (c *TMDBCache) Get(rawURL string) ([]byte, bool) {
+	path := c.path(rawURL)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put caches the response to rawURL, making room for it if the cache is full
+func (c *TMDBCache) Put(rawURL string, data []byte) error {
+	path := c.path(rawURL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var replaced int64
+	if info, err := os.Stat(path); err == nil {
+		replaced = info.Size()
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return err
+	}
+	c.size += int64(len(data)) - replaced
+
+	if c.size > c.maxSize {
+		c.pruneLocked()
+	}
+	return nil
+}
+
+// Clear removes every cached response
+func (c *TMDBCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for _, entry := range c.entriesLocked() {
+		if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		c.size -= entry.size
+	}
+	return firstErr
+}
+
+// Stats returns the number of cached responses and their total size
+func (c *TMDBCache) Stats() (entries int, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entriesLocked()), c.size
+}
+
+// tmdbCacheEntry is a cached response file
+type tmdbCacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entriesLocked lists the cached responses, oldest first
+func (c *TMDBCache) entriesLocked() []tmdbCacheEntry {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil
+	}
+	var entries []tmdbCacheEntry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, tmdbCacheEntry{
+			path:    filepath.Join(c.dir, file.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	return entries
+}
+
+// pruneLocked removes expired responses, then the oldest until the cache is
+// back under 90% of its limit, so it isn't pruned again on the next write
+func (c *TMDBCache) pruneLocked() {
+	target := c.maxSize * 9 / 10
+	removed := 0
+	for _, entry := range c.entriesLocked() {
+		if c.size <= target && time.Since(entry.modTime) <= c.ttl {
+			break
+		}
+		if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+			continue
+		}
+		c.size -= entry.size
+		removed++
+	}
+	log.Printf("Pruned %d TMDB cache entries, %d bytes left", removed, c.size)
+}
+
+// tmdbCacheBypassKey marks contexts whose TMDB requests skip the cache
+type tmdbCacheBypassKey struct{}
+
+// withoutTMDBCache returns a context whose TMDB requests always go to TMDB.
+// The fresh responses still replace what was cached.
+func withoutTMDBCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tmdbCacheBypassKey{}, true)
+}
+
+// bypassesTMDBCache reports whether ctx was made by withoutTMDBCache
+func bypassesTMDBCache(ctx context.Context) bool {
+	bypass, _ := ctx.Value(tmdbCacheBypassKey{}).(bool)
+	return bypass
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCachedTMDBClient returns a client of the fake TMDB server with a cache,
+// and a count of the requests that reached the server
+func newCachedTMDBClient(t *testing.T) (*TMDBClient, *TMDBCache, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	fake := NewFakeTMDB(builtinTMDBFixtures(t))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	cache, err := NewTMDBCache(t.TempDir(), time.Hour, defaultTMDBCacheMaxSize)
+	if err != nil {
+		t.Fatalf("NewTMDBCache() error = %v", err)
+	}
+	client := NewTMDBClient("first-key")
+	client.SetBaseURLs(server.URL+"/3", server.URL+"/t/p")
+	client.SetCache(cache)
+	return client, cache, &requests
+}
+
+func TestTMDBCacheReusesResponses(t *testing.T) {
+	client, cache, requests := newCachedTMDBClient(t)
+	ctx := context.Background()
+
+	// Confirming and saving a match looks the film up three times
+	if _, err := client.FetchMovieMetadata(ctx, "550"); err != nil {
+		t.Fatalf("FetchMovieMetadata() error = %v", err)
+	}
+	if err := client.ValidateTMDBID(ctx, "550", Film); err != nil {
+		t.Fatalf("ValidateTMDBID() error = %v", err)
+	}
+	client.apiKey = "second-key"
+	movie, err := client.FetchMovieMetadata(ctx, "550")
+	if err != nil || movie.Title != "Fight Club" {
+		t.Fatalf("Cached FetchMovieMetadata() = %+v, %v", movie, err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("Requests = %d, want 1", requests.Load())
+	}
+
+	// Refreshes skip the cache
+	if _, err := client.FetchMovieMetadata(withoutTMDBCache(ctx), "550"); err != nil {
+		t.Fatalf("FetchMovieMetadata() without cache error = %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Requests = %d, want 2 after bypassing the cache", requests.Load())
+	}
+
+	// Failures aren't cached
+	client.FetchMovieMetadata(ctx, "999999")
+	client.FetchMovieMetadata(ctx, "999999")
+	if requests.Load() != 4 {
+		t.Errorf("Requests = %d, want 4 after two failed lookups", requests.Load())
+	}
+
+	if entries, size := cache.Stats(); entries != 1 || size == 0 {
+		t.Errorf("Stats() = %d, %d, want one entry", entries, size)
+	}
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if entries, size := cache.Stats(); entries != 0 || size != 0 {
+		t.Errorf("Stats() after Clear() = %d, %d", entries, size)
+	}
+	client.FetchMovieMetadata(ctx, "550")
+	if requests.Load() != 5 {
+		t.Errorf("Requests = %d, want 5 after clearing the cache", requests.Load())
+	}
+}
+
+func TestTMDBCacheExpiry(t *testing.T) {
+	cache, err := NewTMDBCache(t.TempDir(), time.Hour, defaultTMDBCacheMaxSize)
+	if err != nil {
+		t.Fatalf("NewTMDBCache() error = %v", err)
+	}
+
+	const movieURL = "https://api.themoviedb.org/3/movie/550?api_key=secret"
+	if err := cache.Put(movieURL, []byte(`{"id": 550}`)); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, ok := cache.Get("https://api.themoviedb.org/3/movie/550?api_key=other"); !ok {
+		t.Error("Get() with another API key missed")
+	}
+	if _, ok := cache.Get("https://api.themoviedb.org/3/movie/551?api_key=secret"); ok {
+		t.Error("Get() of another movie hit")
+	}
+
+	// The cache file mustn't hold the API key
+	entries, _ := os.ReadDir(cache.dir)
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), "secret") {
+			t.Errorf("Cache file %s names the API key", entry.Name())
+		}
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(cache.path(movieURL), old, old)
+	if _, ok := cache.Get(movieURL); ok {
+		t.Error("Get() of an expired response hit")
+	}
+}
+
+func TestTMDBCacheSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewTMDBCache(dir, time.Hour, 100)
+	if err != nil {
+		t.Fatalf("NewTMDBCache() error = %v", err)
+	}
+
+	response := []byte(strings.Repeat("x", 40))
+	for i, id := range []string{"1", "2", "3"} {
+		rawURL := "https://api.themoviedb.org/3/movie/" + id
+		if err := cache.Put(rawURL, response); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		// Give each entry a distinct age, oldest first
+		modTime := time.Now().Add(time.Duration(i-3) * time.Minute)
+		os.Chtimes(cache.path(rawURL), modTime, modTime)
+	}
+
+	if _, ok := cache.Get("https://api.themoviedb.org/3/movie/1"); ok {
+		t.Error("Oldest response kept past the size limit")
+	}
+	if _, ok := cache.Get("https://api.themoviedb.org/3/movie/3"); !ok {
+		t.Error("Newest response was removed")
+	}
+	if entries, size := cache.Stats(); size > 100 || entries != 2 {
+		t.Errorf("Stats() = %d, %d, want 2 entries within 100 bytes", entries, size)
+	}
+
+	// A reopened cache counts what is already on disk
+	reopened, err := NewTMDBCache(dir, time.Hour, 100)
+	if err != nil {
+		t.Fatalf("NewTMDBCache() error = %v", err)
+	}
+	if _, size := reopened.Stats(); size != 80 {
+		t.Errorf("Reopened size = %d, want 80", size)
+	}
+}
+
+func TestClearTMDBCacheHandler(t *testing.T) {
+	app, _ := newMatchTestApp(t)
+	cache, err := NewTMDBCache(t.TempDir(), time.Hour, defaultTMDBCacheMaxSize)
+	if err != nil {
+		t.Fatalf("NewTMDBCache() error = %v", err)
+	}
+	app.tmdbClient.SetCache(cache)
+	cache.Put("https://api.themoviedb.org/3/movie/550", []byte(`{"id": 550}`))
+	handler := app.routes()
+
+	req := httptest.NewRequest(http.MethodGet, "/matches", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), "1 TMDB responses cached") {
+		t.Errorf("Matches page doesn't show the cache:\n%s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/tmdb/cache/clear", nil)
+	addCSRFToken(t, req)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Clear status = %v: %s", w.Code, w.Body.String())
+	}
+	if entries, _ := cache.Stats(); entries != 0 {
+		t.Errorf("Entries after clearing = %d", entries)
+	}
+	if files, _ := filepath.Glob(filepath.Join(cache.dir, "*.json")); len(files) != 0 {
+		t.Errorf("Cache files left: %v", files)
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -14,6 +13,25 @@ import (
 // mockTMDBServer creates a test HTTP server that mimics TMDB API responses
 func mockTMDBServer() *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Images endpoint (alternative posters)
+		if strings.HasSuffix(r.URL.Path, "/images") {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Query().Get("include_image_language") == "de,null" {
+				w.Write([]byte(`{"id": 550, "posters": [
+					{"file_path": "/german.jpg", "iso_639_1": "de", "width": 1000, "height": 1500}
+				]}`))
+				return
+			}
+			w.Write([]byte(`{"id": 550, "posters": [
+				{"file_path": "/alt-one.jpg", "iso_639_1": "en", "width": 2000, "height": 3000, "vote_average": 5.5},
+				{"file_path": "/alt-two.png", "iso_639_1": null, "width": 1000, "height": 1500}
+			], "logos": [
+				{"file_path": "/logo.svg", "iso_639_1": "en"},
+				{"file_path": "/logo.png", "iso_639_1": "en"}
+			]}`))
+			return
+		}
+
 		// Movie metadata endpoint
 		if strings.HasPrefix(r.URL.Path, "/3/movie/") {
 			movieID := strings.TrimPrefix(r.URL.Path, "/3/movie/")
@@ -40,7 +58,11 @@ func mockTMDBServer() *httptest.Server {
 					"release_date": "2025-01-01",
 					"overview": "A contemporary retelling of H.G. Wells' seminal classic.",
 					"poster_path": "/test.jpg",
-					"genres": [{"id": 878, "name": "Science Fiction"}, {"id": 53, "name": "Thriller"}]
+					"genres": [{"id": 878, "name": "Science Fiction"}, {"id": 53, "name": "Thriller"}],
+					"credits": {
+						"cast": [{"id": 2, "name": "Ice Cube", "character": "Will Radford", "order": 0}],
+						"crew": [{"id": 1, "name": "Rich Lee", "job": "Director", "department": "Directing"}]
+					}
 				}`))
 			case "999999":
 				w.WriteHeader(http.StatusNotFound)
@@ -66,7 +88,12 @@ func mockTMDBServer() *httptest.Server {
 					"first_air_date": "2015-02-08",
 					"overview": "Six years before Saul Goodman meets Walter White.",
 					"poster_path": "/test.jpg",
-					"genres": [{"id": 18, "name": "Drama"}, {"id": 80, "name": "Crime"}]
+					"backdrop_path": "/bcs-backdrop.jpg",
+					"genres": [{"id": 18, "name": "Drama"}, {"id": 80, "name": "Crime"}],
+					"seasons": [
+						{"season_number": 1, "name": "Season 1", "poster_path": "/bcs-s1.jpg"},
+						{"season_number": 2, "name": "Season 2", "poster_path": null}
+					]
 				}`))
 			case "999999":
 				w.WriteHeader(http.StatusNotFound)
@@ -165,12 +192,16 @@ func mockTMDBServer() *httptest.Server {
 	}))
 }
 
-// setupAppWithMockTMDB creates an app with a mock TMDB server and test data
-func setupAppWithMockTMDB(t *testing.T) (*App, *httptest.Server, string) {
+// setupAppWithMockTMDB creates an app with a mock TMDB server and test data.
+// Fixtures add to the test data before it's scanned.
+func setupAppWithMockTMDB(t *testing.T, fixtures ...func(testDir string)) (*App, *httptest.Server, string) {
 	t.Helper()
 
 	// Create test data
 	testDir := setupTestData(t)
+	for _, fixture := range fixtures {
+		fixture(testDir)
+	}
 
 	// Scan the test directory
 	scanner := NewScanner(testDir)
@@ -180,12 +211,7 @@ func setupAppWithMockTMDB(t *testing.T) (*App, *httptest.Server, string) {
 	}
 
 	// Load templates
-	tmpl, err := template.ParseFiles(
-		"templates/index.html",
-		"templates/detail.html",
-		"templates/search.html",
-		"templates/confirm.html",
-	)
+	tmpl, err := parseTemplates(templateFS(""))
 	if err != nil {
 		t.Fatalf("Failed to parse templates: %v", err)
 	}
@@ -193,17 +219,39 @@ func setupAppWithMockTMDB(t *testing.T) (*App, *httptest.Server, string) {
 	// Create app
 	app := NewApp(mediaList, tmpl, testDir, "")
 
-	// Create mock TMDB server
+	// Create mock TMDB server, and a TMDB client whose requests go to it
 	mockServer := mockTMDBServer()
+	t.Cleanup(mockServer.Close)
+	app.SetTMDBClient(newMockTMDBClient(t, mockServer))
 
-	// Create TMDB client pointing to mock server
-	tmdbClient := NewTMDBClient("test-api-key")
-	// Override the base URLs to point to our mock server
-	tmdbClient.httpClient = mockServer.Client()
+	return app, mockServer, testDir
+}
 
-	app.SetTMDBClient(tmdbClient)
+// rewriteTransport sends every request to a test server, whatever host it was addressed to
+type rewriteTransport struct {
+	target *url.URL
+}
 
-	return app, mockServer, testDir
+// RoundTrip rewrites the request URL to the test server and performs the request
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newMockTMDBClient creates a TMDB client whose API and image requests are served by server
+func newMockTMDBClient(t *testing.T, server *httptest.Server) *TMDBClient {
+	t.Helper()
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse mock server URL: %v", err)
+	}
+
+	client := NewTMDBClient("test-api-key")
+	client.httpClient = &http.Client{Transport: rewriteTransport{target: target}}
+	return client
 }
 
 // TestSearchTMDBHandler_NoTMDBClient tests the handler without TMDB client configured
@@ -211,7 +259,7 @@ func TestSearchTMDBHandler_NoTMDBClient(t *testing.T) {
 	testDir := setupTestData(t)
 	scanner := NewScanner(testDir)
 	mediaList, _ := scanner.Scan()
-	tmpl, _ := template.ParseFiles("templates/search.html")
+	tmpl, _ := parseTemplates(templateFS(""))
 	app := NewApp(mediaList, tmpl, testDir, "")
 
 	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025/search-tmdb", nil)
@@ -297,7 +345,7 @@ func TestConfirmTMDBHandler_NoTMDBClient(t *testing.T) {
 	testDir := setupTestData(t)
 	scanner := NewScanner(testDir)
 	mediaList, _ := scanner.Scan()
-	tmpl, _ := template.ParseFiles("templates/confirm.html")
+	tmpl, _ := parseTemplates(templateFS(""))
 	app := NewApp(mediaList, tmpl, testDir, "")
 
 	req := httptest.NewRequest(http.MethodGet, "/media/war-of-the-worlds-2025/confirm-tmdb?id=550", nil)
@@ -377,7 +425,7 @@ func TestSaveTMDBHandler_NoTMDBClient(t *testing.T) {
 	testDir := setupTestData(t)
 	scanner := NewScanner(testDir)
 	mediaList, _ := scanner.Scan()
-	tmpl, _ := template.ParseFiles("templates/detail.html")
+	tmpl, _ := parseTemplates(templateFS(""))
 	app := NewApp(mediaList, tmpl, testDir, "")
 
 	form := url.Values{}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// tmdbTimeout bounds a whole TMDB request, including reading the body
+	tmdbTimeout = 30 * time.Second
+
+	// tmdbMaxAttempts is how many times a request is tried before giving up
+	tmdbMaxAttempts = 4
+
+	// tmdbRetryDelay is the wait before the first retry; it doubles each time
+	tmdbRetryDelay = time.Second
+
+	// tmdbMaxRetryDelay is the longest wait between attempts. A Retry-After
+	// asking for longer fails the request instead of stalling it.
+	tmdbMaxRetryDelay = 30 * time.Second
+
+	// TMDB allows around 50 requests a second; stay comfortably below
+	tmdbRequestsPerSecond = 40
+	tmdbBurst             = 20
+)
+
+// Errors TMDB requests fail with, for handlers to tell apart with errors.Is
+var (
+	ErrTMDBNotFound     = errors.New("not found on TMDB")
+	ErrTMDBUnauthorized = errors.New("TMDB rejected the API key")
+	ErrTMDBRateLimited  = errors.New("TMDB rate limit exceeded")
+	ErrTMDBUnavailable  = errors.New("TMDB is unavailable")
+)
+
+// TMDBError is an unsuccessful response from TMDB
+type TMDBError struct {
+	StatusCode int
+	Message    string // TMDB's status_message, if it sent one
+	kind       error
+}
+
+// Error describes the response
+func (e *TMDBError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("TMDB API returned status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("TMDB API returned status %d", e.StatusCode)
+}
+
+// Unwrap returns the sentinel error for the status, if there is one
+func (e *TMDBError) Unwrap() error {
+	return e.kind
+}
+
+// newTMDBError reads an unsuccessful response into a TMDBError
+func newTMDBError(resp *http.Response) *TMDBError {
+	tmdbErr := &TMDBError{StatusCode: resp.StatusCode}
+	var body struct {
+		StatusMessage string `json:"status_message"`
+	}
+	if json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body) == nil {
+		tmdbErr.Message = body.StatusMessage
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		tmdbErr.kind = ErrTMDBNotFound
+	case resp.StatusCode == http.StatusUnauthorized:
+		tmdbErr.kind = ErrTMDBUnauthorized
+	case resp.StatusCode == http.StatusTooManyRequests:
+		tmdbErr.kind = ErrTMDBRateLimited
+	case resp.StatusCode >= 500:
+		tmdbErr.kind = ErrTMDBUnavailable
+	}
+	return tmdbErr
+}
+
+// retryableStatus reports whether a request that got status may succeed later
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header, given in seconds or as an
+// HTTP date, returning 0 when it is missing or invalid
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil && when.After(now) {
+		return when.Sub(now)
+	}
+	return 0
+}
+
+// networkError wraps a failed request, keeping the API key out of the message
+// and marking it retryable unless the host doesn't exist
+func networkError(err error) (error, bool) {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			u.RawQuery = ""
+			urlErr.URL = u.String()
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return fmt.Errorf("%w: %v", ErrTMDBUnavailable, err), false
+	}
+	return fmt.Errorf("%w: %v", ErrTMDBUnavailable, err), true
+}
+
+// rateLimiter spaces requests evenly at a fixed rate, allowing short bursts
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	next     time.Time // When the next request would be due without a burst
+}
+
+// newRateLimiter creates a limiter allowing perSecond requests a second on
+// average, and up to burst at once
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond), burst: max(burst, 1)}
+}
+
+// Wait blocks until a request may be made or ctx is done
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now) - time.Duration(l.burst-1)*l.interval
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleepContext(ctx, wait)
+}
+
+// sleepContext waits for d, returning early with ctx's error if it is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// get requests rawURL from TMDB and returns the successful response, which
+// the caller must close. Rate limiting, server errors and network failures
+// are retried with exponential backoff, honouring Retry-After.
+func (c *TMDBClient) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		var retryAfter time.Duration
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			var retryable bool
+			if lastErr, retryable = networkError(err); !retryable {
+				return nil, lastErr
+			}
+		} else if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		} else {
+			lastErr = newTMDBError(resp)
+			resp.Body.Close()
+			if !retryableStatus(resp.StatusCode) {
+				return nil, lastErr
+			}
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+
+		if attempt >= c.maxAttempts {
+			return nil, lastErr
+		}
+
+		// Back off exponentially with some jitter, unless TMDB said how long to wait
+		delay := retryAfter
+		if delay == 0 {
+			delay = min(c.retryDelay<<(attempt-1), c.maxRetryDelay)
+			delay += time.Duration(rand.Int64N(int64(delay)/4 + 1))
+		} else if delay > c.maxRetryDelay {
+			return nil, lastErr
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getJSON requests rawURL from TMDB and decodes the JSON response into v,
+// answering from the cache when it has a fresh copy
+func (c *TMDBClient) getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	if c.cache != nil && !bypassesTMDBCache(ctx) {
+		if data, ok := c.cache.Get(rawURL); ok && json.Unmarshal(data, v) == nil {
+			return nil
+		}
+	}
+
+	resp, err := c.get(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if c.cache == nil {
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if err := c.cache.Put(rawURL, data); err != nil {
+		log.Printf("Warning: Failed to cache TMDB response: %v", err)
+	}
+	return nil
+}
+
+// tmdbErrorMessage explains a failed TMDB request to the user
+func tmdbErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, ErrTMDBNotFound):
+		return "TMDB has no entry with that ID"
+	case errors.Is(err, ErrTMDBUnauthorized):
+		return "TMDB rejected the API key, check TMDB_API_KEY"
+	case errors.Is(err, ErrTMDBRateLimited):
+		return "TMDB is limiting requests, try again in a minute"
+	case errors.Is(err, ErrTMDBUnavailable), errors.Is(err, context.DeadlineExceeded):
+		return "TMDB is not responding, try again later"
+	}
+	return fmt.Sprintf("TMDB request failed: %v", err)
+}
+
+// tmdbErrorStatus returns the HTTP status to answer a failed TMDB request with
+func tmdbErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrTMDBNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrTMDBRateLimited), errors.Is(err, ErrTMDBUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyTMDBClient returns a client whose requests are answered by handler,
+// retrying quickly, and a count of the requests made
+func newFlakyTMDBClient(t *testing.T, handler func(w http.ResponseWriter, attempt int)) (*TMDBClient, *atomic.Int32) {
+	t.Helper()
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, int(attempts.Add(1)))
+	}))
+	t.Cleanup(server.Close)
+
+	client := newMockTMDBClient(t, server)
+	client.retryDelay = time.Millisecond
+	return client, &attempts
+}
+
+func TestTMDBClientRetriesServerErrors(t *testing.T) {
+	client, attempts := newFlakyTMDBClient(t, func(w http.ResponseWriter, attempt int) {
+		if attempt < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id": 550, "title": "Fight Club"}`))
+	})
+
+	movie, err := client.FetchMovieMetadata(context.Background(), "550")
+	if err != nil {
+		t.Fatalf("FetchMovieMetadata() error = %v", err)
+	}
+	if movie.Title != "Fight Club" || attempts.Load() != 3 {
+		t.Errorf("Got %q after %d attempts, want Fight Club after 3", movie.Title, attempts.Load())
+	}
+}
+
+func TestTMDBClientGivesUp(t *testing.T) {
+	client, attempts := newFlakyTMDBClient(t, func(w http.ResponseWriter, attempt int) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, err := client.FetchMovieMetadata(context.Background(), "550")
+	if !errors.Is(err, ErrTMDBUnavailable) {
+		t.Errorf("FetchMovieMetadata() error = %v, want ErrTMDBUnavailable", err)
+	}
+	if attempts.Load() != tmdbMaxAttempts {
+		t.Errorf("Attempts = %d, want %d", attempts.Load(), tmdbMaxAttempts)
+	}
+}
+
+func TestTMDBClientHonoursRetryAfter(t *testing.T) {
+	client, attempts := newFlakyTMDBClient(t, func(w http.ResponseWriter, attempt int) {
+		if attempt == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"id": 550, "title": "Fight Club"}`))
+	})
+
+	start := time.Now()
+	if _, err := client.FetchMovieMetadata(context.Background(), "550"); err != nil {
+		t.Fatalf("FetchMovieMetadata() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Retried after %v, want at least the 1s TMDB asked for", elapsed)
+	}
+	if attempts.Load() != 2 {
+		t.Errorf("Attempts = %d, want 2", attempts.Load())
+	}
+}
+
+func TestTMDBClientLongRetryAfter(t *testing.T) {
+	client, attempts := newFlakyTMDBClient(t, func(w http.ResponseWriter, attempt int) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := client.SearchMovies(context.Background(), "Fight Club", 0)
+	if !errors.Is(err, ErrTMDBRateLimited) {
+		t.Errorf("SearchMovies() error = %v, want ErrTMDBRateLimited", err)
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("Attempts = %d, want 1 rather than waiting two minutes", attempts.Load())
+	}
+}
+
+func TestTMDBClientTypedErrors(t *testing.T) {
+	tests := []struct {
+		status     int
+		want       error
+		wantStatus int
+	}{
+		{http.StatusNotFound, ErrTMDBNotFound, http.StatusNotFound},
+		{http.StatusUnauthorized, ErrTMDBUnauthorized, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		client, attempts := newFlakyTMDBClient(t, func(w http.ResponseWriter, attempt int) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(`{"status_code": 7, "status_message": "Something went wrong."}`))
+		})
+
+		_, err := client.FetchTVMetadata(context.Background(), "60059")
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Status %d: error = %v, want %v", tt.status, err, tt.want)
+		}
+		var tmdbErr *TMDBError
+		if !errors.As(err, &tmdbErr) || tmdbErr.Message != "Something went wrong." {
+			t.Errorf("Status %d: error = %#v, want TMDB's status message", tt.status, err)
+		}
+		if attempts.Load() != 1 {
+			t.Errorf("Status %d: attempts = %d, want no retries", tt.status, attempts.Load())
+		}
+		if status := tmdbErrorStatus(err); status != tt.wantStatus {
+			t.Errorf("Status %d: tmdbErrorStatus() = %d, want %d", tt.status, status, tt.wantStatus)
+		}
+	}
+}
+
+func TestTMDBClientCancelled(t *testing.T) {
+	client, attempts := newFlakyTMDBClient(t, func(w http.ResponseWriter, attempt int) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client.retryDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.FetchMovieMetadata(ctx, "550")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FetchMovieMetadata() error = %v, want the context's error", err)
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("Attempts = %d, want 1", attempts.Load())
+	}
+}
+
+func TestTMDBClientRedactsAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	client := newMockTMDBClient(t, server)
+	client.maxAttempts = 1
+	server.Close()
+
+	_, err := client.FetchMovieMetadata(context.Background(), "550")
+	if !errors.Is(err, ErrTMDBUnavailable) {
+		t.Errorf("FetchMovieMetadata() error = %v, want ErrTMDBUnavailable", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "test-api-key") {
+		t.Errorf("Error leaks the API key: %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(100, 2)
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	// A burst of two goes straight through, the other four are spaced 10ms apart
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Six requests took %v, want about 40ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		limiter.Wait(ctx)
+	}
+	if err := limiter.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() with a cancelled context = %v, want context.Canceled", err)
+	}
+}
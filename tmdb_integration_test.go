@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -39,7 +40,7 @@ func TestIntegrationFetchMovieMetadata(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Use Fight Club (1999) - ID: 550
-	metadata, err := client.FetchMovieMetadata("550")
+	metadata, err := client.FetchMovieMetadata(context.Background(), "550")
 	if err != nil {
 		t.Fatalf("FetchMovieMetadata(550) failed: %v", err)
 	}
@@ -77,7 +78,7 @@ func TestIntegrationFetchTVMetadata(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Use Better Call Saul - ID: 60059
-	metadata, err := client.FetchTVMetadata("60059")
+	metadata, err := client.FetchTVMetadata(context.Background(), "60059")
 	if err != nil {
 		t.Fatalf("FetchTVMetadata(60059) failed: %v", err)
 	}
@@ -115,7 +116,7 @@ func TestIntegrationSearchMovies(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Search for "The Matrix" (1999)
-	results, err := client.SearchMovies("The Matrix", 1999)
+	results, err := client.SearchMovies(context.Background(), "The Matrix", 1999)
 	if err != nil {
 		t.Fatalf("SearchMovies() failed: %v", err)
 	}
@@ -150,7 +151,7 @@ func TestIntegrationSearchMoviesNoYear(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Search without year
-	results, err := client.SearchMovies("Inception", 0)
+	results, err := client.SearchMovies(context.Background(), "Inception", 0)
 	if err != nil {
 		t.Fatalf("SearchMovies() without year failed: %v", err)
 	}
@@ -179,7 +180,7 @@ func TestIntegrationSearchTV(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Search for "Breaking Bad"
-	results, err := client.SearchTV("Breaking Bad")
+	results, err := client.SearchTV(context.Background(), "Breaking Bad")
 	if err != nil {
 		t.Fatalf("SearchTV() failed: %v", err)
 	}
@@ -221,7 +222,7 @@ func TestIntegrationDownloadPoster(t *testing.T) {
 	defer os.RemoveAll(tmpDir)
 
 	// First fetch metadata to get a poster path
-	metadata, err := client.FetchMovieMetadata("550")
+	metadata, err := client.FetchMovieMetadata(context.Background(), "550")
 	if err != nil {
 		t.Fatalf("FetchMovieMetadata failed: %v", err)
 	}
@@ -231,7 +232,7 @@ func TestIntegrationDownloadPoster(t *testing.T) {
 	}
 
 	// Download the poster
-	err = client.DownloadPoster(metadata.PosterPath, tmpDir)
+	err = client.DownloadPoster(context.Background(), metadata.PosterPath, tmpDir)
 	if err != nil {
 		t.Fatalf("DownloadPoster() failed: %v", err)
 	}
@@ -280,7 +281,7 @@ func TestIntegrationSaveDescription(t *testing.T) {
 	defer os.RemoveAll(tmpDir)
 
 	// Fetch metadata
-	metadata, err := client.FetchMovieMetadata("550")
+	metadata, err := client.FetchMovieMetadata(context.Background(), "550")
 	if err != nil {
 		t.Fatalf("FetchMovieMetadata failed: %v", err)
 	}
@@ -316,7 +317,7 @@ func TestIntegrationSaveGenres(t *testing.T) {
 	defer os.RemoveAll(tmpDir)
 
 	// Fetch metadata
-	metadata, err := client.FetchMovieMetadata("550")
+	metadata, err := client.FetchMovieMetadata(context.Background(), "550")
 	if err != nil {
 		t.Fatalf("FetchMovieMetadata failed: %v", err)
 	}
@@ -377,7 +378,7 @@ func TestIntegrationFetchAndSaveMetadata(t *testing.T) {
 	}
 
 	// Fetch and save all metadata
-	err = client.FetchAndSaveMetadata(media)
+	err = client.FetchAndSaveMetadata(context.Background(), media)
 	if err != nil {
 		t.Fatalf("FetchAndSaveMetadata() failed: %v", err)
 	}
@@ -436,7 +437,7 @@ func TestIntegrationFetchAndSaveMetadataTV(t *testing.T) {
 	}
 
 	// Fetch and save all metadata
-	err = client.FetchAndSaveMetadata(media)
+	err = client.FetchAndSaveMetadata(context.Background(), media)
 	if err != nil {
 		t.Fatalf("FetchAndSaveMetadata() for TV failed: %v", err)
 	}
@@ -466,13 +467,13 @@ func TestIntegrationValidateTMDBIDMovie(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Valid movie ID
-	err := client.ValidateTMDBID("550", Film)
+	err := client.ValidateTMDBID(context.Background(), "550", Film)
 	if err != nil {
 		t.Errorf("ValidateTMDBID(550, Film) failed: %v", err)
 	}
 
 	// Invalid movie ID
-	err = client.ValidateTMDBID("999999999", Film)
+	err = client.ValidateTMDBID(context.Background(), "999999999", Film)
 	if err == nil {
 		t.Error("Expected error for invalid movie ID")
 	}
@@ -484,13 +485,13 @@ func TestIntegrationValidateTMDBIDTV(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Valid TV ID
-	err := client.ValidateTMDBID("60059", TV)
+	err := client.ValidateTMDBID(context.Background(), "60059", TV)
 	if err != nil {
 		t.Errorf("ValidateTMDBID(60059, TV) failed: %v", err)
 	}
 
 	// Invalid TV ID
-	err = client.ValidateTMDBID("999999999", TV)
+	err = client.ValidateTMDBID(context.Background(), "999999999", TV)
 	if err == nil {
 		t.Error("Expected error for invalid TV ID")
 	}
@@ -502,13 +503,13 @@ func TestIntegrationErrorHandlingInvalidMovieID(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Test with completely invalid ID
-	_, err := client.FetchMovieMetadata("not-a-number")
+	_, err := client.FetchMovieMetadata(context.Background(), "not-a-number")
 	if err == nil {
 		t.Error("Expected error for non-numeric movie ID")
 	}
 
 	// Test with very high ID that doesn't exist
-	_, err = client.FetchMovieMetadata("999999999")
+	_, err = client.FetchMovieMetadata(context.Background(), "999999999")
 	if err == nil {
 		t.Error("Expected error for non-existent movie ID")
 	}
@@ -520,13 +521,13 @@ func TestIntegrationErrorHandlingInvalidTVID(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Test with completely invalid ID
-	_, err := client.FetchTVMetadata("not-a-number")
+	_, err := client.FetchTVMetadata(context.Background(), "not-a-number")
 	if err == nil {
 		t.Error("Expected error for non-numeric TV ID")
 	}
 
 	// Test with very high ID that doesn't exist
-	_, err = client.FetchTVMetadata("999999999")
+	_, err = client.FetchTVMetadata(context.Background(), "999999999")
 	if err == nil {
 		t.Error("Expected error for non-existent TV ID")
 	}
@@ -572,7 +573,7 @@ func TestIntegrationFetchAndSaveMetadataSkipsExisting(t *testing.T) {
 	}
 
 	// This should not fetch anything since all files exist
-	err = client.FetchAndSaveMetadata(media)
+	err = client.FetchAndSaveMetadata(context.Background(), media)
 	if err != nil {
 		t.Errorf("FetchAndSaveMetadata() failed with existing files: %v", err)
 	}
@@ -600,7 +601,7 @@ func TestIntegrationSearchMoviesLimit(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Search for a common term that will have many results
-	results, err := client.SearchMovies("love", 0)
+	results, err := client.SearchMovies(context.Background(), "love", 0)
 	if err != nil {
 		t.Fatalf("SearchMovies() failed: %v", err)
 	}
@@ -617,7 +618,7 @@ func TestIntegrationSearchTVLimit(t *testing.T) {
 	client := NewTMDBClient(os.Getenv("TMDB_API_KEY"))
 
 	// Search for a common term that will have many results
-	results, err := client.SearchTV("love")
+	results, err := client.SearchTV(context.Background(), "love")
 	if err != nil {
 		t.Fatalf("SearchTV() failed: %v", err)
 	}
@@ -641,7 +642,7 @@ func TestIntegrationSaveTitleMovie(t *testing.T) {
 	defer os.RemoveAll(tmpDir)
 
 	// Fetch movie metadata to get the title
-	metadata, err := client.FetchMovieMetadata("550")
+	metadata, err := client.FetchMovieMetadata(context.Background(), "550")
 	if err != nil {
 		t.Fatalf("FetchMovieMetadata failed: %v", err)
 	}
@@ -681,7 +682,7 @@ func TestIntegrationSaveTitleTV(t *testing.T) {
 	defer os.RemoveAll(tmpDir)
 
 	// Fetch TV metadata to get the name
-	metadata, err := client.FetchTVMetadata("60059")
+	metadata, err := client.FetchTVMetadata(context.Background(), "60059")
 	if err != nil {
 		t.Fatalf("FetchTVMetadata failed: %v", err)
 	}
@@ -731,7 +732,7 @@ func TestIntegrationFetchAndSaveMetadataWithTitle(t *testing.T) {
 	}
 
 	// Fetch and save all metadata including title
-	err = client.FetchAndSaveMetadata(media)
+	err = client.FetchAndSaveMetadata(context.Background(), media)
 	if err != nil {
 		t.Fatalf("FetchAndSaveMetadata() failed: %v", err)
 	}
@@ -825,7 +826,7 @@ func TestIntegrationFetchAndSaveMetadataSkipsExistingTitle(t *testing.T) {
 	}
 
 	// This should not fetch anything since all files exist
-	err = client.FetchAndSaveMetadata(media)
+	err = client.FetchAndSaveMetadata(context.Background(), media)
 	if err != nil {
 		t.Errorf("FetchAndSaveMetadata() failed with existing files: %v", err)
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -93,7 +94,7 @@ func TestFetchMovieMetadata(t *testing.T) {
 
 	// Since we can't easily override the base URL, let's test the error case
 	client = NewTMDBClient("")
-	_, err := client.FetchMovieMetadata("invalid")
+	_, err := client.FetchMovieMetadata(context.Background(), "invalid")
 	if err == nil {
 		t.Error("Expected error for invalid API key, got nil")
 	}
@@ -101,7 +102,7 @@ func TestFetchMovieMetadata(t *testing.T) {
 
 func TestFetchMovieMetadataInvalidID(t *testing.T) {
 	client := NewTMDBClient("test-key")
-	_, err := client.FetchMovieMetadata("invalid-id-999999999")
+	_, err := client.FetchMovieMetadata(context.Background(), "invalid-id-999999999")
 	if err == nil {
 		t.Error("Expected error for invalid movie ID, got nil")
 	}
@@ -109,7 +110,7 @@ func TestFetchMovieMetadataInvalidID(t *testing.T) {
 
 func TestFetchTVMetadata(t *testing.T) {
 	client := NewTMDBClient("")
-	_, err := client.FetchTVMetadata("invalid")
+	_, err := client.FetchTVMetadata(context.Background(), "invalid")
 	if err == nil {
 		t.Error("Expected error for invalid API key, got nil")
 	}
@@ -137,7 +138,7 @@ func TestDownloadPoster(t *testing.T) {
 	// So let's test the error cases
 
 	// Test with empty poster path
-	err = client.DownloadPoster("", tmpDir)
+	err = client.DownloadPoster(context.Background(), "", tmpDir)
 	if err == nil {
 		t.Error("Expected error for empty poster path, got nil")
 	}
@@ -213,6 +214,7 @@ func TestFetchAndSavePosterExistingPoster(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	os.WriteFile(filepath.Join(tmpDir, "credits.json"), []byte("{}"), 0644)
 
 	client := NewTMDBClient("test-key")
 
@@ -334,6 +336,7 @@ func TestPosterFileExtensions(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
+			os.WriteFile(filepath.Join(tmpDir, "credits.json"), []byte("{}"), 0644)
 
 			media := &Media{
 				Title:     "Test",
@@ -461,6 +464,7 @@ func TestFetchAndSaveMetadataAllFilesExist(t *testing.T) {
 	os.WriteFile(filepath.Join(tmpDir, "description.txt"), []byte("description"), 0644)
 	os.WriteFile(filepath.Join(tmpDir, "genre.txt"), []byte("Action"), 0644)
 	os.WriteFile(filepath.Join(tmpDir, "title.txt"), []byte("Title"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "credits.json"), []byte("{}"), 0644)
 
 	client := NewTMDBClient("test-key")
 
@@ -473,7 +477,7 @@ func TestFetchAndSaveMetadataAllFilesExist(t *testing.T) {
 	}
 
 	// This should not make any API calls since all files exist
-	err = client.FetchAndSaveMetadata(media)
+	err = client.FetchAndSaveMetadata(context.Background(), media)
 	if err != nil {
 		t.Errorf("Expected no error when all files exist, got %v", err)
 	}
@@ -550,7 +554,7 @@ func TestSearchMovies(t *testing.T) {
 	client := NewTMDBClient("test-key")
 
 	// Test with empty query - should return error
-	_, err := client.SearchMovies("", 0)
+	_, err := client.SearchMovies(context.Background(), "", 0)
 	if err == nil {
 		t.Error("Expected error for empty query, got nil")
 	}
@@ -560,7 +564,7 @@ func TestSearchMoviesWithYear(t *testing.T) {
 	client := NewTMDBClient("test-key")
 
 	// Test with empty query and year - should still error on empty query
-	_, err := client.SearchMovies("", 2020)
+	_, err := client.SearchMovies(context.Background(), "", 2020)
 	if err == nil {
 		t.Error("Expected error for empty query with year, got nil")
 	}
@@ -613,7 +617,7 @@ func TestSearchTV(t *testing.T) {
 	client := NewTMDBClient("test-key")
 
 	// Test with empty query - should return error
-	_, err := client.SearchTV("")
+	_, err := client.SearchTV(context.Background(), "")
 	if err == nil {
 		t.Error("Expected error for empty query, got nil")
 	}
@@ -820,7 +824,7 @@ func TestValidateTMDBIDEmptyID(t *testing.T) {
 	client := NewTMDBClient("test-key")
 
 	// Test with empty TMDB ID
-	err := client.ValidateTMDBID("", Film)
+	err := client.ValidateTMDBID(context.Background(), "", Film)
 	if err == nil {
 		t.Error("Expected error for empty TMDB ID, got nil")
 	}
@@ -830,7 +834,7 @@ func TestValidateTMDBIDInvalidMovieID(t *testing.T) {
 	client := NewTMDBClient("test-key")
 
 	// Test with invalid movie ID (should fail API call)
-	err := client.ValidateTMDBID("invalid-movie-999999999", Film)
+	err := client.ValidateTMDBID(context.Background(), "invalid-movie-999999999", Film)
 	if err == nil {
 		t.Error("Expected error for invalid movie ID, got nil")
 	}
@@ -840,7 +844,7 @@ func TestValidateTMDBIDInvalidTVID(t *testing.T) {
 	client := NewTMDBClient("test-key")
 
 	// Test with invalid TV ID (should fail API call)
-	err := client.ValidateTMDBID("invalid-tv-999999999", TV)
+	err := client.ValidateTMDBID(context.Background(), "invalid-tv-999999999", TV)
 	if err == nil {
 		t.Error("Expected error for invalid TV ID, got nil")
 	}
@@ -853,7 +857,7 @@ func TestValidateTMDBIDTypeMismatch(t *testing.T) {
 	client := NewTMDBClient("test-key")
 
 	// Invalid type should return error
-	err := client.ValidateTMDBID("12345", MediaType(999))
+	err := client.ValidateTMDBID(context.Background(), "12345", MediaType(999))
 	if err == nil {
 		t.Error("Expected error for unknown media type, got nil")
 	}
@@ -938,6 +942,7 @@ func TestFetchAndSaveMetadataAllFilesExistWithTitle(t *testing.T) {
 	os.WriteFile(filepath.Join(tmpDir, "description.txt"), []byte("description"), 0644)
 	os.WriteFile(filepath.Join(tmpDir, "genre.txt"), []byte("Action"), 0644)
 	os.WriteFile(filepath.Join(tmpDir, "title.txt"), []byte("Official Title"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "credits.json"), []byte("{}"), 0644)
 
 	client := NewTMDBClient("test-key")
 
@@ -950,7 +955,7 @@ func TestFetchAndSaveMetadataAllFilesExistWithTitle(t *testing.T) {
 	}
 
 	// This should not make any API calls since all files exist
-	err = client.FetchAndSaveMetadata(media)
+	err = client.FetchAndSaveMetadata(context.Background(), media)
 	if err != nil {
 		t.Errorf("Expected no error when all files exist, got %v", err)
 	}
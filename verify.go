@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// verifyDisk checks that a disk backup has the files its player needs and
+// that everything in it can be read. It returns a description of each problem.
+func verifyDisk(d *Disk) []string {
+	info, err := os.Stat(d.Path)
+	if err != nil {
+		return []string{fmt.Sprintf("cannot access disk directory: %v", err)}
+	}
+	if !info.IsDir() {
+		return []string{"disk is not a directory"}
+	}
+
+	var problems []string
+	switch d.FormatKind() {
+	case FormatBluRay:
+		problems = append(problems, requireFile(d.Path, "BDMV", "index.bdmv")...)
+		if !hasFileWithExt(filepath.Join(d.Path, "BDMV", "STREAM"), ".m2ts") {
+			problems = append(problems, "no .m2ts streams in BDMV/STREAM")
+		}
+	case FormatDVD:
+		problems = append(problems, requireFile(d.Path, "VIDEO_TS", "VIDEO_TS.IFO")...)
+		if !hasFileWithExt(filepath.Join(d.Path, "VIDEO_TS"), ".vob") {
+			problems = append(problems, "no .VOB files in VIDEO_TS")
+		}
+	default:
+		if len(d.VideoFiles()) == 0 {
+			problems = append(problems, "no video files")
+		}
+	}
+
+	// Every file should be readable; a partial copy often leaves empty files
+	filepath.WalkDir(d.Path, func(path string, entry fs.DirEntry, err error) error {
+		rel, _ := filepath.Rel(d.Path, path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("cannot read %s: %v", rel, err))
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("cannot read %s: %v", rel, err))
+			return nil
+		}
+		f.Close()
+		if info, err := entry.Info(); err == nil && info.Size() == 0 {
+			problems = append(problems, fmt.Sprintf("%s is empty", rel))
+		}
+		return nil
+	})
+	return problems
+}
+
+// requireFile reports a problem if the file at the joined path is missing
+func requireFile(elem ...string) []string {
+	path := filepath.Join(elem...)
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		rel := filepath.Join(elem[1:]...)
+		return []string{"missing " + filepath.ToSlash(rel)}
+	}
+	return nil
+}
+
+// hasFileWithExt reports whether dir directly contains a file with the given
+// extension, ignoring case
+func hasFileWithExt(dir, ext string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.EqualFold(filepath.Ext(entry.Name()), ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVerifyDisk(t *testing.T) {
+	// writeFiles creates files with contents under dir
+	writeFiles := func(t *testing.T, dir string, files map[string]string) {
+		t.Helper()
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			os.MkdirAll(filepath.Dir(path), 0755)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		files  map[string]string
+		want   []string
+	}{
+		{
+			name:   "complete Blu-ray",
+			format: "Blu-Ray",
+			files:  map[string]string{"BDMV/index.bdmv": "index", "BDMV/STREAM/00001.m2ts": "video"},
+		},
+		{
+			name:   "Blu-ray without streams",
+			format: "Blu-Ray UHD",
+			files:  map[string]string{"BDMV/index.bdmv": "index"},
+			want:   []string{"no .m2ts streams in BDMV/STREAM"},
+		},
+		{
+			name:   "complete DVD",
+			format: "DVD",
+			files:  map[string]string{"VIDEO_TS/VIDEO_TS.IFO": "ifo", "VIDEO_TS/VTS_01_1.VOB": "video"},
+		},
+		{
+			name:   "DVD with an empty file",
+			format: "DVD",
+			files:  map[string]string{"VIDEO_TS/VIDEO_TS.IFO": "", "VIDEO_TS/VTS_01_1.VOB": "video"},
+			want:   []string{filepath.Join("VIDEO_TS", "VIDEO_TS.IFO") + " is empty"},
+		},
+		{
+			name:   "DVD missing its index",
+			format: "DVD",
+			files:  map[string]string{"VIDEO_TS/VTS_01_1.VOB": "video"},
+			want:   []string{"missing VIDEO_TS/VIDEO_TS.IFO"},
+		},
+		{
+			name:   "file-based disk",
+			format: "MKV",
+			files:  map[string]string{"Feature.mkv": "video"},
+		},
+		{
+			name:   "file-based disk without video",
+			format: "MKV",
+			files:  map[string]string{"notes.txt": "notes"},
+			want:   []string{"no video files"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			if got := verifyDisk(&Disk{Format: tt.format, Path: dir}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("verifyDisk() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := verifyDisk(&Disk{Format: "DVD", Path: filepath.Join(t.TempDir(), "missing")}); len(got) != 1 {
+		t.Errorf("verifyDisk() missing directory = %q, want one problem", got)
+	}
+}